@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/database"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+func main() {
+	email := flag.String("email", "", "email of the user to make an admin (required)")
+	name := flag.String("name", "Admin", "name used when the user has to be created")
+	flag.Parse()
+
+	if *email == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load .env file from the project root
+	_, filename, _, _ := runtime.Caller(0)
+	projectRoot := filepath.Join(filepath.Dir(filename), "../..")
+	envPath := filepath.Join(projectRoot, ".env")
+
+	err := godotenv.Load(envPath)
+	if err != nil {
+		log.Printf("Warning: Could not load .env file from %s: %v", envPath, err)
+	} else {
+		log.Printf("Loaded environment from %s", envPath)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// Connect to the database
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	// Run database migrations
+	if err := db.RunMigrations(); err != nil {
+		log.Fatalf("Error running migrations: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db.DB, &cfg.Database)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user, err := userRepo.GetByEmail(ctx, *email)
+	if err != nil {
+		log.Fatalf("Error looking up user: %v", err)
+	}
+
+	// Create the account first if it does not exist. The password is read from
+	// the environment so that it never appears in the shell history.
+	if user == nil {
+		password := os.Getenv("ADMIN_PASSWORD")
+		if len(password) < 8 {
+			log.Fatalf("User %s does not exist; set ADMIN_PASSWORD (at least 8 characters) to create it", *email)
+		}
+
+		created, err := userRepo.Create(ctx, models.UserInput{Name: *name, Email: *email, Password: password})
+		if err != nil {
+			log.Fatalf("Error creating user: %v", err)
+		}
+		user = &created
+		log.Printf("Created user %s (ID: %d)", user.Email, user.ID)
+	}
+
+	if user.Role == models.RoleAdmin {
+		log.Printf("User %s is already an admin", user.Email)
+		return
+	}
+
+	if _, err := userRepo.UpdateRole(ctx, user.ID, models.RoleAdmin); err != nil {
+		log.Fatalf("Error promoting user: %v", err)
+	}
+	log.Printf("User %s (ID: %d) is now an admin", user.Email, user.ID)
+}
@@ -15,11 +15,19 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/joho/godotenv"
+	"github.com/ombima56/transpacharity/internal/audit"
 	"github.com/ombima56/transpacharity/internal/config"
 	"github.com/ombima56/transpacharity/internal/database"
+	"github.com/ombima56/transpacharity/internal/ethereum"
 	"github.com/ombima56/transpacharity/internal/handlers"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/indexer"
+	"github.com/ombima56/transpacharity/internal/mailer"
 	customMiddleware "github.com/ombima56/transpacharity/internal/middleware"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/receipt"
 	"github.com/ombima56/transpacharity/internal/repository"
+	"github.com/ombima56/transpacharity/internal/throttle"
 )
 
 func main() {
@@ -40,6 +48,15 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error loading configuration: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Load the JWT signing and verification keys
+	keys, err := customMiddleware.LoadKeySet(&cfg.JWT)
+	if err != nil {
+		log.Fatalf("Error loading JWT keys: %v", err)
+	}
 
 	// Connect to the database
 	db, err := database.New(&cfg.Database)
@@ -55,25 +72,86 @@ func main() {
 
 	// Create repositories
 	userRepo := repository.NewUserRepository(db.DB, &cfg.Database)
+	sessionRepo := repository.NewSessionRepository(db.DB, &cfg.Database)
+	tokenRepo := repository.NewUserTokenRepository(db.DB, &cfg.Database)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db.DB, &cfg.Database)
 	categoryRepo := repository.NewCategoryRepository(db.DB, &cfg.Database)
 	causeRepo := repository.NewCauseRepository(db.DB, &cfg.Database)
 	donationRepo := repository.NewDonationRepository(db.DB, &cfg.Database)
+	ledgerRepo := repository.NewLedgerRepository(db.DB, &cfg.Database)
+	auditRepo := repository.NewAuditRepository(db.DB, &cfg.Database)
+	chainRepo := repository.NewChainRepository(db.DB, &cfg.Database)
+	disbursementRepo := repository.NewDisbursementRepository(db.DB, &cfg.Database)
+	recurringRepo := repository.NewRecurringDonationRepository(db.DB, &cfg.Database)
+	pledgeRepo := repository.NewMatchingPledgeRepository(db.DB, &cfg.Database)
+	idempotencyRepo := repository.NewIdempotencyRepository(db.DB, &cfg.Database)
+	receiptRepo := repository.NewReceiptRepository(db.DB, &cfg.Database)
+
+	// Create the on-chain donation verifier if a contract is configured
+	var verifier *indexer.Verifier
+	if cfg.Chain.ContractAddress != "" {
+		contractABI, err := ethereum.LoadABI(cfg.Chain.ResolveABIPath(projectRoot))
+		if err != nil {
+			log.Fatalf("Error loading contract ABI: %v", err)
+		}
+		verifier, err = indexer.NewVerifier(ethereum.NewClient(cfg.Chain.RPCURL), contractABI, cfg.Chain.ContractAddress, cfg.Chain.Confirmations)
+		if err != nil {
+			log.Fatalf("Error creating donation verifier: %v", err)
+		}
+	} else {
+		log.Println("Warning: CONTRACT_ADDRESS not set; on-chain donation confirmation is disabled")
+	}
+
+	// Create the mailer for account emails
+	mail, err := mailer.New(&cfg.Mail)
+	if err != nil {
+		log.Fatalf("Error creating mailer: %v", err)
+	}
+
+	// Create the login throttle
+	throttleStore, err := throttle.NewStore(&cfg.Login, db.DB, &cfg.Database)
+	if err != nil {
+		log.Fatalf("Error creating login throttle: %v", err)
+	}
+	limiter := throttle.NewLimiter(throttleStore, &cfg.Login)
 
 	// Create handlers
-	userHandler := handlers.NewUserHandler(userRepo, &cfg.JWT)
+	userHandler := handlers.NewUserHandler(userRepo, sessionRepo, tokenRepo, loginAttemptRepo, keys, limiter, mail, cfg.Mail.AppURL)
 	categoryHandler := handlers.NewCategoryHandler(categoryRepo)
-	causeHandler := handlers.NewCauseHandler(causeRepo)
-	donationHandler := handlers.NewDonationHandler(donationRepo)
+	causeHandler := handlers.NewCauseHandler(causeRepo, categoryRepo, pledgeRepo)
+	donationHandler := handlers.NewDonationHandler(donationRepo, causeRepo, auditRepo, userRepo, verifier)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerRepo, cfg.Chain.ExplorerURL)
+	auditHandler := handlers.NewAuditHandler(auditRepo, cfg.Chain.ExplorerURL)
+	disbursementHandler := handlers.NewDisbursementHandler(disbursementRepo, causeRepo, chainRepo, userRepo)
+	recurringHandler := handlers.NewRecurringDonationHandler(recurringRepo, causeRepo)
+	pledgeHandler := handlers.NewMatchingPledgeHandler(pledgeRepo, causeRepo)
+	receiptHandler := handlers.NewReceiptHandler(receiptRepo, donationRepo, userRepo,
+		receipt.NewRenderer(cfg.Chain.ExplorerURL, cfg.Server.PublicURL+"/api/receipts"))
+	exportHandler := handlers.NewExportHandler(donationRepo)
+	jwksHandler := handlers.NewJWKSHandler(keys)
+
+	// Replays responses to retried requests sent with an Idempotency-Key
+	idempotent := customMiddleware.Idempotency(idempotencyRepo, cfg.Idempotency.TTL())
 
 	// Create router
 	r := chi.NewRouter()
 
 	// Middleware
+	r.Use(middleware.RequestID)
+	r.Use(customMiddleware.RequestIDHeader)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(30 * time.Second))
+	// Exports stream for as long as they take
+	r.Use(customMiddleware.Timeout(30*time.Second, "/api/admin/exports/"))
 	r.Use(customMiddleware.CorsMiddleware(&cfg.Server))
 
+	// Unknown routes get the same JSON error envelope as everything else
+	r.NotFound(response.NotFound)
+	r.MethodNotAllowed(response.MethodNotAllowed)
+
+	// Public keys for verifying our tokens
+	r.Get("/.well-known/jwks.json", jwksHandler.Get)
+
 	// Routes
 	r.Route("/api", func(r chi.Router) {
 		// Public routes
@@ -81,6 +159,11 @@ func main() {
 			// User routes
 			r.Post("/users/register", userHandler.Register)
 			r.Post("/users/login", userHandler.Login)
+			r.Post("/users/refresh", userHandler.Refresh)
+			r.Post("/users/logout", userHandler.Logout)
+			r.Post("/users/password/forgot", userHandler.ForgotPassword)
+			r.Post("/users/password/reset", userHandler.ResetPassword)
+			r.Post("/users/verify-email", userHandler.VerifyEmail)
 
 			// Category routes
 			r.Get("/categories", categoryHandler.GetAll)
@@ -89,13 +172,38 @@ func main() {
 			// Cause routes
 			r.Get("/causes", causeHandler.GetAll)
 			r.Get("/causes/featured", causeHandler.GetFeatured)
+			r.Get("/causes/search", causeHandler.Search)
 			r.Get("/causes/{id}", causeHandler.GetByID)
 
 			// Donation routes
-			r.Post("/donations", donationHandler.Create)
-			r.Get("/donations/recent", donationHandler.GetRecentDonations)
-			r.Get("/causes/{id}/donations", donationHandler.GetByCauseID)
-			r.Get("/donations", donationHandler.GetAll) // Add this line to make donations accessible without auth
+			r.With(customMiddleware.OptionalAuthMiddleware(keys, sessionRepo), idempotent).
+				Post("/donations", donationHandler.Create)
+			r.With(customMiddleware.OptionalAuthMiddleware(keys, sessionRepo)).
+				Get("/donations/recent", donationHandler.GetRecentDonations)
+			r.With(customMiddleware.OptionalAuthMiddleware(keys, sessionRepo)).
+				Get("/causes/{id}/donations", donationHandler.GetByCauseID)
+			r.With(customMiddleware.OptionalAuthMiddleware(keys, sessionRepo)).
+				Get("/donations", donationHandler.GetAll) // Add this line to make donations accessible without auth
+			r.With(customMiddleware.OptionalAuthMiddleware(keys, sessionRepo)).
+				Patch("/donations/{id}/confirm", donationHandler.Confirm)
+
+			// How causes spend their funds
+			r.Get("/causes/{id}/disbursements", disbursementHandler.GetByCauseID)
+			r.Get("/causes/{id}/funds", disbursementHandler.GetFunds)
+			r.Get("/disbursements/{id}", disbursementHandler.GetByID)
+
+			// Sponsors' pledges to match donations
+			r.Get("/causes/{id}/pledges", pledgeHandler.GetByCauseID)
+
+			// Check a receipt by the verification code printed on it
+			r.Get("/receipts/{code}", receiptHandler.Verify)
+
+			// Public ledger of money in and out of causes
+			r.Get("/ledger", ledgerHandler.GetAll)
+
+			// Merkle roots committed over the donation audit log
+			r.Get("/audit/roots", auditHandler.GetRoots)
+			r.Get("/audit/roots/latest", auditHandler.GetLatestRoot)
 			
 			// Add a debug route to test if the router is working
 			r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
@@ -105,26 +213,66 @@ func main() {
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
-			r.Use(customMiddleware.AuthMiddleware(&cfg.JWT))
+			r.Use(customMiddleware.AuthMiddleware(keys, sessionRepo))
+			r.Use(idempotent)
 
 			// User routes
 			r.Get("/users/me", userHandler.GetMe)
 			r.Put("/users/me", userHandler.UpdateMe)
+			r.Put("/users/me/password", userHandler.ChangePassword)
+			r.Put("/users/me/email", userHandler.ChangeEmail)
+			r.Post("/users/me/verify-email", userHandler.ResendVerification)
 			r.Get("/users/{id}", userHandler.GetUserByID)
 
 			// Donation routes - move these to public if needed
 			r.Get("/donations/{id}", donationHandler.GetByID)
+			r.Get("/donations/{id}/history", donationHandler.GetStatusHistory)
+			r.Get("/donations/{id}/proof", donationHandler.GetProof)
+			r.Get("/donations/{id}/receipt.pdf", receiptHandler.GetDonationReceipt)
 			r.Get("/users/{id}/donations", donationHandler.GetByUserID)
 			r.Get("/users/me/donations", donationHandler.GetMyDonations)
+			r.Get("/users/me/statements/{year}.pdf", receiptHandler.GetMyStatement)
+
+			// Recurring donations, made by cmd/scheduler as they fall due
+			r.Get("/users/me/recurring", recurringHandler.GetMine)
+			r.Post("/users/me/recurring", recurringHandler.Create)
+			r.Get("/users/me/recurring/{id}", recurringHandler.GetByID)
+			r.Put("/users/me/recurring/{id}", recurringHandler.Update)
+			r.Delete("/users/me/recurring/{id}", recurringHandler.Cancel)
+			r.Post("/users/me/recurring/{id}/pause", recurringHandler.Pause)
+			r.Post("/users/me/recurring/{id}/resume", recurringHandler.Resume)
+			r.Post("/users/me/recurring/{id}/cancel", recurringHandler.Cancel)
+
+			// Spending reports, by admins or the cause's owner
+			r.Post("/causes/{id}/disbursements", disbursementHandler.Create)
+			r.Put("/disbursements/{id}", disbursementHandler.Update)
+			r.Delete("/disbursements/{id}", disbursementHandler.Delete)
+
+			// Admin routes
+			r.Group(func(r chi.Router) {
+				r.Use(customMiddleware.RequireRole(userRepo, models.RoleAdmin))
+
+				r.Post("/categories", categoryHandler.Create)
+				r.Put("/categories/{id}", categoryHandler.Update)
+				r.Delete("/categories/{id}", categoryHandler.Delete)
+
+				r.Post("/causes", causeHandler.Create)
+				r.Put("/causes/{id}", causeHandler.Update)
+				r.Delete("/causes/{id}", causeHandler.Delete)
+
+				r.Patch("/donations/{id}/status", donationHandler.UpdateStatus)
 
-			// Admin routes (these would typically have additional authorization)
-			r.Post("/categories", categoryHandler.Create)
-			r.Put("/categories/{id}", categoryHandler.Update)
-			r.Delete("/categories/{id}", categoryHandler.Delete)
+				r.Post("/causes/{id}/pledges", pledgeHandler.Create)
+				r.Post("/pledges/{id}/close", pledgeHandler.Close)
 
-			r.Post("/causes", causeHandler.Create)
-			r.Put("/causes/{id}", causeHandler.Update)
-			r.Delete("/causes/{id}", causeHandler.Delete)
+				r.Put("/audit/roots/{id}/anchor", auditHandler.SetAnchor)
+
+				r.Get("/admin/users", userHandler.ListUsers)
+				r.Put("/admin/users/{id}/role", userHandler.UpdateRole)
+
+				// Donations for finance teams as csv, jsonl or xlsx
+				r.Get("/admin/exports/donations", exportHandler.Donations)
+			})
 		})
 	})
 
@@ -134,6 +282,23 @@ func main() {
 		Handler: r,
 	}
 
+	// Background jobs run until the server shuts down
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Commit Merkle roots over the audit log in the background
+	if cfg.Audit.RootIntervalMinutes > 0 {
+		go audit.RunCommitter(bgCtx, auditRepo, time.Duration(cfg.Audit.RootIntervalMinutes)*time.Minute)
+	} else {
+		log.Println("Warning: AUDIT_ROOT_INTERVAL_MINUTES is 0; audit roots are not committed automatically")
+	}
+
+	// Delete expired idempotency keys in the background
+	go customMiddleware.PurgeIdempotencyKeys(bgCtx, idempotencyRepo, time.Hour)
+
+	// Delete expired login throttle counters in the background
+	go limiter.PurgeExpired(bgCtx, time.Hour)
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Starting server on port %d", cfg.Server.Port)
@@ -147,6 +312,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopBackground()
 
 	// Create a deadline to wait for
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/database"
+	"github.com/ombima56/transpacharity/internal/export"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+func main() {
+	format := flag.String("format", "csv", "file format: csv, jsonl or xlsx")
+	out := flag.String("out", "", "file to write, replaced only once the export completes (default stdout)")
+	causeID := flag.Int("cause", 0, "only donations to this cause")
+	userID := flag.Int("user", 0, "only donations by this user")
+	status := flag.String("status", "", "only donations with this status: pending, completed or failed")
+	currency := flag.String("currency", "", "only donations in this currency: USD, USDC or ETH")
+	minAmount := flag.String("min-amount", "", "only donations of at least this amount")
+	maxAmount := flag.String("max-amount", "", "only donations of at most this amount")
+	from := flag.String("from", "", "only donations made on or after this date (2006-01-02) or RFC 3339 time")
+	to := flag.String("to", "", "only donations made on or before this date (2006-01-02) or before this RFC 3339 time")
+	sort := flag.String("sort", "created_at", "order: created_at or amount, prefixed with - for descending")
+	flag.Parse()
+
+	// Build the filter from the flags, as the donations API does from its
+	// query string
+	exportFormat, err := export.ParseFormat(*format)
+	if err != nil {
+		log.Fatalf("Invalid -format: %v", err)
+	}
+	filter := models.DonationFilter{
+		PageRequest: models.PageRequest{
+			SortKey:    strings.TrimPrefix(*sort, "-"),
+			Descending: strings.HasPrefix(*sort, "-"),
+		},
+		Status:    models.DonationStatus(*status),
+		MinAmount: parseAmount("min-amount", *minAmount),
+		MaxAmount: parseAmount("max-amount", *maxAmount),
+		From:      parseTime("from", *from, false),
+		To:        parseTime("to", *to, true),
+	}
+	if *causeID != 0 {
+		filter.CauseID = causeID
+	}
+	if *userID != 0 {
+		filter.UserID = userID
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		log.Fatalf("Invalid -status %q: must be one of pending, completed, failed", *status)
+	}
+	if *currency != "" {
+		if filter.Currency, err = models.ParseCurrency(*currency); err != nil {
+			log.Fatalf("Invalid -currency: %v", err)
+		}
+	}
+	validSort := false
+	for _, key := range models.DonationSortKeys {
+		validSort = validSort || key == filter.SortKey
+	}
+	if !validSort {
+		log.Fatalf("Invalid -sort %q: must be one of %s, optionally prefixed with -",
+			*sort, strings.Join(models.DonationSortKeys, ", "))
+	}
+
+	// Load .env file from the project root
+	_, filename, _, _ := runtime.Caller(0)
+	projectRoot := filepath.Join(filepath.Dir(filename), "../..")
+	envPath := filepath.Join(projectRoot, ".env")
+
+	err = godotenv.Load(envPath)
+	if err != nil {
+		log.Printf("Warning: Could not load .env file from %s: %v", envPath, err)
+	} else {
+		log.Printf("Loaded environment from %s", envPath)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// Connect to the database
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	donationRepo := repository.NewDonationRepository(db.DB, &cfg.Database)
+
+	// Stop on interrupt, leaving any existing -out file untouched
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Write to a temporary file next to -out and rename it once complete, so
+	// that scheduled jobs never pick up a partial export
+	var dest io.Writer = os.Stdout
+	var tmp *os.File
+	if *out != "" {
+		tmp, err = os.CreateTemp(filepath.Dir(*out), "."+filepath.Base(*out)+".*")
+		if err != nil {
+			log.Fatalf("Error creating %s: %v", *out, err)
+		}
+		defer os.Remove(tmp.Name())
+		dest = tmp
+	}
+	buf := bufio.NewWriter(dest)
+
+	started := time.Now()
+	rows := 0
+	err = func() error {
+		writer, err := export.NewDonationWriter(buf, exportFormat)
+		if err != nil {
+			return err
+		}
+		err = donationRepo.Export(ctx, filter, func(d models.Donation) error {
+			rows++
+			return writer.Write(d)
+		})
+		if err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		return buf.Flush()
+	}()
+	if err != nil {
+		if tmp != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+		log.Fatalf("Error exporting donations after %d rows: %v", rows, err)
+	}
+
+	if tmp != nil {
+		if err := tmp.Close(); err != nil {
+			log.Fatalf("Error writing %s: %v", *out, err)
+		}
+		if err := os.Rename(tmp.Name(), *out); err != nil {
+			log.Fatalf("Error writing %s: %v", *out, err)
+		}
+	}
+	log.Printf("Exported %d donations in %s", rows, time.Since(started).Round(time.Millisecond))
+}
+
+// parseAmount checks that an amount flag is a non-negative decimal number
+func parseAmount(name, s string) string {
+	if s == "" {
+		return ""
+	}
+	n, ok := new(big.Rat).SetString(s)
+	if !ok || strings.Contains(s, "/") || n.Sign() < 0 {
+		log.Fatalf("Invalid -%s %q: must be a non-negative decimal number", name, s)
+	}
+	return s
+}
+
+// parseTime parses a date or RFC 3339 time flag. With endOfDay a date means
+// the end of that day, so that date ranges include their last day.
+func parseTime(name, s string, endOfDay bool) *time.Time {
+	if s == "" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		t = t.UTC()
+		return &t
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		log.Fatalf("Invalid -%s %q: must be a date (2006-01-02) or RFC 3339 timestamp", name, s)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"github.com/joho/godotenv"
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/database"
+	"github.com/ombima56/transpacharity/internal/ethereum"
+	"github.com/ombima56/transpacharity/internal/indexer"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+func main() {
+	// Load .env file from the project root
+	_, filename, _, _ := runtime.Caller(0)
+	projectRoot := filepath.Join(filepath.Dir(filename), "../..")
+	envPath := filepath.Join(projectRoot, ".env")
+
+	err := godotenv.Load(envPath)
+	if err != nil {
+		log.Printf("Warning: Could not load .env file from %s: %v", envPath, err)
+	} else {
+		log.Printf("Loaded environment from %s", envPath)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// Connect to the database
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	// Run database migrations
+	if err := db.RunMigrations(); err != nil {
+		log.Fatalf("Error running migrations: %v", err)
+	}
+
+	// Load the contract ABI, resolving relative paths against the backend directory
+	contractABI, err := ethereum.LoadABI(cfg.Chain.ResolveABIPath(projectRoot))
+	if err != nil {
+		log.Fatalf("Error loading contract ABI: %v", err)
+	}
+
+	chainRepo := repository.NewChainRepository(db.DB, &cfg.Database)
+	client := ethereum.NewClient(cfg.Chain.RPCURL)
+
+	ix, err := indexer.New(client, contractABI, chainRepo, &cfg.Chain)
+	if err != nil {
+		log.Fatalf("Error creating indexer: %v", err)
+	}
+
+	// Stop polling on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Indexing contract %s from %s with %d confirmations",
+		cfg.Chain.ContractAddress, cfg.Chain.RPCURL, cfg.Chain.Confirmations)
+
+	if err := ix.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("Indexer stopped: %v", err)
+	}
+
+	log.Println("Indexer stopped")
+}
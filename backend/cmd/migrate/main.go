@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/database"
+)
+
+const usage = `Usage: migrate <command> [args]
+
+Commands:
+  up          Apply all pending migrations
+  down N      Roll back the N most recently applied migrations
+  status      List migrations and whether they have been applied
+  force V     Mark the schema as being at version V without running SQL`
+
+func main() {
+	// Load .env file from the project root
+	_, filename, _, _ := runtime.Caller(0)
+	projectRoot := filepath.Join(filepath.Dir(filename), "../..")
+	envPath := filepath.Join(projectRoot, ".env")
+
+	err := godotenv.Load(envPath)
+	if err != nil {
+		log.Printf("Warning: Could not load .env file from %s: %v", envPath, err)
+	} else {
+		log.Printf("Loaded environment from %s", envPath)
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// Connect to the database
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := db.NewMigrator()
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+		log.Printf("Applied %d migration(s)", applied)
+
+	case "down":
+		n := parseArg("down", "N")
+		rolledBack, err := migrator.Down(ctx, int(n))
+		if err != nil {
+			log.Fatalf("Error rolling back migrations: %v", err)
+		}
+		log.Printf("Rolled back %d migration(s)", rolledBack)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Error reading migration status: %v", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Dirty {
+				state = "DIRTY"
+			} else if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-40s  %s\n", s.Version, s.Name, state)
+		}
+
+	case "force":
+		version := parseArg("force", "V")
+		if err := migrator.Force(ctx, version); err != nil {
+			log.Fatalf("Error forcing migration version: %v", err)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s\n", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+// parseArg parses the numeric argument following a command
+func parseArg(command, name string) int64 {
+	if len(os.Args) < 3 {
+		fmt.Fprintf(os.Stderr, "%s requires an argument %s\n\n%s\n", command, name, usage)
+		os.Exit(2)
+	}
+	value, err := strconv.ParseInt(os.Args[2], 10, 64)
+	if err != nil || value < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid %s %q: must be a non-negative integer\n", name, os.Args[2])
+		os.Exit(2)
+	}
+	return value
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/database"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "correct drifted raised amounts instead of only reporting them")
+	flag.Parse()
+
+	// Load .env file from the project root
+	_, filename, _, _ := runtime.Caller(0)
+	projectRoot := filepath.Join(filepath.Dir(filename), "../..")
+	envPath := filepath.Join(projectRoot, ".env")
+
+	err := godotenv.Load(envPath)
+	if err != nil {
+		log.Printf("Warning: Could not load .env file from %s: %v", envPath, err)
+	} else {
+		log.Printf("Loaded environment from %s", envPath)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// Connect to the database
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	causeRepo := repository.NewCauseRepository(db.DB, &cfg.Database)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	drifts, err := causeRepo.ReconcileRaisedAmounts(ctx, *apply)
+	if err != nil {
+		log.Fatalf("Error reconciling raised amounts: %v", err)
+	}
+
+	if len(drifts) == 0 {
+		log.Println("All raised amounts match the donations ledger")
+		return
+	}
+
+	fmt.Printf("%-6s  %-40s  %-4s  %28s  %28s  %28s\n", "CAUSE", "TITLE", "CUR", "STORED", "LEDGER", "DRIFT")
+	for _, d := range drifts {
+		drift, err := d.Stored.Sub(d.Computed)
+		if err != nil {
+			log.Fatalf("Error computing drift for cause %d: %v", d.CauseID, err)
+		}
+		fmt.Printf("%-6d  %-40.40s  %-4s  %28s  %28s  %28s\n",
+			d.CauseID, d.Title, d.Stored.Currency, d.Stored, d.Computed, drift)
+	}
+
+	if *apply {
+		log.Printf("Corrected %d cause(s)", len(drifts))
+		return
+	}
+
+	log.Printf("%d cause(s) drifted; run with -apply to correct them", len(drifts))
+	db.Close()
+	os.Exit(1)
+}
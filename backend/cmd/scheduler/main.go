@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"github.com/joho/godotenv"
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/database"
+	"github.com/ombima56/transpacharity/internal/repository"
+	"github.com/ombima56/transpacharity/internal/scheduler"
+)
+
+func main() {
+	// Load .env file from the project root
+	_, filename, _, _ := runtime.Caller(0)
+	projectRoot := filepath.Join(filepath.Dir(filename), "../..")
+	envPath := filepath.Join(projectRoot, ".env")
+
+	err := godotenv.Load(envPath)
+	if err != nil {
+		log.Printf("Warning: Could not load .env file from %s: %v", envPath, err)
+	} else {
+		log.Printf("Loaded environment from %s", envPath)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// Connect to the database
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	// Run database migrations
+	if err := db.RunMigrations(); err != nil {
+		log.Fatalf("Error running migrations: %v", err)
+	}
+
+	recurringRepo := repository.NewRecurringDonationRepository(db.DB, &cfg.Database)
+	s := scheduler.New(recurringRepo, &cfg.Recurring)
+
+	// Stop polling on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Running recurring donations every %d seconds, up to %d per poll",
+		cfg.Recurring.PollIntervalSeconds, cfg.Recurring.BatchSize)
+
+	if err := s.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("Scheduler stopped: %v", err)
+	}
+
+	log.Println("Scheduler stopped")
+}
@@ -109,7 +109,7 @@ func seedDatabase(
 			Organization: "Water First Initiative",
 			Description:  "Help provide clean and safe drinking water to rural communities in need. Your donation helps build wells and water filtration systems.",
 			ImageURL:     "https://images.unsplash.com/photo-1581091226825-a6a2a5aee158",
-			GoalAmount:   50000,
+			GoalAmount:   "50000",
 			CategoryID:   categoryMap["Environment"],
 			Featured:     1,
 		},
@@ -118,7 +118,7 @@ func seedDatabase(
 			Organization: "Learn & Grow Foundation",
 			Description:  "Support education programs for children in underserved communities. Funds go towards school supplies, scholarships, and teaching resources.",
 			ImageURL:     "https://images.unsplash.com/photo-1519389950473-47ba0277781c",
-			GoalAmount:   30000,
+			GoalAmount:   "30000",
 			CategoryID:   categoryMap["Education"],
 			Featured:     1,
 		},
@@ -142,13 +142,11 @@ func seedDatabase(
 			Name:     "Admin User",
 			Email:    "admin@example.com",
 			Password: "password123",
-			Role:     models.RoleAdmin,
 		},
 		{
 			Name:     "Regular User",
 			Email:    "user@example.com",
 			Password: "password123",
-			Role:     models.RoleUser,
 		},
 	}
 
@@ -163,6 +161,13 @@ func seedDatabase(
 		log.Printf("Created user: %s (ID: %d)", u.Email, u.ID)
 	}
 
+	// Registration only creates plain users, so promote the admin explicitly
+	if adminID, ok := userMap["admin@example.com"]; ok {
+		if _, err := userRepo.UpdateRole(ctx, adminID, models.RoleAdmin); err != nil {
+			log.Printf("Error promoting admin user: %v", err)
+		}
+	}
+
 	// Seed donations (only if we have users and causes)
 	if len(userMap) > 0 && len(causeMap) > 0 {
 		log.Println("Seeding donations...")
@@ -175,13 +180,13 @@ func seedDatabase(
 			{
 				UserID:      &adminID,
 				CauseID:     causeMap["Clean Water for Rural Communities"],
-				Amount:      100,
+				Amount:      "100",
 				IsAnonymous: false,
 			},
 			{
 				UserID:      &regularUserID,
 				CauseID:     causeMap["Education for Underserved Children"],
-				Amount:      50,
+				Amount:      "50",
 				IsAnonymous: false,
 			},
 		}
@@ -192,7 +197,17 @@ func seedDatabase(
 				log.Printf("Error creating donation: %v", err)
 				continue
 			}
-			log.Printf("Created donation: ID %d, Amount $%.2f", d.ID, d.Amount)
+			log.Printf("Created donation: ID %d, Amount %s %s", d.ID, d.Amount, d.Currency)
+
+			// Complete the sample donations so they count towards the causes
+			_, err = donationRepo.UpdateStatus(ctx, d.ID, models.DonationStatusChange{
+				ToStatus:  models.DonationStatusCompleted,
+				ActorType: models.StatusActorSystem,
+				Reason:    "seed data",
+			})
+			if err != nil {
+				log.Printf("Error completing donation %d: %v", d.ID, err)
+			}
 		}
 	}
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/ombima56/transpacharity/internal/audit"
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/database"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+func main() {
+	backfill := flag.Bool("backfill", false, "record donations missing from the audit log before verifying")
+	flag.Parse()
+
+	// Load .env file from the project root
+	_, filename, _, _ := runtime.Caller(0)
+	projectRoot := filepath.Join(filepath.Dir(filename), "../..")
+	envPath := filepath.Join(projectRoot, ".env")
+
+	err := godotenv.Load(envPath)
+	if err != nil {
+		log.Printf("Warning: Could not load .env file from %s: %v", envPath, err)
+	} else {
+		log.Printf("Loaded environment from %s", envPath)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	// Connect to the database
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.Close()
+
+	auditRepo := repository.NewAuditRepository(db.DB, &cfg.Database)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if *backfill {
+		recorded, err := auditRepo.Backfill(ctx)
+		if err != nil {
+			log.Fatalf("Error backfilling the audit log: %v", err)
+		}
+		log.Printf("Recorded %d donation(s) missing from the audit log", recorded)
+	}
+
+	report, err := audit.Verify(ctx, auditRepo)
+	if err != nil {
+		log.Fatalf("Error verifying the audit log: %v", err)
+	}
+
+	log.Printf("Checked %d entries, %d root(s) and %d donation(s)", report.Entries, report.Roots, report.Donations)
+	if report.OK() {
+		log.Println("The audit log is intact and matches the donations table")
+		return
+	}
+
+	for _, p := range report.Problems {
+		fmt.Println(p)
+	}
+
+	log.Printf("Found %d problem(s)", len(report.Problems))
+	db.Close()
+	os.Exit(1)
+}
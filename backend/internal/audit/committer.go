@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// RootStore commits the entries appended since the last root to a new root
+type RootStore interface {
+	// CommitRoot commits every entry after the last committed root and
+	// returns the new root, or nil if there were no new entries
+	CommitRoot(ctx context.Context) (*models.AuditRoot, error)
+}
+
+// RunCommitter commits a new Merkle root every interval until ctx is
+// cancelled. Errors are logged and retried on the next tick.
+func RunCommitter(ctx context.Context, store RootStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			root, err := store.CommitRoot(ctx)
+			if err != nil {
+				log.Printf("Error committing audit root: %v", err)
+				continue
+			}
+			if root != nil {
+				log.Printf("Committed audit root %d over entries %d-%d: %s",
+					root.ID, root.FirstEntryID, root.LastEntryID, root.Root)
+			}
+		}
+	}
+}
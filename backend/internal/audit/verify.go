@@ -0,0 +1,222 @@
+// Package audit maintains the tamper-evident donation log: every entry is
+// chained to the previous one by its SHA-256 hash, and batches of entries are
+// periodically committed to a Merkle root that can be published. The hashing
+// itself lives in pkg/proof so that third parties can check receipts with the
+// same code.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/pkg/proof"
+)
+
+// verifyBatchSize is how many entries Verify reads at a time
+const verifyBatchSize = 1000
+
+// Source reads what Verify checks
+type Source interface {
+	// AuditEntries returns up to limit entries with IDs above afterID, in ID order
+	AuditEntries(ctx context.Context, afterID int64, limit int) ([]models.AuditEntry, error)
+	// AuditRoots returns every committed root, oldest first
+	AuditRoots(ctx context.Context) ([]models.AuditRoot, error)
+	// DonationSnapshots returns the current state of every donation
+	DonationSnapshots(ctx context.Context) ([]models.DonationSnapshot, error)
+}
+
+// Problem is one inconsistency found by Verify. EntryID, RootID or DonationID
+// point to the offending row when known.
+type Problem struct {
+	EntryID    int64
+	RootID     int
+	DonationID int
+	Message    string
+}
+
+// String formats the problem for a report
+func (p Problem) String() string {
+	var where []string
+	if p.EntryID != 0 {
+		where = append(where, fmt.Sprintf("entry %d", p.EntryID))
+	}
+	if p.RootID != 0 {
+		where = append(where, fmt.Sprintf("root %d", p.RootID))
+	}
+	if p.DonationID != 0 {
+		where = append(where, fmt.Sprintf("donation %d", p.DonationID))
+	}
+	return strings.Join(where, ", ") + ": " + p.Message
+}
+
+// Report is the result of Verify
+type Report struct {
+	Entries   int
+	Roots     int
+	Donations int
+	Problems  []Problem
+}
+
+// OK reports whether no problem was found
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify recomputes the hash chain and every committed Merkle root, then
+// checks that the current state of every donation matches the last state the
+// log recorded for it. It reports every problem rather than stopping at the
+// first, so that a tampered row can be told apart from the entries after it.
+func Verify(ctx context.Context, src Source) (*Report, error) {
+	report := &Report{}
+
+	roots, err := src.AuditRoots(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading audit roots: %w", err)
+	}
+	report.Roots = len(roots)
+
+	prev := proof.GenesisHash
+	var prevID int64
+	var batch []string
+	nextRoot := 0
+	logged := make(map[int]models.DonationSnapshot)
+
+	var afterID int64
+	for {
+		entries, err := src.AuditEntries(ctx, afterID, verifyBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("error reading audit entries: %w", err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		for _, e := range entries {
+			report.Entries++
+			afterID = e.ID
+
+			if e.PrevHash != prev {
+				report.add(Problem{EntryID: e.ID, Message: fmt.Sprintf(
+					"prev_hash does not match the hash of entry %d; an entry in between was changed or deleted", prevID)})
+			}
+			if proof.EntryHash(e.PrevHash, e.Payload) != e.Hash {
+				report.add(Problem{EntryID: e.ID, Message: "hash does not match its payload; the entry was changed"})
+			}
+			// Continue from the stored hash so one bad entry is reported once
+			prev, prevID = e.Hash, e.ID
+
+			var record models.AuditRecord
+			if err := json.Unmarshal([]byte(e.Payload), &record); err != nil {
+				report.add(Problem{EntryID: e.ID, Message: "payload is not a valid audit record"})
+			} else {
+				if record.Type != e.Type || record.Donation.ID != e.DonationID {
+					report.add(Problem{EntryID: e.ID, Message: "type or donation_id column does not match the payload"})
+				}
+				if record.Type == models.AuditDonationRemoved {
+					delete(logged, record.Donation.ID)
+				} else {
+					logged[record.Donation.ID] = record.Donation
+				}
+			}
+
+			// Check each root once the last entry of its batch is read
+			for nextRoot < len(roots) && roots[nextRoot].LastEntryID < e.ID {
+				report.add(missingBatch(roots[nextRoot], batch))
+				batch = nil
+				nextRoot++
+			}
+			if nextRoot < len(roots) && e.ID >= roots[nextRoot].FirstEntryID {
+				batch = append(batch, e.Hash)
+				if e.ID == roots[nextRoot].LastEntryID {
+					if p, ok := checkRoot(roots[nextRoot], batch); !ok {
+						report.add(p)
+					}
+					batch = nil
+					nextRoot++
+				}
+			}
+		}
+	}
+	for ; nextRoot < len(roots); nextRoot++ {
+		report.add(missingBatch(roots[nextRoot], batch))
+		batch = nil
+	}
+
+	donations, err := src.DonationSnapshots(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading donations: %w", err)
+	}
+	report.Donations = len(donations)
+
+	for _, current := range donations {
+		recorded, ok := logged[current.ID]
+		if !ok {
+			report.add(Problem{DonationID: current.ID, Message: "is not in the audit log"})
+			continue
+		}
+		delete(logged, current.ID)
+		if fields := snapshotDiff(recorded, current); len(fields) > 0 {
+			report.add(Problem{DonationID: current.ID, Message: fmt.Sprintf(
+				"%s changed outside the audit log", strings.Join(fields, ", "))})
+		}
+	}
+	deleted := make([]int, 0, len(logged))
+	for id := range logged {
+		deleted = append(deleted, id)
+	}
+	sort.Ints(deleted)
+	for _, id := range deleted {
+		report.add(Problem{DonationID: id, Message: "was deleted outside the audit log"})
+	}
+
+	return report, nil
+}
+
+// add records a problem
+func (r *Report) add(p Problem) {
+	r.Problems = append(r.Problems, p)
+}
+
+// checkRoot recomputes the Merkle root of a batch
+func checkRoot(root models.AuditRoot, hashes []string) (Problem, bool) {
+	if len(hashes) != root.EntryCount {
+		return Problem{RootID: root.ID, Message: fmt.Sprintf(
+			"covers %d entries but %d remain between entries %d and %d",
+			root.EntryCount, len(hashes), root.FirstEntryID, root.LastEntryID)}, false
+	}
+	computed, err := proof.Root(hashes)
+	if err != nil {
+		return Problem{RootID: root.ID, Message: err.Error()}, false
+	}
+	if computed != root.Root {
+		return Problem{RootID: root.ID, Message: fmt.Sprintf(
+			"Merkle root %s does not match the recomputed root %s", root.Root, computed)}, false
+	}
+	return Problem{}, true
+}
+
+// missingBatch reports a root whose last entry no longer exists
+func missingBatch(root models.AuditRoot, hashes []string) Problem {
+	return Problem{RootID: root.ID, Message: fmt.Sprintf(
+		"entry %d, the last one it covers, is missing (%d of %d entries found)",
+		root.LastEntryID, len(hashes), root.EntryCount)}
+}
+
+// snapshotDiff returns the JSON names of the fields that differ between two
+// snapshots
+func snapshotDiff(a, b models.DonationSnapshot) []string {
+	var fields []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			name, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
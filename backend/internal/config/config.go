@@ -4,15 +4,23 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
-	JWT      JWTConfig
+	Database    DatabaseConfig
+	Server      ServerConfig
+	JWT         JWTConfig
+	Chain       ChainConfig
+	Mail        MailConfig
+	Login       LoginThrottleConfig
+	Audit       AuditConfig
+	Recurring   RecurringConfig
+	Idempotency IdempotencyConfig
 }
 
 // DatabaseConfig holds all database related configuration
@@ -31,12 +39,88 @@ type ServerConfig struct {
 	Port            int
 	Environment     string
 	AllowedOrigins  []string
+	// PublicURL is the API's externally reachable base URL, used in links
+	// printed on receipts
+	PublicURL       string
 }
 
 // JWTConfig holds all JWT related configuration
 type JWTConfig struct {
-	Secret          string
-	ExpirationHours int
+	Secret             string
+	AccessTokenMinutes int
+	RefreshTokenDays   int
+	Issuer             string
+	// SigningKeyFile is a PEM encoded RSA or Ed25519 private key. When set,
+	// tokens are signed with it (RS256 or EdDSA) instead of Secret.
+	SigningKeyFile string
+	SigningKeyID   string
+	// VerificationKeyFiles lists PEM public keys, optionally as "kid=path",
+	// that are still accepted after a signing key has been rotated out
+	VerificationKeyFiles []string
+}
+
+// DefaultJWTSecret is the development-only fallback for JWT_SECRET
+const DefaultJWTSecret = "default_secret_key"
+
+// MailConfig holds all email related configuration
+type MailConfig struct {
+	// Driver selects how mail is delivered: smtp, file or log
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	FileDir      string
+	// AppURL is the frontend base URL used in links sent by email
+	AppURL string
+}
+
+// AuditConfig holds the donation audit log settings
+type AuditConfig struct {
+	// RootIntervalMinutes is how often a Merkle root is committed over new
+	// audit log entries; 0 disables periodic commits
+	RootIntervalMinutes int
+}
+
+// RecurringConfig holds the recurring donation scheduler settings
+type RecurringConfig struct {
+	PollIntervalSeconds int
+	// BatchSize is the most recurring donations run per poll
+	BatchSize int
+}
+
+// IdempotencyConfig holds the Idempotency-Key settings
+type IdempotencyConfig struct {
+	// TTLHours is how long the response to a request with an
+	// Idempotency-Key is kept for replay
+	TTLHours int
+}
+
+// LoginThrottleConfig holds the login brute-force protection settings
+type LoginThrottleConfig struct {
+	// Store selects where failure counters are kept: memory or postgres
+	Store string
+	// LockoutThreshold failed logins lock an account for LockoutMinutes
+	LockoutThreshold int
+	LockoutMinutes   int
+	// AccountFreeAttempts failed logins are allowed per account before backoff
+	AccountFreeAttempts int
+	// IPFreeAttempts failed logins are allowed per client IP before backoff
+	IPFreeAttempts int
+}
+
+// ChainConfig holds all blockchain related configuration
+type ChainConfig struct {
+	RPCURL              string
+	ContractAddress     string
+	ABIPath             string
+	StartBlock          uint64
+	Confirmations       uint64
+	BatchSize           uint64
+	PollIntervalSeconds int
+	// ExplorerURL is the block explorer that ledger entries link to
+	ExplorerURL string
 }
 
 // Load loads the configuration from environment variables
@@ -65,9 +149,85 @@ func Load() (*Config, error) {
 	}
 
 	// JWT config
-	jwtExpiration, err := strconv.Atoi(getEnv("JWT_EXPIRATION_HOURS", "24"))
+	accessTokenMinutes, err := strconv.Atoi(getEnv("JWT_ACCESS_TOKEN_MINUTES", "15"))
+	if err != nil || accessTokenMinutes <= 0 {
+		return nil, fmt.Errorf("invalid JWT_ACCESS_TOKEN_MINUTES: must be a positive integer")
+	}
+
+	refreshTokenDays, err := strconv.Atoi(getEnv("JWT_REFRESH_TOKEN_DAYS", "30"))
+	if err != nil || refreshTokenDays <= 0 {
+		return nil, fmt.Errorf("invalid JWT_REFRESH_TOKEN_DAYS: must be a positive integer")
+	}
+
+	// Chain config
+	startBlock, err := strconv.ParseUint(getEnv("INDEXER_START_BLOCK", "0"), 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("invalid JWT_EXPIRATION_HOURS: %w", err)
+		return nil, fmt.Errorf("invalid INDEXER_START_BLOCK: %w", err)
+	}
+
+	confirmations, err := strconv.ParseUint(getEnv("INDEXER_CONFIRMATIONS", "12"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid INDEXER_CONFIRMATIONS: %w", err)
+	}
+
+	batchSize, err := strconv.ParseUint(getEnv("INDEXER_BATCH_SIZE", "2000"), 10, 64)
+	if err != nil || batchSize == 0 {
+		return nil, fmt.Errorf("invalid INDEXER_BATCH_SIZE: must be a positive integer")
+	}
+
+	pollInterval, err := strconv.Atoi(getEnv("INDEXER_POLL_INTERVAL_SECONDS", "15"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid INDEXER_POLL_INTERVAL_SECONDS: %w", err)
+	}
+
+	// Mail config
+	smtpPort, err := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+	}
+
+	// Login throttle config
+	lockoutThreshold, err := strconv.Atoi(getEnv("LOGIN_LOCKOUT_THRESHOLD", "10"))
+	if err != nil || lockoutThreshold <= 0 {
+		return nil, fmt.Errorf("invalid LOGIN_LOCKOUT_THRESHOLD: must be a positive integer")
+	}
+
+	lockoutMinutes, err := strconv.Atoi(getEnv("LOGIN_LOCKOUT_MINUTES", "15"))
+	if err != nil || lockoutMinutes <= 0 {
+		return nil, fmt.Errorf("invalid LOGIN_LOCKOUT_MINUTES: must be a positive integer")
+	}
+
+	accountFreeAttempts, err := strconv.Atoi(getEnv("LOGIN_ACCOUNT_FREE_ATTEMPTS", "3"))
+	if err != nil || accountFreeAttempts < 0 {
+		return nil, fmt.Errorf("invalid LOGIN_ACCOUNT_FREE_ATTEMPTS: must be a non-negative integer")
+	}
+
+	ipFreeAttempts, err := strconv.Atoi(getEnv("LOGIN_IP_FREE_ATTEMPTS", "20"))
+	if err != nil || ipFreeAttempts < 0 {
+		return nil, fmt.Errorf("invalid LOGIN_IP_FREE_ATTEMPTS: must be a non-negative integer")
+	}
+
+	// Audit config
+	rootInterval, err := strconv.Atoi(getEnv("AUDIT_ROOT_INTERVAL_MINUTES", "60"))
+	if err != nil || rootInterval < 0 {
+		return nil, fmt.Errorf("invalid AUDIT_ROOT_INTERVAL_MINUTES: must be a non-negative integer")
+	}
+
+	// Recurring donation config
+	recurringPollInterval, err := strconv.Atoi(getEnv("RECURRING_POLL_INTERVAL_SECONDS", "60"))
+	if err != nil || recurringPollInterval <= 0 {
+		return nil, fmt.Errorf("invalid RECURRING_POLL_INTERVAL_SECONDS: must be a positive integer")
+	}
+
+	recurringBatchSize, err := strconv.Atoi(getEnv("RECURRING_BATCH_SIZE", "100"))
+	if err != nil || recurringBatchSize <= 0 {
+		return nil, fmt.Errorf("invalid RECURRING_BATCH_SIZE: must be a positive integer")
+	}
+
+	// Idempotency config
+	idempotencyTTL, err := strconv.Atoi(getEnv("IDEMPOTENCY_TTL_HOURS", "24"))
+	if err != nil || idempotencyTTL <= 0 {
+		return nil, fmt.Errorf("invalid IDEMPOTENCY_TTL_HOURS: must be a positive integer")
 	}
 
 	return &Config{
@@ -84,20 +244,118 @@ func Load() (*Config, error) {
 			Port:            apiPort,
 			Environment:     getEnv("ENVIRONMENT", "development"),
 			AllowedOrigins:  strings.Split(getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:5173"), ","),
+			PublicURL:       strings.TrimRight(getEnv("API_PUBLIC_URL", fmt.Sprintf("http://localhost:%d", apiPort)), "/"),
 		},
 		JWT: JWTConfig{
-			Secret:          getEnv("JWT_SECRET", "default_secret_key"),
-			ExpirationHours: jwtExpiration,
+			Secret:               getEnv("JWT_SECRET", DefaultJWTSecret),
+			AccessTokenMinutes:   accessTokenMinutes,
+			RefreshTokenDays:     refreshTokenDays,
+			Issuer:               getEnv("JWT_ISSUER", "transpacharity"),
+			SigningKeyFile:       getEnv("JWT_SIGNING_KEY_FILE", ""),
+			SigningKeyID:         getEnv("JWT_SIGNING_KEY_ID", ""),
+			VerificationKeyFiles: splitList(getEnv("JWT_VERIFICATION_KEY_FILES", "")),
+		},
+		Chain: ChainConfig{
+			RPCURL:              getEnv("ETH_RPC_URL", "http://localhost:8545"),
+			ContractAddress:     getEnv("CONTRACT_ADDRESS", ""),
+			ABIPath:             getEnv("CONTRACT_ABI_PATH", "../contracts/CharityDonation.json"),
+			StartBlock:          startBlock,
+			Confirmations:       confirmations,
+			BatchSize:           batchSize,
+			PollIntervalSeconds: pollInterval,
+			ExplorerURL:         strings.TrimRight(getEnv("CHAIN_EXPLORER_URL", "https://etherscan.io"), "/"),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "TranspaCharity <no-reply@transpacharity.local>"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     smtpPort,
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FileDir:      getEnv("MAIL_FILE_DIR", "tmp/mail"),
+			AppURL:       strings.TrimRight(getEnv("APP_URL", "http://localhost:5173"), "/"),
+		},
+		Login: LoginThrottleConfig{
+			Store:               getEnv("LOGIN_THROTTLE_STORE", "memory"),
+			LockoutThreshold:    lockoutThreshold,
+			LockoutMinutes:      lockoutMinutes,
+			AccountFreeAttempts: accountFreeAttempts,
+			IPFreeAttempts:      ipFreeAttempts,
+		},
+		Audit: AuditConfig{
+			RootIntervalMinutes: rootInterval,
+		},
+		Recurring: RecurringConfig{
+			PollIntervalSeconds: recurringPollInterval,
+			BatchSize:           recurringBatchSize,
+		},
+		Idempotency: IdempotencyConfig{
+			TTLHours: idempotencyTTL,
 		},
 	}, nil
 }
 
+// Validate checks settings that are unsafe outside development
+func (c *Config) Validate() error {
+	if c.Server.Environment == "development" {
+		return nil
+	}
+	if c.JWT.SigningKeyFile == "" && c.JWT.Secret == DefaultJWTSecret {
+		return fmt.Errorf("refusing to run in %s with the default JWT secret: set JWT_SIGNING_KEY_FILE or JWT_SECRET", c.Server.Environment)
+	}
+	// The log and file drivers keep password reset and verification tokens
+	// where anyone reading the server's logs or disk can use them
+	if c.Mail.Driver != "smtp" {
+		return fmt.Errorf("refusing to run in %s with MAIL_DRIVER %q: set MAIL_DRIVER=smtp", c.Server.Environment, c.Mail.Driver)
+	}
+	return nil
+}
+
 // DSN returns the PostgreSQL connection string
 func (c *DatabaseConfig) DSN() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode)
 }
 
+// ResolveABIPath resolves a relative ABIPath against baseDir
+func (c *ChainConfig) ResolveABIPath(baseDir string) string {
+	if filepath.IsAbs(c.ABIPath) {
+		return c.ABIPath
+	}
+	return filepath.Join(baseDir, c.ABIPath)
+}
+
+// AccessTokenTTL returns how long access tokens are valid
+func (c *JWTConfig) AccessTokenTTL() time.Duration {
+	return time.Duration(c.AccessTokenMinutes) * time.Minute
+}
+
+// RefreshTokenTTL returns how long refresh tokens are valid
+func (c *JWTConfig) RefreshTokenTTL() time.Duration {
+	return time.Duration(c.RefreshTokenDays) * 24 * time.Hour
+}
+
+// LockoutDuration returns how long an account stays locked
+func (c *LoginThrottleConfig) LockoutDuration() time.Duration {
+	return time.Duration(c.LockoutMinutes) * time.Minute
+}
+
+// TTL returns how long idempotent responses are kept
+func (c *IdempotencyConfig) TTL() time.Duration {
+	return time.Duration(c.TTLHours) * time.Hour
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -0,0 +1,428 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// schemaPlaceholder is replaced with the configured schema name in every migration
+const schemaPlaceholder = "{{schema}}"
+
+// noTransactionDirective marks a migration that must run outside a transaction
+// (for example CREATE INDEX CONCURRENTLY)
+const noTransactionDirective = "-- migrate:no-transaction"
+
+// migrationFilePattern matches files such as 0002_add_sessions.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrDirtyMigration is returned when a previous migration failed half-way and
+// the schema has to be repaired and forced to a known version
+var ErrDirtyMigration = errors.New("database is in a dirty migration state; fix the schema and run 'migrate force <version>'")
+
+// Migration is a single numbered schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether a known migration has been applied
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+}
+
+// Migrator applies the embedded migrations to the configured schema
+type Migrator struct {
+	db         *sql.DB
+	schema     string
+	migrations []Migration
+}
+
+// NewMigrator creates a Migrator for the database's configured schema
+func (db *DB) NewMigrator() (*Migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db.DB, schema: db.config.Schema, migrations: migrations}, nil
+}
+
+// loadMigrations reads and pairs the embedded up/down files, ordered by version
+func loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		contents, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %q and %q", version, m.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies every pending migration and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if state.dirty {
+			return ErrDirtyMigration
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := state.versions[migration.Version]; ok {
+				continue
+			}
+			log.Printf("Applying migration %d_%s", migration.Version, migration.Name)
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the n most recently applied migrations
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("number of migrations to roll back must be positive, got %d", n)
+	}
+
+	rolledBack := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if state.dirty {
+			return ErrDirtyMigration
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && rolledBack < n; i-- {
+			migration := m.migrations[i]
+			if _, ok := state.versions[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+			log.Printf("Rolling back migration %d_%s", migration.Version, migration.Name)
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			rolledBack++
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if row, ok := state.versions[migration.Version]; ok {
+				appliedAt := row.appliedAt
+				status.Applied = !row.dirty
+				status.Dirty = row.dirty
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Force records the schema as being exactly at version without running any
+// SQL. It clears the dirty flag and is meant for manual recovery.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s.schema_migrations`, m.schema)); err != nil {
+			return fmt.Errorf("error clearing migration history: %w", err)
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if err := m.record(ctx, tx, migration, false); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		log.Printf("Forced migration version to %d", version)
+		return nil
+	})
+}
+
+// find returns the migration with the given version, if any
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// withLock runs fn on a dedicated connection holding a session-level advisory
+// lock, so that replicas booting at the same time apply migrations one by one
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error acquiring connection for migrations: %w", err)
+	}
+	defer conn.Close()
+
+	lockKey := m.lockKey()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			log.Printf("Error releasing migration lock: %v", err)
+		}
+	}()
+
+	if err := m.ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// lockKey derives a stable advisory lock key from the schema name
+func (m *Migrator) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("transpacharity-migrations:" + m.schema))
+	return int64(h.Sum64())
+}
+
+// ensureVersionTable creates the schema and the schema_migrations table
+func (m *Migrator) ensureVersionTable(ctx context.Context, conn *sql.Conn) error {
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s`, m.schema)); err != nil {
+		return fmt.Errorf("error creating %s schema: %w", m.schema, err)
+	}
+
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s.schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			dirty BOOLEAN NOT NULL DEFAULT FALSE,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`, m.schema)
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+
+	return nil
+}
+
+// appliedRow is a row of the schema_migrations table
+type appliedRow struct {
+	dirty     bool
+	appliedAt time.Time
+}
+
+// appliedState is the contents of the schema_migrations table
+type appliedState struct {
+	versions map[int64]appliedRow
+	dirty    bool
+}
+
+// appliedVersions loads the schema_migrations table
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (appliedState, error) {
+	state := appliedState{versions: make(map[int64]appliedRow)}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`
+		SELECT version, dirty, applied_at FROM %s.schema_migrations
+	`, m.schema))
+	if err != nil {
+		return state, fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		var row appliedRow
+		if err := rows.Scan(&version, &row.dirty, &row.appliedAt); err != nil {
+			return state, err
+		}
+		state.versions[version] = row
+		if row.dirty {
+			state.dirty = true
+		}
+	}
+
+	return state, rows.Err()
+}
+
+// apply runs one direction of a migration and updates schema_migrations.
+// Transactional migrations are applied atomically together with their
+// version row; migrations marked no-transaction are bracketed by a dirty flag.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	body := migration.Down
+	if up {
+		body = migration.Up
+	}
+	body = strings.ReplaceAll(body, schemaPlaceholder, m.schema)
+
+	if strings.HasPrefix(strings.TrimSpace(body), noTransactionDirective) {
+		return m.applyWithoutTransaction(ctx, conn, migration, body, up)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return fmt.Errorf("error running migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if up {
+		err = m.record(ctx, tx, migration, false)
+	} else {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s.schema_migrations WHERE version = $1`, m.schema), migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("error recording migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+// applyWithoutTransaction runs a migration that cannot be wrapped in a
+// transaction, leaving the version marked dirty if it fails part-way
+func (m *Migrator) applyWithoutTransaction(ctx context.Context, conn *sql.Conn, migration Migration, body string, up bool) error {
+	if up {
+		if err := m.record(ctx, conn, migration, true); err != nil {
+			return fmt.Errorf("error recording migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	} else {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.schema_migrations SET dirty = TRUE WHERE version = $1
+		`, m.schema), migration.Version); err != nil {
+			return fmt.Errorf("error marking migration %d_%s dirty: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	if _, err := conn.ExecContext(ctx, body); err != nil {
+		return fmt.Errorf("error running migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	var err error
+	if up {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.schema_migrations SET dirty = FALSE WHERE version = $1
+		`, m.schema), migration.Version)
+	} else {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s.schema_migrations WHERE version = $1`, m.schema), migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("error recording migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// execer is satisfied by both *sql.Conn and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// record inserts a schema_migrations row for the migration
+func (m *Migrator) record(ctx context.Context, db execer, migration Migration, dirty bool) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.schema_migrations (version, name, dirty)
+		VALUES ($1, $2, $3)
+	`, m.schema), migration.Version, migration.Name, dirty)
+	return err
+}
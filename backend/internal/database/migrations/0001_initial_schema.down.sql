@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS {{schema}}.donations;
+DROP TABLE IF EXISTS {{schema}}.causes;
+DROP TABLE IF EXISTS {{schema}}.categories;
+DROP TABLE IF EXISTS {{schema}}.users;
@@ -0,0 +1,49 @@
+-- Baseline schema. Uses IF NOT EXISTS so databases created by the old
+-- hard-coded migrations can adopt the versioned engine without changes.
+
+CREATE TABLE IF NOT EXISTS {{schema}}.users (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	email TEXT UNIQUE NOT NULL,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT 'user',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS {{schema}}.categories (
+	id SERIAL PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL,
+	description TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS {{schema}}.causes (
+	id SERIAL PRIMARY KEY,
+	title TEXT NOT NULL,
+	organization TEXT NOT NULL,
+	description TEXT NOT NULL,
+	image_url TEXT NOT NULL,
+	raised_amount REAL DEFAULT 0.0,
+	goal_amount REAL NOT NULL,
+	category_id INTEGER REFERENCES {{schema}}.categories(id),
+	featured INTEGER DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS {{schema}}.donations (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER REFERENCES {{schema}}.users(id),
+	cause_id INTEGER NOT NULL REFERENCES {{schema}}.causes(id),
+	amount REAL NOT NULL,
+	is_anonymous BOOLEAN DEFAULT FALSE,
+	status TEXT NOT NULL DEFAULT 'pending',
+	transaction_id TEXT,
+	transaction_hash TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE {{schema}}.donations ADD COLUMN IF NOT EXISTS transaction_hash TEXT;
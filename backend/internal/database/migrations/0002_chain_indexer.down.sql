@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS {{schema}}.indexer_checkpoints;
+DROP TABLE IF EXISTS {{schema}}.chain_withdrawals;
+
+DROP INDEX IF EXISTS {{schema}}.donations_block_number_idx;
+DROP INDEX IF EXISTS {{schema}}.donations_chain_log_idx;
+
+ALTER TABLE {{schema}}.donations DROP COLUMN IF EXISTS chain_asset;
+ALTER TABLE {{schema}}.donations DROP COLUMN IF EXISTS chain_amount;
+ALTER TABLE {{schema}}.donations DROP COLUMN IF EXISTS chain_donation_id;
+ALTER TABLE {{schema}}.donations DROP COLUMN IF EXISTS donor_address;
+ALTER TABLE {{schema}}.donations DROP COLUMN IF EXISTS log_index;
+ALTER TABLE {{schema}}.donations DROP COLUMN IF EXISTS block_number;
+ALTER TABLE {{schema}}.donations DROP COLUMN IF EXISTS source;
+
+ALTER TABLE {{schema}}.causes DROP COLUMN IF EXISTS chain_charity_id;
@@ -0,0 +1,43 @@
+-- On-chain charity ID registered in the CharityDonation contract for each cause
+ALTER TABLE {{schema}}.causes ADD COLUMN chain_charity_id BIGINT UNIQUE;
+
+-- Where a donation record came from: created through the API ('offchain') or
+-- discovered by the indexer in a DonationMade log ('onchain')
+ALTER TABLE {{schema}}.donations ADD COLUMN source TEXT NOT NULL DEFAULT 'offchain';
+ALTER TABLE {{schema}}.donations ADD COLUMN block_number BIGINT;
+ALTER TABLE {{schema}}.donations ADD COLUMN log_index INTEGER;
+ALTER TABLE {{schema}}.donations ADD COLUMN donor_address TEXT;
+ALTER TABLE {{schema}}.donations ADD COLUMN chain_donation_id NUMERIC(78, 0);
+-- Raw on-chain amount in the asset's smallest unit and the asset it was paid in
+ALTER TABLE {{schema}}.donations ADD COLUMN chain_amount NUMERIC(78, 0);
+ALTER TABLE {{schema}}.donations ADD COLUMN chain_asset TEXT;
+
+CREATE UNIQUE INDEX donations_chain_log_idx
+	ON {{schema}}.donations (transaction_hash, log_index)
+	WHERE log_index IS NOT NULL;
+CREATE INDEX donations_block_number_idx
+	ON {{schema}}.donations (block_number)
+	WHERE block_number IS NOT NULL;
+
+-- UsdcWithdrawn / EthWithdrawn events
+CREATE TABLE {{schema}}.chain_withdrawals (
+	id SERIAL PRIMARY KEY,
+	chain_charity_id BIGINT NOT NULL,
+	cause_id INTEGER REFERENCES {{schema}}.causes(id),
+	wallet_address TEXT NOT NULL,
+	amount NUMERIC(78, 0) NOT NULL,
+	asset TEXT NOT NULL,
+	transaction_hash TEXT NOT NULL,
+	block_number BIGINT NOT NULL,
+	log_index INTEGER NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (transaction_hash, log_index)
+);
+
+-- Last block processed by each indexer, with its hash for reorg detection
+CREATE TABLE {{schema}}.indexer_checkpoints (
+	name TEXT PRIMARY KEY,
+	block_number BIGINT NOT NULL,
+	block_hash TEXT NOT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
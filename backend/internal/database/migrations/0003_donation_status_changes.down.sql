@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS {{schema}}.donation_status_changes;
+
+ALTER TABLE {{schema}}.donations DROP CONSTRAINT IF EXISTS donations_status_check;
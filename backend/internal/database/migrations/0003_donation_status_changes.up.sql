@@ -0,0 +1,19 @@
+ALTER TABLE {{schema}}.donations
+	ADD CONSTRAINT donations_status_check
+	CHECK (status IN ('pending', 'completed', 'failed'));
+
+-- Every status transition of a donation and who or what made it
+CREATE TABLE {{schema}}.donation_status_changes (
+	id SERIAL PRIMARY KEY,
+	donation_id INTEGER NOT NULL REFERENCES {{schema}}.donations(id) ON DELETE CASCADE,
+	from_status TEXT,
+	to_status TEXT NOT NULL,
+	actor_type TEXT NOT NULL,
+	actor_id INTEGER,
+	reason TEXT,
+	transaction_hash TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX donation_status_changes_donation_idx
+	ON {{schema}}.donation_status_changes (donation_id, created_at);
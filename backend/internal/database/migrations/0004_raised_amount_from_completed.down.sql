@@ -0,0 +1,7 @@
+-- Restore the previous behaviour of counting every non-failed donation
+UPDATE {{schema}}.causes c
+SET raised_amount = COALESCE((
+	SELECT SUM(d.amount)
+	FROM {{schema}}.donations d
+	WHERE d.cause_id = c.id AND d.status <> 'failed'
+), 0);
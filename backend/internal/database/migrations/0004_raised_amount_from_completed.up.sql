@@ -0,0 +1,8 @@
+-- Raised amounts used to include pending donations. Recompute them from
+-- completed donations only; from now on they change with donation status.
+UPDATE {{schema}}.causes c
+SET raised_amount = COALESCE((
+	SELECT SUM(d.amount)
+	FROM {{schema}}.donations d
+	WHERE d.cause_id = c.id AND d.status = 'completed'
+), 0);
@@ -0,0 +1,13 @@
+ALTER TABLE {{schema}}.chain_withdrawals
+	ALTER COLUMN amount TYPE NUMERIC(78, 0)
+	USING amount * CASE currency WHEN 'ETH' THEN 1000000000000000000 ELSE 1000000 END;
+ALTER TABLE {{schema}}.chain_withdrawals RENAME COLUMN currency TO asset;
+
+ALTER TABLE {{schema}}.causes ALTER COLUMN raised_amount DROP NOT NULL;
+ALTER TABLE {{schema}}.causes ALTER COLUMN raised_amount TYPE REAL USING raised_amount::real;
+ALTER TABLE {{schema}}.causes ALTER COLUMN raised_amount SET DEFAULT 0.0;
+ALTER TABLE {{schema}}.causes ALTER COLUMN goal_amount TYPE REAL USING goal_amount::real;
+ALTER TABLE {{schema}}.causes DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE {{schema}}.donations ALTER COLUMN amount TYPE REAL USING amount::real;
+ALTER TABLE {{schema}}.donations DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,46 @@
+-- Amounts move from REAL to exact NUMERIC decimals and gain a currency.
+-- Existing off-chain amounts were entered in dollars, so they are rounded to
+-- cents to drop REAL rounding noise. On-chain amounts are restored exactly
+-- from the raw token amounts recorded by the indexer (multiplying rather than
+-- dividing keeps NUMERIC arithmetic exact).
+
+ALTER TABLE {{schema}}.donations
+	ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD'
+	CHECK (currency IN ('USD', 'USDC', 'ETH'));
+
+ALTER TABLE {{schema}}.donations
+	ALTER COLUMN amount TYPE NUMERIC(38, 18) USING ROUND(amount::numeric, 2);
+
+UPDATE {{schema}}.donations
+SET currency = chain_asset,
+	amount = chain_amount * CASE chain_asset WHEN 'ETH' THEN 0.000000000000000001 ELSE 0.000001 END
+WHERE chain_asset IS NOT NULL AND chain_amount IS NOT NULL;
+
+ALTER TABLE {{schema}}.causes
+	ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD'
+	CHECK (currency IN ('USD', 'USDC', 'ETH'));
+
+ALTER TABLE {{schema}}.causes
+	ALTER COLUMN goal_amount TYPE NUMERIC(38, 18) USING ROUND(goal_amount::numeric, 2);
+
+ALTER TABLE {{schema}}.causes ALTER COLUMN raised_amount DROP DEFAULT;
+ALTER TABLE {{schema}}.causes
+	ALTER COLUMN raised_amount TYPE NUMERIC(38, 18) USING ROUND(raised_amount::numeric, 2);
+ALTER TABLE {{schema}}.causes ALTER COLUMN raised_amount SET DEFAULT 0;
+UPDATE {{schema}}.causes SET raised_amount = 0 WHERE raised_amount IS NULL;
+ALTER TABLE {{schema}}.causes ALTER COLUMN raised_amount SET NOT NULL;
+
+-- A cause raises money in its own currency; donations in other currencies are
+-- recorded but not converted
+UPDATE {{schema}}.causes c
+SET raised_amount = COALESCE((
+	SELECT SUM(d.amount)
+	FROM {{schema}}.donations d
+	WHERE d.cause_id = c.id AND d.status = 'completed' AND d.currency = c.currency
+), 0);
+
+-- Withdrawals switch from raw token units to the same decimal representation
+ALTER TABLE {{schema}}.chain_withdrawals RENAME COLUMN asset TO currency;
+ALTER TABLE {{schema}}.chain_withdrawals
+	ALTER COLUMN amount TYPE NUMERIC(38, 18)
+	USING amount * CASE currency WHEN 'ETH' THEN 0.000000000000000001 ELSE 0.000001 END;
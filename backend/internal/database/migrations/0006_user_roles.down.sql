@@ -0,0 +1 @@
+ALTER TABLE {{schema}}.users DROP CONSTRAINT IF EXISTS users_role_check;
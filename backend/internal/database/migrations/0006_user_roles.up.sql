@@ -0,0 +1,7 @@
+-- Registration used to write any requested role, including an empty one.
+-- Unknown roles become plain users; existing admins must be reviewed by hand.
+UPDATE {{schema}}.users SET role = 'user' WHERE role NOT IN ('user', 'admin');
+
+ALTER TABLE {{schema}}.users
+	ADD CONSTRAINT users_role_check
+	CHECK (role IN ('user', 'admin'));
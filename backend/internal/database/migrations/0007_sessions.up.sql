@@ -0,0 +1,19 @@
+-- One row per refresh token. Rotating a refresh token adds a row to the same
+-- family; revoking a family logs out every token that descends from a login.
+CREATE TABLE {{schema}}.sessions (
+	id SERIAL PRIMARY KEY,
+	family_id TEXT NOT NULL,
+	user_id INTEGER NOT NULL REFERENCES {{schema}}.users(id) ON DELETE CASCADE,
+	token_hash TEXT NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	rotated_at TIMESTAMPTZ,
+	replaced_by INTEGER REFERENCES {{schema}}.sessions(id) ON DELETE SET NULL,
+	revoked_at TIMESTAMPTZ,
+	revoked_reason TEXT,
+	user_agent TEXT,
+	ip_address TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX sessions_family_idx ON {{schema}}.sessions (family_id);
+CREATE INDEX sessions_user_idx ON {{schema}}.sessions (user_id);
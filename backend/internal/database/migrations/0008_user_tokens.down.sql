@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS {{schema}}.user_tokens;
+
+ALTER TABLE {{schema}}.users DROP COLUMN IF EXISTS email_verified;
@@ -0,0 +1,15 @@
+ALTER TABLE {{schema}}.users
+	ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Single-use tokens sent by email. Only a hash of each token is stored.
+CREATE TABLE {{schema}}.user_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES {{schema}}.users(id) ON DELETE CASCADE,
+	purpose TEXT NOT NULL CHECK (purpose IN ('password_reset', 'email_verification')),
+	token_hash TEXT NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX user_tokens_user_idx ON {{schema}}.user_tokens (user_id, purpose);
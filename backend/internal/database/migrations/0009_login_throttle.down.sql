@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS {{schema}}.login_throttle;
+DROP TABLE IF EXISTS {{schema}}.login_attempts;
@@ -0,0 +1,22 @@
+-- Every login attempt, kept for auditing. user_id is only set when the email
+-- belongs to an account.
+CREATE TABLE {{schema}}.login_attempts (
+	id BIGSERIAL PRIMARY KEY,
+	email TEXT NOT NULL,
+	user_id INTEGER REFERENCES {{schema}}.users(id) ON DELETE SET NULL,
+	ip_address TEXT,
+	user_agent TEXT,
+	result TEXT NOT NULL CHECK (result IN ('success', 'invalid_credentials', 'throttled')),
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX login_attempts_email_idx ON {{schema}}.login_attempts (email, created_at);
+CREATE INDEX login_attempts_ip_idx ON {{schema}}.login_attempts (ip_address, created_at);
+
+-- Failure counters used by the postgres login throttle store
+CREATE TABLE {{schema}}.login_throttle (
+	key TEXT PRIMARY KEY,
+	failures INTEGER NOT NULL,
+	last_failure_at TIMESTAMPTZ NOT NULL,
+	blocked_until TIMESTAMPTZ
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS {{schema}}.donations_user_created_at_idx;
+DROP INDEX IF EXISTS {{schema}}.donations_cause_created_at_idx;
+DROP INDEX IF EXISTS {{schema}}.donations_amount_idx;
+DROP INDEX IF EXISTS {{schema}}.donations_created_at_idx;
+DROP INDEX IF EXISTS {{schema}}.causes_category_created_at_idx;
+DROP INDEX IF EXISTS {{schema}}.causes_created_at_idx;
@@ -0,0 +1,8 @@
+-- Indexes backing the keyset pagination of the cause and donation lists. The
+-- id column breaks ties between rows with the same sort value.
+CREATE INDEX causes_created_at_idx ON {{schema}}.causes (created_at, id);
+CREATE INDEX causes_category_created_at_idx ON {{schema}}.causes (category_id, created_at, id);
+CREATE INDEX donations_created_at_idx ON {{schema}}.donations (created_at, id);
+CREATE INDEX donations_amount_idx ON {{schema}}.donations (amount, id);
+CREATE INDEX donations_cause_created_at_idx ON {{schema}}.donations (cause_id, created_at, id);
+CREATE INDEX donations_user_created_at_idx ON {{schema}}.donations (user_id, created_at, id);
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS categories_search_vector_trigger ON {{schema}}.categories;
+DROP FUNCTION IF EXISTS {{schema}}.categories_search_vector_update();
+DROP TRIGGER IF EXISTS causes_search_vector_trigger ON {{schema}}.causes;
+DROP FUNCTION IF EXISTS {{schema}}.causes_search_vector_update();
+DROP FUNCTION IF EXISTS {{schema}}.cause_search_text(TEXT, "char");
+DROP INDEX IF EXISTS {{schema}}.causes_search_idx;
+ALTER TABLE {{schema}}.causes DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,47 @@
+-- Full-text search over causes. The vector weights the title above the
+-- organization, category name and description, and is kept up to date by
+-- triggers because it depends on the category's name. Every word is indexed
+-- both stemmed ('english') and as written ('simple'): stemmed lexemes match
+-- complete words in any form, unstemmed ones let a partly typed word match as
+-- a prefix.
+ALTER TABLE {{schema}}.causes ADD COLUMN search_vector tsvector;
+
+CREATE FUNCTION {{schema}}.cause_search_text(body TEXT, weight "char") RETURNS tsvector AS $$
+	SELECT setweight(to_tsvector('english', COALESCE(body, '')), weight) ||
+		setweight(to_tsvector('simple', COALESCE(body, '')), weight)
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE FUNCTION {{schema}}.causes_search_vector_update() RETURNS trigger AS $$
+BEGIN
+	NEW.search_vector :=
+		{{schema}}.cause_search_text(NEW.title, 'A') ||
+		{{schema}}.cause_search_text(NEW.organization, 'B') ||
+		{{schema}}.cause_search_text((
+			SELECT name FROM {{schema}}.categories WHERE id = NEW.category_id
+		), 'C') ||
+		{{schema}}.cause_search_text(NEW.description, 'D');
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER causes_search_vector_trigger
+	BEFORE INSERT OR UPDATE OF title, organization, description, category_id
+	ON {{schema}}.causes
+	FOR EACH ROW EXECUTE FUNCTION {{schema}}.causes_search_vector_update();
+
+-- Renaming a category re-indexes its causes
+CREATE FUNCTION {{schema}}.categories_search_vector_update() RETURNS trigger AS $$
+BEGIN
+	UPDATE {{schema}}.causes SET category_id = category_id WHERE category_id = NEW.id;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER categories_search_vector_trigger
+	AFTER UPDATE OF name ON {{schema}}.categories
+	FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+	EXECUTE FUNCTION {{schema}}.categories_search_vector_update();
+
+UPDATE {{schema}}.causes SET category_id = category_id;
+
+CREATE INDEX causes_search_idx ON {{schema}}.causes USING GIN (search_vector);
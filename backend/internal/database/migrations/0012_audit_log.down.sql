@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS {{schema}}.audit_roots;
+DROP TABLE IF EXISTS {{schema}}.audit_log;
+DROP FUNCTION IF EXISTS {{schema}}.audit_log_append_only();
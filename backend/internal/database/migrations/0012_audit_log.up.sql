@@ -0,0 +1,44 @@
+-- Append-only, hash-chained log of every change to a donation. Each entry's
+-- hash is the SHA-256 of the previous entry's hash followed by its payload, so
+-- editing or removing an entry breaks the chain from that point on.
+-- donation_id has no foreign key because entries outlive removed donations.
+CREATE TABLE {{schema}}.audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	entry_type TEXT NOT NULL,
+	donation_id INTEGER NOT NULL,
+	payload TEXT NOT NULL,
+	prev_hash TEXT NOT NULL,
+	hash TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_log_donation_idx ON {{schema}}.audit_log (donation_id, id);
+
+-- The application never changes an entry once written. The trigger only stops
+-- accidents; the hash chain is what detects deliberate edits.
+CREATE FUNCTION {{schema}}.audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only_trigger
+	BEFORE UPDATE OR DELETE ON {{schema}}.audit_log
+	FOR EACH ROW EXECUTE FUNCTION {{schema}}.audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate_trigger
+	BEFORE TRUNCATE ON {{schema}}.audit_log
+	FOR EACH STATEMENT EXECUTE FUNCTION {{schema}}.audit_log_append_only();
+
+-- Merkle roots over consecutive batches of audit log entries, published so
+-- that donors can prove their donation is included. anchor_transaction_hash
+-- is set once a root has been written on-chain.
+CREATE TABLE {{schema}}.audit_roots (
+	id SERIAL PRIMARY KEY,
+	first_entry_id BIGINT NOT NULL,
+	last_entry_id BIGINT NOT NULL UNIQUE,
+	entry_count INTEGER NOT NULL,
+	root TEXT NOT NULL,
+	anchor_transaction_hash TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS {{schema}}.disbursement_documents;
+DROP TABLE IF EXISTS {{schema}}.disbursements;
+DROP INDEX IF EXISTS {{schema}}.causes_owner_idx;
+ALTER TABLE {{schema}}.causes DROP COLUMN IF EXISTS owner_id;
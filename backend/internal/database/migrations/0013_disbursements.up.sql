@@ -0,0 +1,41 @@
+-- The user who runs a cause and may report how its funds are spent
+ALTER TABLE {{schema}}.causes
+	ADD COLUMN owner_id INTEGER REFERENCES {{schema}}.users(id) ON DELETE SET NULL;
+
+CREATE INDEX causes_owner_idx ON {{schema}}.causes (owner_id) WHERE owner_id IS NOT NULL;
+
+-- Spending reports: money paid out of a cause's funds, optionally against the
+-- on-chain withdrawal it came from. A chain reorganisation that removes the
+-- withdrawal unlinks the report rather than deleting it.
+CREATE TABLE {{schema}}.disbursements (
+	id SERIAL PRIMARY KEY,
+	cause_id INTEGER NOT NULL REFERENCES {{schema}}.causes(id),
+	withdrawal_id INTEGER REFERENCES {{schema}}.chain_withdrawals(id) ON DELETE SET NULL,
+	amount NUMERIC(38, 18) NOT NULL CHECK (amount > 0),
+	currency TEXT NOT NULL,
+	transaction_hash TEXT,
+	recipient_wallet TEXT,
+	purpose TEXT NOT NULL,
+	spent_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	reported_by INTEGER REFERENCES {{schema}}.users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX disbursements_cause_spent_idx ON {{schema}}.disbursements (cause_id, spent_at, id);
+CREATE INDEX disbursements_withdrawal_idx ON {{schema}}.disbursements (withdrawal_id)
+	WHERE withdrawal_id IS NOT NULL;
+
+-- Receipts and invoices backing a disbursement. Documents are stored
+-- elsewhere; sha256 lets readers check that a document has not changed.
+CREATE TABLE {{schema}}.disbursement_documents (
+	id SERIAL PRIMARY KEY,
+	disbursement_id INTEGER NOT NULL REFERENCES {{schema}}.disbursements(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	url TEXT NOT NULL,
+	sha256 TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX disbursement_documents_disbursement_idx
+	ON {{schema}}.disbursement_documents (disbursement_id);
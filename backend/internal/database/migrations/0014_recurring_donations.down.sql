@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS {{schema}}.donations_recurring_run_idx;
+ALTER TABLE {{schema}}.donations
+	DROP COLUMN IF EXISTS recurring_run_at,
+	DROP COLUMN IF EXISTS recurring_donation_id;
+DROP TABLE IF EXISTS {{schema}}.recurring_donations;
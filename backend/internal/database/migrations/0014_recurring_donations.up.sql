@@ -0,0 +1,39 @@
+-- Standing instructions to donate to a cause every week or month. Run n of a
+-- schedule is due at start_at plus n intervals; run_count is the next run
+-- that has not been made or skipped. next_attempt_at is when the scheduler
+-- will next try to make it, which backs off after failures.
+CREATE TABLE {{schema}}.recurring_donations (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES {{schema}}.users(id) ON DELETE CASCADE,
+	cause_id INTEGER NOT NULL REFERENCES {{schema}}.causes(id),
+	amount NUMERIC(38, 18) NOT NULL CHECK (amount > 0),
+	currency TEXT NOT NULL,
+	is_anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+	frequency TEXT NOT NULL CHECK (frequency IN ('weekly', 'monthly')),
+	status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'cancelled')),
+	start_at TIMESTAMPTZ NOT NULL,
+	run_count INTEGER NOT NULL DEFAULT 0,
+	next_run_at TIMESTAMPTZ NOT NULL,
+	next_attempt_at TIMESTAMPTZ NOT NULL,
+	last_run_at TIMESTAMPTZ,
+	last_donation_id INTEGER REFERENCES {{schema}}.donations(id) ON DELETE SET NULL,
+	failure_count INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	cancelled_at TIMESTAMP
+);
+
+CREATE INDEX recurring_donations_user_idx ON {{schema}}.recurring_donations (user_id);
+CREATE INDEX recurring_donations_due_idx ON {{schema}}.recurring_donations (next_attempt_at)
+	WHERE status = 'active';
+
+-- Donations made by a recurring donation record the run they were made for,
+-- so that each run creates at most one donation however often it is retried
+ALTER TABLE {{schema}}.donations
+	ADD COLUMN recurring_donation_id INTEGER REFERENCES {{schema}}.recurring_donations(id) ON DELETE SET NULL,
+	ADD COLUMN recurring_run_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX donations_recurring_run_idx
+	ON {{schema}}.donations (recurring_donation_id, recurring_run_at)
+	WHERE recurring_donation_id IS NOT NULL;
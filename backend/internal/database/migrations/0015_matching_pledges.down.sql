@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS {{schema}}.donations_matched_donation_idx;
+DROP INDEX IF EXISTS {{schema}}.donations_matched_idx;
+ALTER TABLE {{schema}}.donations
+	DROP COLUMN IF EXISTS matched_donation_id,
+	DROP COLUMN IF EXISTS matching_pledge_id;
+DROP TABLE IF EXISTS {{schema}}.matching_pledges;
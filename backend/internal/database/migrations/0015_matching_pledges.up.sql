@@ -0,0 +1,33 @@
+-- Sponsors' promises to match donations to a cause, one for one, until
+-- matched_amount reaches cap or ends_at passes. Only donations in the
+-- pledge's currency are matched.
+CREATE TABLE {{schema}}.matching_pledges (
+	id SERIAL PRIMARY KEY,
+	cause_id INTEGER NOT NULL REFERENCES {{schema}}.causes(id),
+	sponsor_name TEXT NOT NULL,
+	currency TEXT NOT NULL,
+	cap NUMERIC(38, 18) NOT NULL CHECK (cap > 0),
+	matched_amount NUMERIC(38, 18) NOT NULL DEFAULT 0 CHECK (matched_amount >= 0),
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ NOT NULL,
+	closed_at TIMESTAMPTZ,
+	created_by INTEGER REFERENCES {{schema}}.users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CHECK (matched_amount <= cap),
+	CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX matching_pledges_cause_idx ON {{schema}}.matching_pledges (cause_id);
+
+-- A matching donation records the pledge that paid for it and the donation it
+-- matched. Each pledge matches a donation at most once.
+ALTER TABLE {{schema}}.donations
+	ADD COLUMN matching_pledge_id INTEGER REFERENCES {{schema}}.matching_pledges(id),
+	ADD COLUMN matched_donation_id INTEGER REFERENCES {{schema}}.donations(id);
+
+CREATE UNIQUE INDEX donations_matched_idx
+	ON {{schema}}.donations (matching_pledge_id, matched_donation_id)
+	WHERE matching_pledge_id IS NOT NULL;
+CREATE INDEX donations_matched_donation_idx ON {{schema}}.donations (matched_donation_id)
+	WHERE matched_donation_id IS NOT NULL;
@@ -0,0 +1,20 @@
+-- Responses to mutating requests sent with an Idempotency-Key header, replayed
+-- when the same request is retried. scope is "user:<id>" for authenticated
+-- requests and "guest:<fingerprint>" for guests, so that a guest's key only
+-- matches the same request body. status_code is NULL while the first
+-- request is still running; locked_until lets another request take over the
+-- key if that request died without releasing it.
+CREATE TABLE {{schema}}.idempotency_keys (
+	scope TEXT NOT NULL,
+	key TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	status_code INTEGER,
+	content_type TEXT,
+	response_body BYTEA,
+	locked_until TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON {{schema}}.idempotency_keys (expires_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS {{schema}}.donation_receipts;
+ALTER TABLE {{schema}}.causes
+	DROP COLUMN IF EXISTS tax_deductible,
+	DROP COLUMN IF EXISTS tax_id;
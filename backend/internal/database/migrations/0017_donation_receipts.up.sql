@@ -0,0 +1,16 @@
+-- Tax details printed on donation receipts. tax_id is the organization's
+-- charity registration or tax identification number.
+ALTER TABLE {{schema}}.causes
+	ADD COLUMN tax_id TEXT NOT NULL DEFAULT '',
+	ADD COLUMN tax_deductible BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Receipts issued for completed donations. Numbers are assigned in sequence
+-- without gaps the first time a receipt is requested. A chain reorganisation
+-- that removes the donation keeps the number but voids the receipt.
+-- verification_code is the unguessable part of the receipt's verification URL.
+CREATE TABLE {{schema}}.donation_receipts (
+	number BIGINT PRIMARY KEY,
+	donation_id INTEGER UNIQUE REFERENCES {{schema}}.donations(id) ON DELETE SET NULL,
+	verification_code TEXT NOT NULL UNIQUE,
+	issued_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS {{schema}}.donations_transaction_hash_idx;
@@ -0,0 +1,7 @@
+-- A transaction can only back one donation claimed through the API. Donations
+-- the indexer recorded from DonationMade logs carry a log_index and may share
+-- a transaction that emitted several logs; donations_chain_log_idx keeps
+-- those apart.
+CREATE UNIQUE INDEX donations_transaction_hash_idx
+	ON {{schema}}.donations (LOWER(transaction_hash))
+	WHERE transaction_hash IS NOT NULL AND log_index IS NULL;
@@ -0,0 +1,257 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ErrUnknownEvent is returned when a log's signature is not in the ABI
+var ErrUnknownEvent = errors.New("unknown event")
+
+// Argument is an event input declared in the ABI
+type Argument struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Indexed bool   `json:"indexed"`
+}
+
+// Event is an event declared in the ABI
+type Event struct {
+	Name   string
+	Inputs []Argument
+	// Topic is the keccak256 hash of the event signature (topics[0] of its logs)
+	Topic string
+}
+
+// ABI holds the events of a contract ABI
+type ABI struct {
+	Events map[string]*Event
+	topics map[string]*Event
+}
+
+// abiEntry is a single element of a JSON ABI
+type abiEntry struct {
+	Type   string     `json:"type"`
+	Name   string     `json:"name"`
+	Inputs []Argument `json:"inputs"`
+}
+
+// LoadABI reads a JSON ABI from a file
+func LoadABI(path string) (*ABI, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open ABI file: %w", err)
+	}
+	defer f.Close()
+	return ParseABI(f)
+}
+
+// ParseABI parses a JSON ABI. Both a bare ABI array and a compiler artifact
+// with an "abi" field are accepted.
+func ParseABI(r io.Reader) (*ABI, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []abiEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		var artifact struct {
+			ABI []abiEntry `json:"abi"`
+		}
+		if err2 := json.Unmarshal(raw, &artifact); err2 != nil || artifact.ABI == nil {
+			return nil, fmt.Errorf("invalid ABI JSON: %w", err)
+		}
+		entries = artifact.ABI
+	}
+
+	abi := &ABI{
+		Events: make(map[string]*Event),
+		topics: make(map[string]*Event),
+	}
+	for _, entry := range entries {
+		if entry.Type != "event" {
+			continue
+		}
+		event := &Event{Name: entry.Name, Inputs: entry.Inputs}
+		event.Topic = eventTopic(event)
+		abi.Events[event.Name] = event
+		abi.topics[event.Topic] = event
+	}
+
+	return abi, nil
+}
+
+// Keccak256 returns the legacy Keccak-256 hash used by Ethereum
+func Keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// eventTopic computes topics[0] for an event, e.g. keccak256("Transfer(address,address,uint256)")
+func eventTopic(event *Event) string {
+	types := make([]string, len(event.Inputs))
+	for i, input := range event.Inputs {
+		types[i] = input.Type
+	}
+	signature := fmt.Sprintf("%s(%s)", event.Name, strings.Join(types, ","))
+	return "0x" + hex.EncodeToString(Keccak256([]byte(signature)))
+}
+
+// EventByTopic returns the event whose signature hash is topic
+func (a *ABI) EventByTopic(topic string) (*Event, bool) {
+	event, ok := a.topics[strings.ToLower(topic)]
+	return event, ok
+}
+
+// Topics returns the signature hashes of the named events, for use in a log filter
+func (a *ABI) Topics(names ...string) ([]string, error) {
+	topics := make([]string, 0, len(names))
+	for _, name := range names {
+		event, ok := a.Events[name]
+		if !ok {
+			return nil, fmt.Errorf("event %s not found in ABI", name)
+		}
+		topics = append(topics, event.Topic)
+	}
+	return topics, nil
+}
+
+// Decode unpacks a log into its event and named arguments. Integers decode to
+// *big.Int, addresses to lower-case hex strings, bools to bool, strings and
+// bytes to string and []byte. Indexed dynamic values decode to their topic hash.
+func (a *ABI) Decode(log Log) (*Event, map[string]interface{}, error) {
+	if len(log.Topics) == 0 {
+		return nil, nil, ErrUnknownEvent
+	}
+	event, ok := a.EventByTopic(log.Topics[0])
+	if !ok {
+		return nil, nil, ErrUnknownEvent
+	}
+
+	data, err := DecodeHex(log.Data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid log data: %w", err)
+	}
+
+	values := make(map[string]interface{}, len(event.Inputs))
+	topicIndex := 1
+	dataIndex := 0
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			if topicIndex >= len(log.Topics) {
+				return nil, nil, fmt.Errorf("%s: missing topic for %s", event.Name, input.Name)
+			}
+			word, err := DecodeHex(log.Topics[topicIndex])
+			if err != nil || len(word) != 32 {
+				return nil, nil, fmt.Errorf("%s: invalid topic for %s", event.Name, input.Name)
+			}
+			topicIndex++
+
+			if isDynamic(input.Type) {
+				values[input.Name] = "0x" + hex.EncodeToString(word)
+				continue
+			}
+			value, err := decodeStatic(input.Type, word)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s.%s: %w", event.Name, input.Name, err)
+			}
+			values[input.Name] = value
+			continue
+		}
+
+		word, err := wordAt(data, dataIndex)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s.%s: %w", event.Name, input.Name, err)
+		}
+		dataIndex++
+
+		var value interface{}
+		if isDynamic(input.Type) {
+			value, err = decodeDynamic(input.Type, data, word)
+		} else {
+			value, err = decodeStatic(input.Type, word)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s.%s: %w", event.Name, input.Name, err)
+		}
+		values[input.Name] = value
+	}
+
+	return event, values, nil
+}
+
+// isDynamic reports whether an ABI type is encoded out-of-line
+func isDynamic(typ string) bool {
+	return typ == "string" || typ == "bytes" || strings.HasSuffix(typ, "[]")
+}
+
+// wordAt returns the i-th 32-byte word of data
+func wordAt(data []byte, i int) ([]byte, error) {
+	start := i * 32
+	if start+32 > len(data) {
+		return nil, errors.New("data too short")
+	}
+	return data[start : start+32], nil
+}
+
+// decodeStatic decodes a single static ABI word
+func decodeStatic(typ string, word []byte) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(typ, "uint"):
+		return wordToBig(word), nil
+	case strings.HasPrefix(typ, "int"):
+		v := wordToBig(word)
+		if word[0]&0x80 != 0 {
+			v.Sub(v, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return v, nil
+	case typ == "address":
+		return "0x" + hex.EncodeToString(word[12:]), nil
+	case typ == "bool":
+		return word[31] == 1, nil
+	case strings.HasPrefix(typ, "bytes"):
+		var size int
+		if _, err := fmt.Sscanf(typ, "bytes%d", &size); err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("unsupported type %s", typ)
+		}
+		return "0x" + hex.EncodeToString(word[:size]), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+}
+
+// decodeDynamic decodes a string or bytes value whose offset is held in head
+func decodeDynamic(typ string, data, head []byte) (interface{}, error) {
+	if typ != "string" && typ != "bytes" {
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+
+	offset := wordToBig(head)
+	if !offset.IsInt64() || offset.Int64()+32 > int64(len(data)) {
+		return nil, errors.New("offset out of range")
+	}
+	start := int(offset.Int64())
+
+	length := wordToBig(data[start : start+32])
+	if !length.IsInt64() || int64(start+32)+length.Int64() > int64(len(data)) {
+		return nil, errors.New("length out of range")
+	}
+	value := data[start+32 : start+32+int(length.Int64())]
+
+	if typ == "string" {
+		return string(value), nil
+	}
+	return value, nil
+}
@@ -0,0 +1,145 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotFound is returned when the node has no receipt or block for the request
+var ErrNotFound = errors.New("not found")
+
+// RPCError is an error object returned by a JSON-RPC endpoint
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// Client is a minimal Ethereum JSON-RPC client over HTTP
+type Client struct {
+	url        string
+	httpClient *http.Client
+	nextID     atomic.Uint64
+}
+
+// NewClient creates a new Client for the given JSON-RPC endpoint
+func NewClient(url string) *Client {
+	return &Client{
+		url:        url,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// rpcRequest is a JSON-RPC 2.0 request
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response
+type rpcResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// Call invokes method with params and decodes the result into result
+func (c *Client) Call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextID.Add(1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected HTTP status %s", method, resp.Status)
+	}
+
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s: %w", method, rpcResp.Error)
+	}
+	if len(rpcResp.Result) == 0 || string(rpcResp.Result) == "null" {
+		return ErrNotFound
+	}
+
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("%s: invalid result: %w", method, err)
+	}
+	return nil
+}
+
+// BlockNumber returns the number of the most recent block
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var number Quantity
+	if err := c.Call(ctx, "eth_blockNumber", &number); err != nil {
+		return 0, err
+	}
+	return uint64(number), nil
+}
+
+// HeaderByNumber returns the header of the block with the given number
+func (c *Client) HeaderByNumber(ctx context.Context, number uint64) (*Header, error) {
+	var header Header
+	if err := c.Call(ctx, "eth_getBlockByNumber", &header, EncodeQuantity(number), false); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// GetLogs returns the logs matching the filter
+func (c *Client) GetLogs(ctx context.Context, query FilterQuery) ([]Log, error) {
+	var logs []Log
+	if err := c.Call(ctx, "eth_getLogs", &logs, query); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return logs, nil
+}
+
+// TransactionReceipt returns the receipt of a mined transaction, or
+// ErrNotFound if the transaction is unknown or still pending
+func (c *Client) TransactionReceipt(ctx context.Context, txHash string) (*Receipt, error) {
+	var receipt Receipt
+	if err := c.Call(ctx, "eth_getTransactionReceipt", &receipt, txHash); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
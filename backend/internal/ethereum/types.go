@@ -0,0 +1,147 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Quantity is a JSON-RPC hex encoded unsigned integer such as "0x1b4"
+type Quantity uint64
+
+// UnmarshalJSON decodes a hex quantity
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("quantity must be a hex string: %w", err)
+	}
+	v, err := ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = Quantity(v)
+	return nil
+}
+
+// MarshalJSON encodes a hex quantity
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(EncodeQuantity(uint64(q)))
+}
+
+// ParseQuantity parses a 0x-prefixed hex quantity
+func ParseQuantity(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	v, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hex quantity %q: %w", s, err)
+	}
+	return v, nil
+}
+
+// EncodeQuantity encodes a value as a 0x-prefixed hex quantity
+func EncodeQuantity(v uint64) string {
+	return "0x" + strconv.FormatUint(v, 16)
+}
+
+// DecodeHex decodes 0x-prefixed hex data
+func DecodeHex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(s, "0x")
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	return hex.DecodeString(s)
+}
+
+// NormalizeAddress lower-cases an address and validates its length
+func NormalizeAddress(address string) (string, error) {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if !strings.HasPrefix(address, "0x") || len(address) != 42 {
+		return "", fmt.Errorf("invalid address %q", address)
+	}
+	if _, err := hex.DecodeString(address[2:]); err != nil {
+		return "", fmt.Errorf("invalid address %q", address)
+	}
+	return address, nil
+}
+
+// NormalizeHash lower-cases a 32-byte hash and validates its length
+func NormalizeHash(hash string) (string, error) {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if !strings.HasPrefix(hash, "0x") || len(hash) != 66 {
+		return "", fmt.Errorf("invalid hash %q", hash)
+	}
+	if _, err := hex.DecodeString(hash[2:]); err != nil {
+		return "", fmt.Errorf("invalid hash %q", hash)
+	}
+	return hash, nil
+}
+
+// Log is an event log returned by eth_getLogs or inside a receipt
+type Log struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      Quantity `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex Quantity `json:"transactionIndex"`
+	LogIndex         Quantity `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+}
+
+// Header is the subset of a block returned by eth_getBlockByNumber that we use
+type Header struct {
+	Number    Quantity `json:"number"`
+	Hash      string   `json:"hash"`
+	Timestamp Quantity `json:"timestamp"`
+}
+
+// Receipt is a transaction receipt returned by eth_getTransactionReceipt
+type Receipt struct {
+	TransactionHash string   `json:"transactionHash"`
+	BlockNumber     Quantity `json:"blockNumber"`
+	BlockHash       string   `json:"blockHash"`
+	From            string   `json:"from"`
+	To              string   `json:"to"`
+	Status          Quantity `json:"status"`
+	Logs            []Log    `json:"logs"`
+}
+
+// Succeeded reports whether the transaction executed successfully
+func (r *Receipt) Succeeded() bool {
+	return r.Status == 1
+}
+
+// FilterQuery selects logs for eth_getLogs
+type FilterQuery struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Addresses []string
+	// Topics[0] lists acceptable event signatures; later positions filter indexed arguments
+	Topics [][]string
+}
+
+// MarshalJSON encodes the filter in the JSON-RPC wire format
+func (q FilterQuery) MarshalJSON() ([]byte, error) {
+	filter := map[string]interface{}{
+		"fromBlock": EncodeQuantity(q.FromBlock),
+		"toBlock":   EncodeQuantity(q.ToBlock),
+	}
+	if len(q.Addresses) > 0 {
+		filter["address"] = q.Addresses
+	}
+	if len(q.Topics) > 0 {
+		filter["topics"] = q.Topics
+	}
+	return json.Marshal(filter)
+}
+
+// wordToBig interprets a 32-byte ABI word as an unsigned integer
+func wordToBig(word []byte) *big.Int {
+	return new(big.Int).SetBytes(word)
+}
@@ -0,0 +1,192 @@
+// Package export writes donations for finance teams as CSV, JSON lines or
+// XLSX. Writers stream: each donation is written as it is passed in, so an
+// export's memory use does not grow with its length.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// Format is an export file format
+type Format string
+
+const (
+	CSV       Format = "csv"
+	JSONLines Format = "jsonl"
+	XLSX      Format = "xlsx"
+)
+
+// ParseFormat parses a format name, which is also its file extension
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case CSV, JSONLines, XLSX:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown export format %q: must be one of csv, jsonl, xlsx", s)
+}
+
+// ContentType returns the media type of files in the format
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case JSONLines:
+		return "application/x-ndjson"
+	case XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// Writer writes donations one at a time. Close must be called to complete
+// the file; it does not close the underlying writer.
+type Writer interface {
+	Write(d models.Donation) error
+	Close() error
+}
+
+// NewDonationWriter returns a writer of donations to w in format
+func NewDonationWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w), nil
+	case JSONLines:
+		return &jsonWriter{enc: json.NewEncoder(w)}, nil
+	case XLSX:
+		return newXLSXWriter(w, "Donations"), nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// kind is how a column's values are typed in a spreadsheet
+type kind int
+
+const (
+	text kind = iota
+	number
+	boolean
+	timestamp
+)
+
+// column is one column of a CSV or XLSX export. value returns "" for an
+// empty cell.
+type column struct {
+	name  string
+	kind  kind
+	value func(d *models.Donation) string
+}
+
+// columns are the columns of the CSV and XLSX exports. The JSON lines export
+// has the same fields as the donations API instead.
+var columns = []column{
+	{"id", number, func(d *models.Donation) string { return strconv.Itoa(d.ID) }},
+	{"created_at", timestamp, func(d *models.Donation) string { return formatTime(d.CreatedAt) }},
+	{"updated_at", timestamp, func(d *models.Donation) string { return formatTime(d.UpdatedAt) }},
+	{"status", text, func(d *models.Donation) string { return string(d.Status) }},
+	{"amount", number, func(d *models.Donation) string { return d.Amount.String() }},
+	{"currency", text, func(d *models.Donation) string { return string(d.Currency) }},
+	{"cause_id", number, func(d *models.Donation) string { return strconv.Itoa(d.CauseID) }},
+	{"cause_title", text, func(d *models.Donation) string { return d.CauseTitle }},
+	{"cause_organization", text, func(d *models.Donation) string { return d.CauseOrganization }},
+	{"user_id", number, func(d *models.Donation) string {
+		if d.UserID == nil {
+			return ""
+		}
+		return strconv.Itoa(*d.UserID)
+	}},
+	{"user_name", text, func(d *models.Donation) string { return d.UserName }},
+	{"is_anonymous", boolean, func(d *models.Donation) string { return strconv.FormatBool(d.IsAnonymous) }},
+	{"source", text, func(d *models.Donation) string { return d.Source }},
+	{"transaction_id", text, func(d *models.Donation) string { return d.TransactionID }},
+	{"transaction_hash", text, func(d *models.Donation) string { return d.TransactionHash }},
+	{"block_number", number, func(d *models.Donation) string {
+		if d.BlockNumber == nil {
+			return ""
+		}
+		return strconv.FormatInt(*d.BlockNumber, 10)
+	}},
+	{"donor_address", text, func(d *models.Donation) string { return d.DonorAddress }},
+}
+
+// formatTime formats a timestamp in UTC
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// csvWriter writes donations as CSV with a header row
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+	record []string
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), record: make([]string, len(columns))}
+}
+
+// Write writes a donation's row, preceded by the header if it is the first
+func (c *csvWriter) Write(d models.Donation) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	for i, col := range columns {
+		c.record[i] = col.value(&d)
+		if col.kind == text {
+			c.record[i] = defuse(c.record[i])
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+// Close writes the header if there were no donations and flushes the rows
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	for i, col := range columns {
+		c.record[i] = col.name
+	}
+	return c.w.Write(c.record)
+}
+
+// defuse stops spreadsheet programs from running text that donors and cause
+// owners control, such as names and titles, as a formula when a CSV export is
+// opened
+func defuse(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// jsonWriter writes donations as JSON lines: one JSON object per line
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+// Write writes a donation's line
+func (j *jsonWriter) Write(d models.Donation) error {
+	return j.enc.Encode(d)
+}
+
+// Close does nothing; every line is complete once written
+func (j *jsonWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,349 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+func testDonation(id int, causeTitle string) models.Donation {
+	userID := 3
+	return models.Donation{
+		ID:          id,
+		UserID:      &userID,
+		CauseID:     2,
+		Amount:      models.NewMoney(big.NewInt(2550), models.CurrencyUSD),
+		Currency:    models.CurrencyUSD,
+		Status:      models.DonationStatusCompleted,
+		Source:      "offchain",
+		CreatedAt:   time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2026, time.March, 1, 18, 0, 0, 0, time.UTC),
+		CauseTitle:  causeTitle,
+		UserName:    "Jane Doe",
+		IsAnonymous: true,
+	}
+}
+
+// export writes donations in format and returns the file
+func export(t *testing.T, format Format, donations ...models.Donation) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewDonationWriter(&buf, format)
+	if err != nil {
+		t.Fatalf("NewDonationWriter: %v", err)
+	}
+	for _, d := range donations {
+		if err := w.Write(d); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func readCSV(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	return records
+}
+
+// field returns the value of the named column in a CSV record
+func field(t *testing.T, header, record []string, name string) string {
+	t.Helper()
+	for i, h := range header {
+		if h == name {
+			return record[i]
+		}
+	}
+	t.Fatalf("no %s column", name)
+	return ""
+}
+
+func TestParseFormat(t *testing.T) {
+	for s, want := range map[string]Format{"csv": CSV, "JSONL": JSONLines, "xlsx": XLSX} {
+		if f, err := ParseFormat(s); err != nil || f != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", s, f, err, want)
+		}
+	}
+	for _, s := range []string{"", "json", "xls", "pdf"} {
+		if _, err := ParseFormat(s); err == nil {
+			t.Errorf("ParseFormat(%q) succeeded", s)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	records := readCSV(t, export(t, CSV, testDonation(1, "Clean, \"safe\" water")))
+	if len(records) != 2 {
+		t.Fatalf("%d records, want a header and one row", len(records))
+	}
+	header, row := records[0], records[1]
+
+	for name, want := range map[string]string{
+		"id":           "1",
+		"created_at":   "2026-03-01T12:00:00Z",
+		"amount":       "25.50",
+		"currency":     "USD",
+		"cause_title":  "Clean, \"safe\" water",
+		"user_id":      "3",
+		"is_anonymous": "true",
+		"block_number": "",
+	} {
+		if got := field(t, header, row, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCSVDefusesFormulas(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"=HYPERLINK(\"http://example.com\")", "'=HYPERLINK(\"http://example.com\")"},
+		{"+1+1", "'+1+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"Water = life", "Water = life"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		records := readCSV(t, export(t, CSV, testDonation(1, tt.title)))
+		if got := field(t, records[0], records[1], "cause_title"); got != tt.want {
+			t.Errorf("cause title %q exported as %q, want %q", tt.title, got, tt.want)
+		}
+	}
+
+	// Numbers and times are never prefixed
+	d := testDonation(1, "")
+	d.Amount = models.NewMoney(big.NewInt(-100), models.CurrencyUSD)
+	records := readCSV(t, export(t, CSV, d))
+	if got := field(t, records[0], records[1], "amount"); got != "-1.00" {
+		t.Errorf("amount = %q, want -1.00", got)
+	}
+}
+
+func TestCSVWithoutDonations(t *testing.T) {
+	records := readCSV(t, export(t, CSV))
+	if len(records) != 1 {
+		t.Fatalf("%d records, want only the header", len(records))
+	}
+	if len(records[0]) != len(columns) || records[0][0] != "id" {
+		t.Errorf("header = %v", records[0])
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	data := export(t, JSONLines, testDonation(1, "A"), testDonation(2, "B"))
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d lines, want 2", len(lines))
+	}
+	var d struct {
+		ID     int    `json:"id"`
+		Amount string `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &d); err != nil {
+		t.Fatalf("invalid JSON line: %v", err)
+	}
+	if d.ID != 2 || d.Amount != "25.50" {
+		t.Errorf("line 2 = %+v", d)
+	}
+	if data := export(t, JSONLines); len(data) != 0 {
+		t.Errorf("export without donations = %q, want nothing", data)
+	}
+}
+
+// worksheet is the part of a worksheet the tests read
+type worksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R     string `xml:"r,attr"`
+			T     string `xml:"t,attr"`
+			S     string `xml:"s,attr"`
+			V     string `xml:"v"`
+			Value string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// workbook is the part of the workbook the tests read
+type workbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// readXLSX checks that data is a zip of well-formed XML parts and returns
+// their contents by name
+func readXLSX(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", f.Name, err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %v", f.Name, err)
+		}
+
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed XML: %v", f.Name, err)
+			}
+		}
+		parts[f.Name] = body
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+	return parts
+}
+
+func TestXLSX(t *testing.T) {
+	parts := readXLSX(t, export(t, XLSX, testDonation(1, "<Water & \"Wells\">\x01")))
+
+	var sheet worksheet
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("sheet1: %v", err)
+	}
+	if len(sheet.Rows) != 2 || sheet.Rows[0].R != 1 || sheet.Rows[1].R != 2 {
+		t.Fatalf("rows = %+v, want the header and one row", sheet.Rows)
+	}
+	header := sheet.Rows[0].Cells
+	if len(header) != len(columns) || header[0].Value != "id" || header[0].S != "1" {
+		t.Errorf("header = %+v", header)
+	}
+
+	cells := map[string]string{}
+	for _, c := range sheet.Rows[1].Cells {
+		cells[c.R] = c.T + "|" + c.S + "|" + c.V + c.Value
+	}
+	for ref, want := range map[string]string{
+		"A2": "||1",                                  // id
+		"B2": "|2|46082.5",                           // created_at as a date
+		"E2": "||25.50",                              // amount
+		"H2": "inlineStr||<Water & \"Wells\">\uFFFD", // cause_title
+		"L2": "b||1",                                 // is_anonymous
+	} {
+		if cells[ref] != want {
+			t.Errorf("%s = %q, want %q", ref, cells[ref], want)
+		}
+	}
+	if _, ok := cells["I2"]; ok {
+		t.Error("empty cause_organization written as a cell")
+	}
+
+	var book workbook
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &book); err != nil {
+		t.Fatalf("workbook: %v", err)
+	}
+	if len(book.Sheets) != 1 || book.Sheets[0].Name != "Donations" {
+		t.Errorf("sheets = %+v", book.Sheets)
+	}
+}
+
+func TestXLSXWithoutDonations(t *testing.T) {
+	parts := readXLSX(t, export(t, XLSX))
+	var sheet worksheet
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("sheet1: %v", err)
+	}
+	if len(sheet.Rows) != 1 {
+		t.Errorf("%d rows, want only the header", len(sheet.Rows))
+	}
+}
+
+func TestXLSXRollsOverFullSheets(t *testing.T) {
+	var buf bytes.Buffer
+	w := newXLSXWriter(&buf, "Donations")
+	w.maxRows = 3
+	for id := 1; id <= 5; id++ {
+		if err := w.Write(testDonation(id, "")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	parts := readXLSX(t, buf.Bytes())
+
+	// Each sheet holds a header and at most two donations
+	wantIDs := [][]string{{"1", "2"}, {"3", "4"}, {"5"}}
+	for i, ids := range wantIDs {
+		name := "xl/worksheets/sheet" + string(rune('1'+i)) + ".xml"
+		var sheet worksheet
+		if err := xml.Unmarshal(parts[name], &sheet); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(sheet.Rows) != len(ids)+1 || sheet.Rows[0].Cells[0].Value != "id" {
+			t.Fatalf("%s has %d rows, want a header and %d donations", name, len(sheet.Rows), len(ids))
+		}
+		for j, id := range ids {
+			if row := sheet.Rows[j+1]; row.R != j+2 || row.Cells[0].V != id {
+				t.Errorf("%s row %d = %+v, want donation %s", name, j+2, row, id)
+			}
+		}
+		if !bytes.Contains(parts["[Content_Types].xml"], []byte("/"+name)) {
+			t.Errorf("%s is missing from the content types", name)
+		}
+	}
+	if _, ok := parts["xl/worksheets/sheet4.xml"]; ok {
+		t.Error("wrote an empty fourth sheet")
+	}
+
+	var book workbook
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &book); err != nil {
+		t.Fatalf("workbook: %v", err)
+	}
+	var names []string
+	for _, s := range book.Sheets {
+		names = append(names, s.Name)
+	}
+	if strings.Join(names, ",") != "Donations,Donations (2),Donations (3)" {
+		t.Errorf("sheets = %v", names)
+	}
+}
+
+func TestCellRef(t *testing.T) {
+	for _, tt := range []struct {
+		col, row int
+		want     string
+	}{
+		{0, 1, "A1"}, {25, 2, "Z2"}, {26, 3, "AA3"}, {701, 1, "ZZ1"}, {702, 1, "AAA1"},
+	} {
+		if got := cellRef(tt.col, tt.row); got != tt.want {
+			t.Errorf("cellRef(%d, %d) = %s, want %s", tt.col, tt.row, got, tt.want)
+		}
+	}
+}
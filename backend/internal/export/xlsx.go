@@ -0,0 +1,221 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// maxSheetRows is the most rows a worksheet can hold. Exports with more
+// donations continue on further sheets, each with its own header row.
+const maxSheetRows = 1 << 20
+
+// Cell styles defined in xlsxStyles
+const (
+	styleHeader    = 1
+	styleTimestamp = 2
+)
+
+// excelEpoch is day zero of spreadsheet date serial numbers
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxWriter writes donations as an Office Open XML workbook. Each worksheet
+// is streamed into the zip archive row by row; the workbook parts that list
+// the sheets are written by Close, once their number is known. Amounts are
+// number cells, which spreadsheet programs hold to 15 significant digits;
+// use CSV or JSON lines for exact ETH amounts.
+type xlsxWriter struct {
+	zip     *zip.Writer
+	name    string
+	maxRows int
+	sheets  int
+	sheet   *bufio.Writer
+	row     int
+}
+
+func newXLSXWriter(w io.Writer, name string) *xlsxWriter {
+	return &xlsxWriter{zip: zip.NewWriter(w), name: name, maxRows: maxSheetRows}
+}
+
+// Write writes a donation's row, starting a new sheet if there is none yet
+// or the current one is full
+func (x *xlsxWriter) Write(d models.Donation) error {
+	if x.sheet == nil || x.row == x.maxRows {
+		if err := x.startSheet(); err != nil {
+			return err
+		}
+	}
+	x.row++
+
+	x.sheet.WriteString(`<row r="` + strconv.Itoa(x.row) + `">`)
+	for i, col := range columns {
+		value := col.value(&d)
+		if value == "" {
+			continue
+		}
+		ref := cellRef(i, x.row)
+		switch col.kind {
+		case number:
+			x.sheet.WriteString(`<c r="` + ref + `"><v>` + value + `</v></c>`)
+		case boolean:
+			v := "0"
+			if value == "true" {
+				v = "1"
+			}
+			x.sheet.WriteString(`<c r="` + ref + `" t="b"><v>` + v + `</v></c>`)
+		case timestamp:
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(x.sheet, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleTimestamp, serial(t))
+		default:
+			x.inlineString(ref, 0, value)
+		}
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Close finishes the last sheet, starting one with just the header if there
+// were no donations, and writes the rest of the workbook
+func (x *xlsxWriter) Close() error {
+	if x.sheet == nil {
+		if err := x.startSheet(); err != nil {
+			return err
+		}
+	}
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+
+	var sheets, sheetRels, sheetTypes strings.Builder
+	for i := 1; i <= x.sheets; i++ {
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, x.sheetName(i), i, i)
+		fmt.Fprintf(&sheetRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+		fmt.Fprintf(&sheetTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			sheetTypes.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			sheetRels.String() +
+			fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, x.sheets+1) +
+			`</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		w, err := x.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, xml.Header+part.body); err != nil {
+			return err
+		}
+	}
+	return x.zip.Close()
+}
+
+// startSheet ends the current sheet, if any, and starts the next with the
+// header row. The header row stays in view when scrolling.
+func (x *xlsxWriter) startSheet() error {
+	if x.sheet != nil {
+		if err := x.endSheet(); err != nil {
+			return err
+		}
+	}
+
+	x.sheets++
+	w, err := x.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", x.sheets))
+	if err != nil {
+		return err
+	}
+	x.sheet = bufio.NewWriter(w)
+	x.row = 1
+
+	x.sheet.WriteString(xml.Header)
+	x.sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	x.sheet.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	x.sheet.WriteString(`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+	x.sheet.WriteString(`</sheetView></sheetViews><sheetData><row r="1">`)
+	for i, col := range columns {
+		x.inlineString(cellRef(i, 1), styleHeader, col.name)
+	}
+	_, err = x.sheet.WriteString(`</row>`)
+	return err
+}
+
+// endSheet closes the current sheet's elements and flushes it
+func (x *xlsxWriter) endSheet() error {
+	x.sheet.WriteString(`</sheetData></worksheet>`)
+	return x.sheet.Flush()
+}
+
+// inlineString writes a text cell. Characters XML cannot hold are replaced
+// with U+FFFD.
+func (x *xlsxWriter) inlineString(ref string, style int, s string) {
+	x.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"`)
+	if style != 0 {
+		x.sheet.WriteString(` s="` + strconv.Itoa(style) + `"`)
+	}
+	x.sheet.WriteString(`><is><t xml:space="preserve">`)
+	xml.EscapeText(x.sheet, []byte(s))
+	x.sheet.WriteString(`</t></is></c>`)
+}
+
+// sheetName returns the name of the nth sheet
+func (x *xlsxWriter) sheetName(n int) string {
+	if n == 1 {
+		return x.name
+	}
+	return fmt.Sprintf("%s (%d)", x.name, n)
+}
+
+// cellRef returns the A1 reference of a zero-based column and one-based row
+func cellRef(col, row int) string {
+	var name []byte
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = append([]byte{byte('A' + (col-1)%26)}, name...)
+	}
+	return string(name) + strconv.Itoa(row)
+}
+
+// serial returns t as a spreadsheet date serial number: days since
+// excelEpoch, with the time of day as the fraction
+func serial(t time.Time) string {
+	days := float64(t.Sub(excelEpoch)) / float64(24*time.Hour)
+	return strconv.FormatFloat(days, 'f', -1, 64)
+}
+
+// xlsxStyles defines the default cell style, then styleHeader and
+// styleTimestamp
+const xlsxStyles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/mailer"
+	"github.com/ombima56/transpacharity/internal/middleware"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+	"github.com/ombima56/transpacharity/internal/validation"
+)
+
+// resetEmailTimeout bounds sending a password reset email, which outlives the
+// request that asked for it
+const resetEmailTimeout = 30 * time.Second
+
+// ForgotPassword emails a password reset link. It responds the same way
+// whether or not the email belongs to an account, so it cannot be used to
+// discover registered addresses. The email is sent in the background so that
+// the response time does not tell either.
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	// Parse the request body
+	var input models.ForgotPasswordInput
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	user, err := h.userRepo.GetByEmail(r.Context(), input.Email)
+	if err != nil {
+		response.FromError(w, r, "getting user", err)
+		return
+	}
+
+	if user != nil {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), resetEmailTimeout)
+		go func() {
+			defer cancel()
+			err := h.sendTokenEmail(ctx, user, models.TokenPurposePasswordReset,
+				"Reset your TranspaCharity password", "/reset-password",
+				"We received a request to reset your password. Open the link below within an hour to choose a new one:")
+			if err != nil {
+				log.Printf("Error sending password reset email to user %d: %v", user.ID, err)
+			}
+		}()
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password using a token from a reset email. All of
+// the user's sessions are logged out.
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	// Parse the request body
+	var input models.ResetPasswordInput
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	passwordHash, err := models.HashPassword(input.Password)
+	if err != nil {
+		response.FromError(w, r, "hashing password", err)
+		return
+	}
+
+	// Redeem the token and update the password
+	userID, err := h.tokenRepo.ResetPassword(r.Context(), middleware.HashOpaqueToken(input.Token), passwordHash)
+	if err != nil {
+		response.FromError(w, r, "resetting password", err)
+		return
+	}
+
+	// A reset proves control of the email, so lift any lockout on the account
+	if user, err := h.userRepo.GetByID(r.Context(), userID); err == nil && user != nil {
+		if err := h.limiter.Success(r.Context(), user.Email); err != nil {
+			log.Printf("Error clearing failed logins for user %d: %v", userID, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail marks the user's email as verified using a token from a
+// verification email
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	// Parse the request body
+	var input models.VerifyEmailInput
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	// Redeem the token
+	userID, err := h.tokenRepo.VerifyEmail(r.Context(), middleware.HashOpaqueToken(input.Token))
+	if err != nil {
+		response.FromError(w, r, "verifying email", err)
+		return
+	}
+
+	// Return the updated user
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		response.FromError(w, r, "getting user", err)
+		return
+	}
+	response.JSON(w, http.StatusOK, user)
+}
+
+// ResendVerification sends a new verification email to the current user
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	// Get the user ID from the context
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get the user
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		response.FromError(w, r, "getting user", err)
+		return
+	}
+	if user == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "User not found")
+		return
+	}
+	if user.EmailVerified {
+		response.Error(w, r, http.StatusConflict, response.CodeConflict, "Email is already verified")
+		return
+	}
+
+	if err := h.sendVerificationEmail(r.Context(), user); err != nil {
+		response.FromError(w, r, "sending verification email", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ChangePassword changes the current user's password after checking the
+// current one. Every other session of the user is logged out.
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	// Parse the request body
+	var input models.ChangePasswordInput
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	user, ok := h.reauthenticate(w, r, input.CurrentPassword)
+	if !ok {
+		return
+	}
+
+	passwordHash, err := models.HashPassword(input.NewPassword)
+	if err != nil {
+		response.FromError(w, r, "hashing password", err)
+		return
+	}
+
+	// Keep the session making this request signed in
+	sessionID, _ := middleware.GetSessionIDFromContext(r.Context())
+	if err := h.userRepo.UpdatePassword(r.Context(), user.ID, passwordHash, sessionID); err != nil {
+		response.FromError(w, r, "updating password", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangeEmail changes the current user's email address after checking their
+// password. The new address must be verified again.
+func (h *UserHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	// Parse the request body
+	var input models.ChangeEmailInput
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	user, ok := h.reauthenticate(w, r, input.Password)
+	if !ok {
+		return
+	}
+	if input.Email == user.Email {
+		response.ValidationFailed(w, r, validation.Errors{{Field: "email", Code: validation.CodeInvalid, Message: "is the current email address"}})
+		return
+	}
+
+	// Check if the email is already taken
+	existing, err := h.userRepo.GetByEmail(r.Context(), input.Email)
+	if err != nil {
+		response.FromError(w, r, "checking email", err)
+		return
+	}
+	if existing != nil {
+		response.FromError(w, r, "changing email", repository.ErrEmailTaken)
+		return
+	}
+
+	// Update the email
+	user, err = h.userRepo.UpdateEmail(r.Context(), user.ID, input.Email)
+	if err != nil {
+		response.FromError(w, r, "updating email", err)
+		return
+	}
+	if user == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "User not found")
+		return
+	}
+
+	if err := h.sendVerificationEmail(r.Context(), user); err != nil {
+		log.Printf("Error sending verification email to user %d: %v", user.ID, err)
+	}
+
+	// Return the updated user
+	response.JSON(w, http.StatusOK, user)
+}
+
+// reauthenticate loads the current user and checks password against theirs.
+// It writes an error response and returns false if either step fails.
+func (h *UserHandler) reauthenticate(w http.ResponseWriter, r *http.Request, password string) (*models.User, bool) {
+	// Get the user ID from the context
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		response.FromError(w, r, "getting user", err)
+		return nil, false
+	}
+	if user == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "User not found")
+		return nil, false
+	}
+
+	if !models.CheckPassword(password, user.PasswordHash) {
+		response.Error(w, r, http.StatusForbidden, response.CodeInvalidCredentials, "Current password is incorrect")
+		return nil, false
+	}
+
+	return user, true
+}
+
+// sendVerificationEmail emails user a link to verify their address
+func (h *UserHandler) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	return h.sendTokenEmail(ctx, user, models.TokenPurposeEmailVerification,
+		"Confirm your TranspaCharity email address", "/verify-email",
+		"Please confirm your email address by opening the link below:")
+}
+
+// sendTokenEmail issues a single-use token for purpose and emails user a
+// frontend link at path carrying it
+func (h *UserHandler) sendTokenEmail(ctx context.Context, user *models.User, purpose models.TokenPurpose, subject, path, intro string) error {
+	token, hash, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if err := h.tokenRepo.Create(ctx, user.ID, purpose, hash); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s%s?token=%s", h.appURL, path, url.QueryEscape(token))
+	body := fmt.Sprintf("Hello %s,\n\n%s\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+		user.Name, intro, link)
+
+	return h.mailer.Send(ctx, mailer.Message{To: user.Email, Subject: subject, Body: body})
+}
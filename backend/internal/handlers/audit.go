@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+// AuditHandler publishes the Merkle roots committed over the donation audit log
+type AuditHandler struct {
+	auditRepo   *repository.AuditRepository
+	explorerURL string
+}
+
+// NewAuditHandler creates a new AuditHandler. Anchored roots link to their
+// transaction on explorerURL, e.g. https://etherscan.io.
+func NewAuditHandler(auditRepo *repository.AuditRepository, explorerURL string) *AuditHandler {
+	return &AuditHandler{
+		auditRepo:   auditRepo,
+		explorerURL: explorerURL,
+	}
+}
+
+// auditRootResponse is an audit root with a link to its anchor transaction
+type auditRootResponse struct {
+	models.AuditRoot
+	ExplorerURL string `json:"explorer_url,omitempty"`
+}
+
+// withExplorerURL links an anchored root to its transaction
+func (h *AuditHandler) withExplorerURL(root models.AuditRoot) auditRootResponse {
+	resp := auditRootResponse{AuditRoot: root}
+	if root.AnchorTransactionHash != "" && h.explorerURL != "" {
+		resp.ExplorerURL = h.explorerURL + "/tx/" + root.AnchorTransactionHash
+	}
+	return resp
+}
+
+// GetRoots gets one page of the committed roots, newest first by default
+func (h *AuditHandler) GetRoots(w http.ResponseWriter, r *http.Request) {
+	// Parse the query string
+	q := queryParams{values: r.URL.Query()}
+	page := q.page(models.AuditRootSortKeys, "-created_at")
+	if q.err != "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, q.err)
+		return
+	}
+
+	// Get the roots
+	roots, err := h.auditRepo.ListRoots(r.Context(), page)
+	if err != nil {
+		response.FromError(w, r, "getting audit roots", err)
+		return
+	}
+
+	// Return the roots
+	items := make([]auditRootResponse, len(roots.Items))
+	for i, root := range roots.Items {
+		items[i] = h.withExplorerURL(root)
+	}
+	writePage(w, r, &models.Page[auditRootResponse]{Items: items, NextCursor: roots.NextCursor, Total: roots.Total})
+}
+
+// GetLatestRoot gets the most recently committed root
+func (h *AuditHandler) GetLatestRoot(w http.ResponseWriter, r *http.Request) {
+	root, err := h.auditRepo.LatestRoot(r.Context())
+	if err != nil {
+		response.FromError(w, r, "getting latest audit root", err)
+		return
+	}
+	if root == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "No audit root has been committed yet")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, h.withExplorerURL(*root))
+}
+
+// SetAnchor records the transaction that published a root on-chain
+func (h *AuditHandler) SetAnchor(w http.ResponseWriter, r *http.Request) {
+	// Get the root ID from the URL
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid audit root ID")
+		return
+	}
+
+	// Parse the request body
+	var input models.AuditAnchorInput
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	// Record the anchor
+	root, err := h.auditRepo.SetAnchor(r.Context(), id, input.TransactionHash)
+	if err != nil {
+		response.FromError(w, r, "anchoring audit root", err)
+		return
+	}
+	if root == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Audit root not found")
+		return
+	}
+
+	// Return the anchored root
+	response.JSON(w, http.StatusOK, h.withExplorerURL(*root))
+}
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
 	"github.com/ombima56/transpacharity/internal/models"
 	"github.com/ombima56/transpacharity/internal/repository"
 )
@@ -26,12 +26,11 @@ func NewCategoryHandler(categoryRepo *repository.CategoryRepository) *CategoryHa
 func (h *CategoryHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	categories, err := h.categoryRepo.GetAll(r.Context())
 	if err != nil {
-		http.Error(w, "Error getting categories: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting categories", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(categories)
+	response.JSON(w, http.StatusOK, categories)
 }
 
 // GetByID gets a category by ID
@@ -39,46 +38,42 @@ func (h *CategoryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid category ID")
 		return
 	}
 
 	// Get the category
 	category, err := h.categoryRepo.GetByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Error getting category: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting category", err)
 		return
 	}
 	if category == nil {
-		http.Error(w, "Category not found", http.StatusNotFound)
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Category not found")
 		return
 	}
 
 	// Return the category
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(category)
+	response.JSON(w, http.StatusOK, category)
 }
 
 // Create creates a new category
 func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Parse the request body
 	var input models.CategoryInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeAndValidate(w, r, &input) {
 		return
 	}
 
 	// Create the category
 	category, err := h.categoryRepo.Create(r.Context(), input)
 	if err != nil {
-		http.Error(w, "Error creating category: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "creating category", err)
 		return
 	}
 
 	// Return the category
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(category)
+	response.JSON(w, http.StatusCreated, category)
 }
 
 // Update updates a category
@@ -87,31 +82,29 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid category ID")
 		return
 	}
 
 	// Parse the request body
 	var input models.CategoryInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeAndValidate(w, r, &input) {
 		return
 	}
 
 	// Update the category
 	category, err := h.categoryRepo.Update(r.Context(), id, input)
 	if err != nil {
-		http.Error(w, "Error updating category: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "updating category", err)
 		return
 	}
 	if category == nil {
-		http.Error(w, "Category not found", http.StatusNotFound)
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Category not found")
 		return
 	}
 
 	// Return the updated category
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(category)
+	response.JSON(w, http.StatusOK, category)
 }
 
 // Delete deletes a category
@@ -120,14 +113,14 @@ func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid category ID")
 		return
 	}
 
 	// Delete the category
 	err = h.categoryRepo.Delete(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Error deleting category: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "deleting category", err)
 		return
 	}
 
@@ -1,39 +1,89 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
 	"github.com/ombima56/transpacharity/internal/models"
 	"github.com/ombima56/transpacharity/internal/repository"
+	"github.com/ombima56/transpacharity/internal/validation"
 )
 
 // CauseHandler handles cause-related requests
 type CauseHandler struct {
-	causeRepo *repository.CauseRepository
+	causeRepo    *repository.CauseRepository
+	categoryRepo *repository.CategoryRepository
+	pledgeRepo   *repository.MatchingPledgeRepository
 }
 
 // NewCauseHandler creates a new CauseHandler
-func NewCauseHandler(causeRepo *repository.CauseRepository) *CauseHandler {
+func NewCauseHandler(causeRepo *repository.CauseRepository, categoryRepo *repository.CategoryRepository, pledgeRepo *repository.MatchingPledgeRepository) *CauseHandler {
 	return &CauseHandler{
-		causeRepo: causeRepo,
+		causeRepo:    causeRepo,
+		categoryRepo: categoryRepo,
+		pledgeRepo:   pledgeRepo,
 	}
 }
 
-// GetAll gets all causes
+// GetAll gets one page of causes, filtered by category_id, featured and
+// currency and ordered by sort
 func (h *CauseHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	// Get all causes
-	causes, err := h.causeRepo.GetAll(r.Context())
+	// Parse the query string
+	q := queryParams{values: r.URL.Query()}
+	filter := models.CauseFilter{
+		PageRequest: q.page(models.CauseSortKeys, "-created_at"),
+		CategoryID:  q.int("category_id"),
+		Featured:    q.bool("featured"),
+		Currency:    q.currency("currency"),
+	}
+	if q.err != "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, q.err)
+		return
+	}
+
+	// Get the causes
+	page, err := h.causeRepo.List(r.Context(), filter)
 	if err != nil {
-		http.Error(w, "Error getting causes: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting causes", err)
 		return
 	}
 
 	// Return the causes
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(causes)
+	writePage(w, r, page)
+}
+
+// Search gets one page of the causes matching the q parameter, best matches
+// first, optionally filtered by category_id and featured
+func (h *CauseHandler) Search(w http.ResponseWriter, r *http.Request) {
+	// Parse the query string
+	q := queryParams{values: r.URL.Query()}
+	filter := models.CauseSearchFilter{
+		PageRequest: models.PageRequest{Limit: q.limit(), Cursor: q.values.Get("cursor")},
+		Query:       strings.TrimSpace(q.values.Get("q")),
+		CategoryID:  q.int("category_id"),
+		Featured:    q.bool("featured"),
+	}
+	if filter.Query == "" {
+		q.invalid("q", "is required")
+	}
+	if q.err != "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, q.err)
+		return
+	}
+
+	// Search the causes
+	page, err := h.causeRepo.Search(r.Context(), filter)
+	if err != nil {
+		response.FromError(w, r, "searching causes", err)
+		return
+	}
+
+	// Return the results
+	writePage(w, r, page)
 }
 
 // GetFeatured gets featured causes
@@ -41,13 +91,12 @@ func (h *CauseHandler) GetFeatured(w http.ResponseWriter, r *http.Request) {
 	// Get featured causes
 	causes, err := h.causeRepo.GetFeatured(r.Context())
 	if err != nil {
-		http.Error(w, "Error getting featured causes: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting featured causes", err)
 		return
 	}
 
 	// Return the causes
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(causes)
+	response.JSON(w, http.StatusOK, causes)
 }
 
 // GetByID gets a cause by ID
@@ -56,46 +105,63 @@ func (h *CauseHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid cause ID", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid cause ID")
 		return
 	}
 
 	// Get the cause
 	cause, err := h.causeRepo.GetByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Error getting cause: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting cause", err)
 		return
 	}
 	if cause == nil {
-		http.Error(w, "Cause not found", http.StatusNotFound)
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Cause not found")
+		return
+	}
+
+	// Add what matching pledges have given and still promise
+	matched, err := h.pledgeRepo.MatchedAmount(r.Context(), cause.ID, cause.Currency)
+	if err != nil {
+		response.FromError(w, r, "getting matched amount", err)
+		return
+	}
+	cause.MatchedAmount = &matched
+	if cause.MatchingPledges, err = h.pledgeRepo.GetByCauseID(r.Context(), cause.ID); err != nil {
+		response.FromError(w, r, "getting matching pledges", err)
 		return
 	}
 
 	// Return the cause
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(cause)
+	response.JSON(w, http.StatusOK, cause)
 }
 
 // Create creates a new cause
 func (h *CauseHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Parse the request body
 	var input models.CauseInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	errs, err := h.validate(r.Context(), input)
+	if err != nil {
+		response.FromError(w, r, "validating cause", err)
+		return
+	}
+	if len(errs) > 0 {
+		response.ValidationFailed(w, r, errs)
 		return
 	}
 
 	// Create the cause
 	cause, err := h.causeRepo.Create(r.Context(), input)
 	if err != nil {
-		http.Error(w, "Error creating cause: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "creating cause", err)
 		return
 	}
 
 	// Return the cause
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(cause)
+	response.JSON(w, http.StatusCreated, cause)
 }
 
 // Update updates a cause
@@ -104,31 +170,38 @@ func (h *CauseHandler) Update(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid cause ID", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid cause ID")
 		return
 	}
 
 	// Parse the request body
 	var input models.CauseInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	errs, err := h.validate(r.Context(), input)
+	if err != nil {
+		response.FromError(w, r, "validating cause", err)
+		return
+	}
+	if len(errs) > 0 {
+		response.ValidationFailed(w, r, errs)
 		return
 	}
 
 	// Update the cause
 	cause, err := h.causeRepo.Update(r.Context(), id, input)
 	if err != nil {
-		http.Error(w, "Error updating cause: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "updating cause", err)
 		return
 	}
 	if cause == nil {
-		http.Error(w, "Cause not found", http.StatusNotFound)
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Cause not found")
 		return
 	}
 
 	// Return the updated cause
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(cause)
+	response.JSON(w, http.StatusOK, cause)
 }
 
 // Delete deletes a cause
@@ -137,17 +210,39 @@ func (h *CauseHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid cause ID", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid cause ID")
 		return
 	}
 
 	// Delete the cause
 	err = h.causeRepo.Delete(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Error deleting cause: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "deleting cause", err)
 		return
 	}
 
 	// Return success
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// validate checks a cause input's tags, its goal amount in the chosen
+// currency and that its category exists
+func (h *CauseHandler) validate(ctx context.Context, input models.CauseInput) (validation.Errors, error) {
+	errs := validation.Struct(&input)
+
+	if currency, ok := validateCurrency(&errs, "currency", input.Currency); ok {
+		validateAmount(&errs, "goal_amount", input.GoalAmount, currency)
+	}
+
+	if !errs.Has("category_id") {
+		category, err := h.categoryRepo.GetByID(ctx, input.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		if category == nil {
+			errs.Add("category_id", validation.CodeNotFound, "does not match an existing category")
+		}
+	}
+
+	return errs, nil
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/middleware"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+	"github.com/ombima56/transpacharity/internal/validation"
+)
+
+// DisbursementHandler handles the reports of how causes spend their funds
+type DisbursementHandler struct {
+	disbursementRepo *repository.DisbursementRepository
+	causeRepo        *repository.CauseRepository
+	chainRepo        *repository.ChainRepository
+	userRepo         *repository.UserRepository
+}
+
+// NewDisbursementHandler creates a new DisbursementHandler
+func NewDisbursementHandler(
+	disbursementRepo *repository.DisbursementRepository,
+	causeRepo *repository.CauseRepository,
+	chainRepo *repository.ChainRepository,
+	userRepo *repository.UserRepository,
+) *DisbursementHandler {
+	return &DisbursementHandler{
+		disbursementRepo: disbursementRepo,
+		causeRepo:        causeRepo,
+		chainRepo:        chainRepo,
+		userRepo:         userRepo,
+	}
+}
+
+// GetByCauseID gets one page of a cause's disbursements, filtered by
+// withdrawal_id and currency and ordered by sort
+func (h *DisbursementHandler) GetByCauseID(w http.ResponseWriter, r *http.Request) {
+	// Get the cause ID from the URL
+	idStr := chi.URLParam(r, "id")
+	causeID, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid cause ID")
+		return
+	}
+
+	// Parse the query string
+	q := queryParams{values: r.URL.Query()}
+	filter := models.DisbursementFilter{
+		PageRequest:  q.page(models.DisbursementSortKeys, "-spent_at"),
+		CauseID:      &causeID,
+		WithdrawalID: q.int("withdrawal_id"),
+		Currency:     q.currency("currency"),
+	}
+	if q.err != "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, q.err)
+		return
+	}
+
+	// Get the disbursements
+	page, err := h.disbursementRepo.List(r.Context(), filter)
+	if err != nil {
+		response.FromError(w, r, "getting disbursements", err)
+		return
+	}
+
+	// Return the disbursements
+	writePage(w, r, page)
+}
+
+// GetByID gets a disbursement by ID
+func (h *DisbursementHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	// Get the disbursement ID from the URL
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid disbursement ID")
+		return
+	}
+
+	// Get the disbursement
+	disbursement, err := h.disbursementRepo.GetByID(r.Context(), id)
+	if err != nil {
+		response.FromError(w, r, "getting disbursement", err)
+		return
+	}
+	if disbursement == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Disbursement not found")
+		return
+	}
+
+	// Return the disbursement
+	response.JSON(w, http.StatusOK, disbursement)
+}
+
+// GetFunds gets a cause's funds raised, withdrawn and reported spent
+func (h *DisbursementHandler) GetFunds(w http.ResponseWriter, r *http.Request) {
+	// Get the cause ID from the URL
+	idStr := chi.URLParam(r, "id")
+	causeID, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid cause ID")
+		return
+	}
+
+	cause, err := h.causeRepo.GetByID(r.Context(), causeID)
+	if err != nil {
+		response.FromError(w, r, "getting cause", err)
+		return
+	}
+	if cause == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Cause not found")
+		return
+	}
+
+	// Get the summary
+	summary, err := h.disbursementRepo.Funds(r.Context(), cause.ID)
+	if err != nil {
+		response.FromError(w, r, "getting cause funds", err)
+		return
+	}
+
+	// Return the summary
+	response.JSON(w, http.StatusOK, summary)
+}
+
+// Create reports a disbursement for a cause. Only admins and the cause's
+// owner may report disbursements.
+func (h *DisbursementHandler) Create(w http.ResponseWriter, r *http.Request) {
+	// Get the cause ID from the URL
+	idStr := chi.URLParam(r, "id")
+	causeID, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid cause ID")
+		return
+	}
+
+	cause, err := h.causeRepo.GetByID(r.Context(), causeID)
+	if err != nil {
+		response.FromError(w, r, "getting cause", err)
+		return
+	}
+	if cause == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Cause not found")
+		return
+	}
+
+	userID, ok := h.authorize(w, r, cause)
+	if !ok {
+		return
+	}
+
+	// Parse the request body
+	var input models.DisbursementInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	errs, err := h.validate(r.Context(), cause, input)
+	if err != nil {
+		response.FromError(w, r, "validating disbursement", err)
+		return
+	}
+	if len(errs) > 0 {
+		response.ValidationFailed(w, r, errs)
+		return
+	}
+
+	// Create the disbursement
+	disbursement, err := h.disbursementRepo.Create(r.Context(), cause.ID, &userID, input)
+	if err != nil {
+		response.FromError(w, r, "creating disbursement", err)
+		return
+	}
+
+	// Return the disbursement
+	response.JSON(w, http.StatusCreated, disbursement)
+}
+
+// Update replaces a disbursement. Only admins and the owner of its cause may
+// update it.
+func (h *DisbursementHandler) Update(w http.ResponseWriter, r *http.Request) {
+	disbursement, cause, ok := h.getForChange(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse the request body
+	var input models.DisbursementInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	errs, err := h.validate(r.Context(), cause, input)
+	if err != nil {
+		response.FromError(w, r, "validating disbursement", err)
+		return
+	}
+	if len(errs) > 0 {
+		response.ValidationFailed(w, r, errs)
+		return
+	}
+
+	// Update the disbursement
+	updated, err := h.disbursementRepo.Update(r.Context(), disbursement.ID, input)
+	if err != nil {
+		response.FromError(w, r, "updating disbursement", err)
+		return
+	}
+	if updated == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Disbursement not found")
+		return
+	}
+
+	// Return the updated disbursement
+	response.JSON(w, http.StatusOK, updated)
+}
+
+// Delete deletes a disbursement. Only admins and the owner of its cause may
+// delete it.
+func (h *DisbursementHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	disbursement, _, ok := h.getForChange(w, r)
+	if !ok {
+		return
+	}
+
+	// Delete the disbursement
+	if err := h.disbursementRepo.Delete(r.Context(), disbursement.ID); err != nil {
+		response.FromError(w, r, "deleting disbursement", err)
+		return
+	}
+
+	// Return success
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getForChange gets the disbursement in the URL and its cause, checking that
+// the current user may change it. It writes an error response and returns
+// false otherwise.
+func (h *DisbursementHandler) getForChange(w http.ResponseWriter, r *http.Request) (*models.Disbursement, *models.Cause, bool) {
+	// Get the disbursement ID from the URL
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid disbursement ID")
+		return nil, nil, false
+	}
+
+	disbursement, err := h.disbursementRepo.GetByID(r.Context(), id)
+	if err != nil {
+		response.FromError(w, r, "getting disbursement", err)
+		return nil, nil, false
+	}
+	if disbursement == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Disbursement not found")
+		return nil, nil, false
+	}
+
+	cause, err := h.causeRepo.GetByID(r.Context(), disbursement.CauseID)
+	if err != nil {
+		response.FromError(w, r, "getting cause", err)
+		return nil, nil, false
+	}
+	if cause == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Cause not found")
+		return nil, nil, false
+	}
+
+	if _, ok := h.authorize(w, r, cause); !ok {
+		return nil, nil, false
+	}
+	return disbursement, cause, true
+}
+
+// authorize checks that the current user is an admin or owns cause and
+// returns their ID. It writes a 401 or 403 response and returns false
+// otherwise.
+func (h *DisbursementHandler) authorize(w http.ResponseWriter, r *http.Request, cause *models.Cause) (int, bool) {
+	return middleware.AuthorizeOwnerOrAdmin(w, r, h.userRepo, cause.OwnerID,
+		"Forbidden: only admins and the cause's owner may report its spending")
+}
+
+// validate checks a disbursement input beyond its struct tags: the amount
+// must fit its currency, a withdrawal must belong to the cause and be in the
+// same currency, and hashes and addresses must be well formed
+func (h *DisbursementHandler) validate(ctx context.Context, cause *models.Cause, input models.DisbursementInput) (validation.Errors, error) {
+	errs := validation.Struct(&input)
+
+	currency, currencyOK := validateCurrency(&errs, "currency", input.Currency)
+	if currencyOK {
+		validateAmount(&errs, "amount", input.Amount, currency)
+	}
+
+	validateHex(&errs, "transaction_hash", input.TransactionHash, 32)
+	validateHex(&errs, "recipient_wallet", input.RecipientWallet, 20)
+
+	for i, doc := range input.Documents {
+		for _, fe := range validation.Struct(&doc) {
+			fe.Field = fmt.Sprintf("documents[%d].%s", i, fe.Field)
+			errs = append(errs, fe)
+		}
+		validateHex(&errs, fmt.Sprintf("documents[%d].sha256", i), doc.SHA256, 32)
+	}
+
+	if input.WithdrawalID != nil {
+		withdrawal, err := h.chainRepo.GetWithdrawal(ctx, *input.WithdrawalID)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case withdrawal == nil:
+			errs.Add("withdrawal_id", validation.CodeNotFound, "does not match an existing withdrawal")
+		case withdrawal.CauseID == nil || *withdrawal.CauseID != cause.ID:
+			errs.Add("withdrawal_id", validation.CodeInvalid, "is not a withdrawal of this cause")
+		case currencyOK && withdrawal.Currency != currency:
+			errs.Add("currency", validation.CodeInvalid, fmt.Sprintf("must match the withdrawal's currency, %s", withdrawal.Currency))
+		}
+	}
+
+	return errs, nil
+}
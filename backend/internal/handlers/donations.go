@@ -1,26 +1,40 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/ombima56/transpacharity/internal/ethereum"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/indexer"
 	"github.com/ombima56/transpacharity/internal/middleware"
 	"github.com/ombima56/transpacharity/internal/models"
 	"github.com/ombima56/transpacharity/internal/repository"
+	"github.com/ombima56/transpacharity/internal/validation"
 )
 
 // DonationHandler handles donation-related requests
 type DonationHandler struct {
 	donationRepo *repository.DonationRepository
+	causeRepo    *repository.CauseRepository
+	auditRepo    *repository.AuditRepository
+	userRepo     *repository.UserRepository
+	verifier     *indexer.Verifier
 }
 
-// NewDonationHandler creates a new DonationHandler
-func NewDonationHandler(donationRepo *repository.DonationRepository) *DonationHandler {
+// NewDonationHandler creates a new DonationHandler. verifier may be nil when
+// no contract is configured, in which case on-chain confirmation is disabled.
+func NewDonationHandler(donationRepo *repository.DonationRepository, causeRepo *repository.CauseRepository, auditRepo *repository.AuditRepository, userRepo *repository.UserRepository, verifier *indexer.Verifier) *DonationHandler {
 	return &DonationHandler{
 		donationRepo: donationRepo,
+		causeRepo:    causeRepo,
+		auditRepo:    auditRepo,
+		userRepo:     userRepo,
+		verifier:     verifier,
 	}
 }
 
@@ -28,22 +42,20 @@ func NewDonationHandler(donationRepo *repository.DonationRepository) *DonationHa
 func (h *DonationHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Parse the request body
 	var input models.DonationInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+	if !decodeJSON(w, r, &input) {
 		return
 	}
 
 	// Log the received input for debugging
 	log.Printf("Received donation input: %+v", input)
 
-	// Basic validation
-	if input.CauseID <= 0 {
-		http.Error(w, "Invalid cause ID: must be greater than 0", http.StatusBadRequest)
+	errs, err := h.validate(r.Context(), input)
+	if err != nil {
+		response.FromError(w, r, "validating donation", err)
 		return
 	}
-
-	if input.Amount <= 0 {
-		http.Error(w, "Invalid amount: must be greater than 0", http.StatusBadRequest)
+	if len(errs) > 0 {
+		response.ValidationFailed(w, r, errs)
 		return
 	}
 
@@ -59,28 +71,27 @@ func (h *DonationHandler) Create(w http.ResponseWriter, r *http.Request) {
 	donation, err := h.donationRepo.Create(r.Context(), input)
 	if err != nil {
 		log.Printf("Error creating donation: %v", err)
-		http.Error(w, "Error creating donation: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "creating donation", err)
 		return
 	}
 
 	// Return the donation
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(donation)
+	response.JSON(w, http.StatusCreated, donation)
 }
 
-// GetAll gets all donations
+// GetAll gets one page of donations, filtered by cause_id, user_id, status,
+// currency, min_amount, max_amount, from and to and ordered by sort
 func (h *DonationHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	// Get all donations
-	donations, err := h.donationRepo.GetAll(r.Context())
-	if err != nil {
-		http.Error(w, "Error getting donations: "+err.Error(), http.StatusInternalServerError)
+	// Parse the query string
+	q := queryParams{values: r.URL.Query()}
+	filter := donationFilter(&q)
+	filter.CauseID = q.int("cause_id")
+	if q.err != "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, q.err)
 		return
 	}
 
-	// Return the donations
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(donations)
+	h.writeDonations(w, r, filter)
 }
 
 // GetByID gets a donation by ID
@@ -89,46 +100,103 @@ func (h *DonationHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid donation ID", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid donation ID")
 		return
 	}
 
 	// Get the donation
 	donation, err := h.donationRepo.GetByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Error getting donation: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting donation", err)
 		return
 	}
 	if donation == nil {
-		http.Error(w, "Donation not found", http.StatusNotFound)
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Donation not found")
 		return
 	}
 
 	// Return the donation
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(donation)
+	response.JSON(w, http.StatusOK, donation)
 }
 
-// GetByCauseID gets donations for a cause
+// GetByCauseID gets one page of donations for a cause. It accepts the same
+// filters as GetAll.
 func (h *DonationHandler) GetByCauseID(w http.ResponseWriter, r *http.Request) {
 	// Get the cause ID from the URL
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid cause ID", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid cause ID")
+		return
+	}
+
+	// Parse the query string
+	q := queryParams{values: r.URL.Query()}
+	filter := donationFilter(&q)
+	filter.CauseID = &id
+	if q.err != "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, q.err)
+		return
+	}
+
+	h.writeDonations(w, r, filter)
+}
+
+// writeDonations writes one page of the donations matching filter for the
+// public listings. Only admins may list another user's donations, and
+// anonymous donations are shown without their donor except to the donor and
+// admins.
+func (h *DonationHandler) writeDonations(w http.ResponseWriter, r *http.Request, filter models.DonationFilter) {
+	viewerID, admin, err := h.viewer(r.Context())
+	if err != nil {
+		response.FromError(w, r, "getting viewer", err)
+		return
+	}
+	if filter.UserID != nil && !admin && (viewerID == nil || *viewerID != *filter.UserID) {
+		response.Error(w, r, http.StatusForbidden, response.CodeForbidden, "Forbidden: only admins may list another user's donations")
 		return
 	}
 
 	// Get the donations
-	donations, err := h.donationRepo.GetByCauseID(r.Context(), id)
+	page, err := h.donationRepo.List(r.Context(), filter)
 	if err != nil {
-		http.Error(w, "Error getting donations: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting donations", err)
 		return
 	}
+	for i := range page.Items {
+		hideDonor(&page.Items[i], viewerID, admin)
+	}
 
 	// Return the donations
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(donations)
+	writePage(w, r, page)
+}
+
+// viewer returns the signed-in user, or nil for guests, and whether they are
+// an admin. Like RequireRole, the role is read from the database.
+func (h *DonationHandler) viewer(ctx context.Context) (*int, bool, error) {
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, false, nil
+	}
+	role, err := h.userRepo.GetRole(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	return &userID, role == models.RoleAdmin, nil
+}
+
+// hideDonor removes the donor from an anonymous donation unless the viewer
+// made it or is an admin, and names donors without an account "Anonymous"
+func hideDonor(d *models.Donation, viewerID *int, admin bool) {
+	ownDonation := viewerID != nil && d.UserID != nil && *d.UserID == *viewerID
+	if d.IsAnonymous && !admin && !ownDonation {
+		d.UserID = nil
+		d.UserName = ""
+		d.DonorAddress = ""
+	}
+	if d.UserName == "" {
+		d.UserName = "Anonymous"
+	}
 }
 
 // GetByUserID gets donations for a user
@@ -137,20 +205,19 @@ func (h *DonationHandler) GetByUserID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid user ID")
 		return
 	}
 
 	// Get the donations
 	donations, err := h.donationRepo.GetByUserID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Error getting donations: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting donations", err)
 		return
 	}
 
 	// Return the donations
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(donations)
+	response.JSON(w, http.StatusOK, donations)
 }
 
 // GetMyDonations gets donations for the current user
@@ -158,20 +225,19 @@ func (h *DonationHandler) GetMyDonations(w http.ResponseWriter, r *http.Request)
 	// Get the user ID from the context
 	userID, err := middleware.GetUserIDFromContext(r.Context())
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Get the donations
 	donations, err := h.donationRepo.GetByUserID(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Error getting donations: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting donations", err)
 		return
 	}
 
 	// Return the donations
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(donations)
+	response.JSON(w, http.StatusOK, donations)
 }
 
 // GetRecentDonations gets recent donations
@@ -190,11 +256,305 @@ func (h *DonationHandler) GetRecentDonations(w http.ResponseWriter, r *http.Requ
 	// Get the recent donations
 	donations, err := h.donationRepo.GetRecent(r.Context(), limit)
 	if err != nil {
-		http.Error(w, "Error getting recent donations: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting recent donations", err)
 		return
 	}
 
+	viewerID, admin, err := h.viewer(r.Context())
+	if err != nil {
+		response.FromError(w, r, "getting viewer", err)
+		return
+	}
+	for _, donation := range donations {
+		hideDonor(donation, viewerID, admin)
+	}
+
 	// Return the donations
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(donations)
+	response.JSON(w, http.StatusOK, donations)
+}
+
+// Confirm verifies a donation's transaction on-chain and completes or fails it
+func (h *DonationHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	// Get the donation ID from the URL
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid donation ID")
+		return
+	}
+
+	// Parse the request body
+	var input models.DonationConfirmInput
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	txHash, err := ethereum.NormalizeHash(input.TransactionHash)
+	if err != nil {
+		response.ValidationFailed(w, r, validation.Errors{{
+			Field: "transaction_hash", Code: validation.CodeInvalid, Message: "must be a 32-byte hex transaction hash",
+		}})
+		return
+	}
+
+	if h.verifier == nil {
+		response.Error(w, r, http.StatusServiceUnavailable, response.CodeServiceUnavailable, "On-chain confirmation is not configured")
+		return
+	}
+
+	// Get the donation
+	donation, err := h.donationRepo.GetByID(r.Context(), id)
+	if err != nil {
+		response.FromError(w, r, "getting donation", err)
+		return
+	}
+	if donation == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Donation not found")
+		return
+	}
+
+	// Guest donations may be confirmed without signing in; a user's donation
+	// only by that user or an admin. trusted records whether the caller is the
+	// donor or an admin.
+	var actor *int
+	trusted := false
+	if donation.UserID != nil {
+		actorID, ok := middleware.AuthorizeOwnerOrAdmin(w, r, h.userRepo, donation.UserID,
+			"Forbidden: only the donor and admins may confirm this donation")
+		if !ok {
+			return
+		}
+		actor, trusted = &actorID, true
+	} else if actorID, err := middleware.GetUserIDFromContext(r.Context()); err == nil {
+		actor = &actorID
+		role, err := h.userRepo.GetRole(r.Context(), actorID)
+		if err != nil {
+			response.Internal(w, r, "looking up role of user "+strconv.Itoa(actorID), err)
+			return
+		}
+		trusted = role == models.RoleAdmin
+	}
+
+	if donation.Status != models.DonationStatusPending {
+		response.Error(w, r, http.StatusConflict, response.CodeInvalidStatusTransition, "Donation is already "+string(donation.Status))
+		return
+	}
+
+	cause, err := h.causeRepo.GetByID(r.Context(), donation.CauseID)
+	if err != nil {
+		response.FromError(w, r, "getting cause", err)
+		return
+	}
+	if cause == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Cause not found")
+		return
+	}
+
+	// Check the transaction receipt
+	verification, err := h.verifier.VerifyDonation(r.Context(), donation, cause, txHash)
+	if err != nil {
+		if errors.Is(err, indexer.ErrVerificationMismatch) {
+			response.Error(w, r, http.StatusUnprocessableEntity, response.CodeVerificationFailed, err.Error())
+			return
+		}
+		log.Printf("Error verifying transaction %s: %v", txHash, err)
+		response.Error(w, r, http.StatusBadGateway, response.CodeBadGateway, "Error verifying transaction")
+		return
+	}
+
+	change := models.DonationStatusChange{
+		ActorType:       models.StatusActorUser,
+		ActorID:         actor,
+		TransactionHash: txHash,
+	}
+	switch verification.Status {
+	case indexer.VerificationPending:
+		response.Error(w, r, http.StatusConflict, response.CodeTransactionPending, "Transaction is not yet confirmed on-chain; try again later")
+		return
+	case indexer.VerificationReverted:
+		// Anyone can send a reverted call to the contract, and failing a
+		// donation is final. Unless the verifier matched the sender to the
+		// donor's wallet, only the donor or an admin may fail it this way.
+		if donation.DonorAddress == "" && !trusted {
+			response.Error(w, r, http.StatusUnprocessableEntity, response.CodeVerificationFailed,
+				"Transaction reverted, but its sender cannot be matched to this donation; the donation stays pending")
+			return
+		}
+		change.ToStatus = models.DonationStatusFailed
+		change.Reason = "transaction reverted"
+	case indexer.VerificationConfirmed:
+		change.ToStatus = models.DonationStatusCompleted
+		change.Reason = "DonationMade log verified"
+	}
+
+	// Transition the donation
+	donation, err = h.donationRepo.UpdateStatus(r.Context(), id, change)
+	if err != nil {
+		response.FromError(w, r, "updating donation", err)
+		return
+	}
+	if donation == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Donation not found")
+		return
+	}
+
+	// Return the donation
+	response.JSON(w, http.StatusOK, donation)
+}
+
+// UpdateStatus lets an admin manually complete or fail a pending donation
+func (h *DonationHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	// Get the donation ID from the URL
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid donation ID")
+		return
+	}
+
+	// Parse the request body
+	var input models.DonationStatusInput
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	adminID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Transition the donation
+	donation, err := h.donationRepo.UpdateStatus(r.Context(), id, models.DonationStatusChange{
+		ToStatus:  input.Status,
+		ActorType: models.StatusActorAdmin,
+		ActorID:   &adminID,
+		Reason:    input.Reason,
+	})
+	if err != nil {
+		response.FromError(w, r, "updating donation", err)
+		return
+	}
+	if donation == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Donation not found")
+		return
+	}
+
+	// Return the donation
+	response.JSON(w, http.StatusOK, donation)
+}
+
+// GetStatusHistory gets the status transitions of a donation
+func (h *DonationHandler) GetStatusHistory(w http.ResponseWriter, r *http.Request) {
+	// Get the donation ID from the URL
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid donation ID")
+		return
+	}
+
+	// Get the history
+	changes, err := h.donationRepo.GetStatusHistory(r.Context(), id)
+	if err != nil {
+		response.FromError(w, r, "getting donation history", err)
+		return
+	}
+
+	// Return the history
+	response.JSON(w, http.StatusOK, changes)
+}
+
+// GetProof gets a receipt proving that the donation's latest audit log entry
+// is included in a committed Merkle root. It can be checked offline with
+// pkg/proof. The entry's payload names the donor, so only the donor and
+// admins get it; others get the entry hash and its audit path.
+func (h *DonationHandler) GetProof(w http.ResponseWriter, r *http.Request) {
+	// Get the donation ID from the URL
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid donation ID")
+		return
+	}
+
+	// Check that the donation exists
+	donation, err := h.donationRepo.GetByID(r.Context(), id)
+	if err != nil {
+		response.FromError(w, r, "getting donation", err)
+		return
+	}
+	if donation == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Donation not found")
+		return
+	}
+
+	// Get the proof
+	receipt, err := h.auditRepo.Receipt(r.Context(), donation.ID)
+	if err != nil {
+		response.FromError(w, r, "getting donation proof", err)
+		return
+	}
+	if receipt == nil {
+		response.Error(w, r, http.StatusConflict, response.CodeProofPending, "Donation is not yet included in a committed audit root; try again later")
+		return
+	}
+
+	viewerID, admin, err := h.viewer(r.Context())
+	if err != nil {
+		response.FromError(w, r, "getting viewer", err)
+		return
+	}
+	ownDonation := viewerID != nil && donation.UserID != nil && *donation.UserID == *viewerID
+	if !admin && !ownDonation {
+		receipt.Payload, receipt.PrevHash = "", ""
+	}
+
+	// Return the proof
+	response.JSON(w, http.StatusOK, receipt)
+}
+
+// donationFilter reads the donation list parameters shared by GetAll and
+// GetByCauseID
+func donationFilter(q *queryParams) models.DonationFilter {
+	filter := models.DonationFilter{
+		PageRequest: q.page(models.DonationSortKeys, "-created_at"),
+		UserID:      q.int("user_id"),
+		Currency:    q.currency("currency"),
+		MinAmount:   q.decimal("min_amount"),
+		MaxAmount:   q.decimal("max_amount"),
+		From:        q.time("from", false),
+		To:          q.time("to", true),
+	}
+	if status := q.values.Get("status"); status != "" {
+		filter.Status = models.DonationStatus(status)
+		if !filter.Status.IsValid() {
+			q.invalid("status", "must be one of pending, completed, failed")
+		}
+	}
+	return filter
+}
+
+// validate checks a donation input's tags, its amount in the chosen currency
+// against the per-donation limit, and that its cause exists
+func (h *DonationHandler) validate(ctx context.Context, input models.DonationInput) (validation.Errors, error) {
+	errs := validation.Struct(&input)
+
+	if currency, ok := validateCurrency(&errs, "currency", input.Currency); ok {
+		if amount, ok := validateAmount(&errs, "amount", input.Amount, currency); ok {
+			validateMaxDonation(&errs, "amount", amount)
+		}
+	}
+
+	if !errs.Has("cause_id") {
+		cause, err := h.causeRepo.GetByID(ctx, input.CauseID)
+		if err != nil {
+			return nil, err
+		}
+		if cause == nil {
+			errs.Add("cause_id", validation.CodeNotFound, "does not match an existing cause")
+		}
+	}
+
+	return errs, nil
 }
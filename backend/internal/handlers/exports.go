@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/export"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+// ExportHandler handles exports of donations for finance teams
+type ExportHandler struct {
+	donationRepo *repository.DonationRepository
+}
+
+// NewExportHandler creates a new ExportHandler
+func NewExportHandler(donationRepo *repository.DonationRepository) *ExportHandler {
+	return &ExportHandler{donationRepo: donationRepo}
+}
+
+// Donations exports every donation matching the same filters as GetAll, and
+// ordered by sort, as a csv, jsonl or xlsx download chosen by format. limit
+// and cursor are ignored. Rows are streamed as they are read from the
+// database, so if the export fails part way the connection is aborted rather
+// than ending the download early as if it were complete.
+func (h *ExportHandler) Donations(w http.ResponseWriter, r *http.Request) {
+	// Parse the query string
+	q := queryParams{values: r.URL.Query()}
+	filter := donationFilter(&q)
+	filter.CauseID = q.int("cause_id")
+	format := export.CSV
+	if s := q.values.Get("format"); s != "" {
+		var err error
+		if format, err = export.ParseFormat(s); err != nil {
+			q.invalid("format", "must be one of csv, jsonl, xlsx")
+		}
+	}
+	if q.err != "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, q.err)
+		return
+	}
+
+	// The response starts with the first row, so that errors running the
+	// query can still be reported with an error response
+	var writer export.Writer
+	start := func() error {
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+			fmt.Sprintf("donations-%s.%s", time.Now().UTC().Format("20060102-150405"), format)))
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.WriteHeader(http.StatusOK)
+
+		var err error
+		writer, err = export.NewDonationWriter(w, format)
+		return err
+	}
+
+	// Stream the donations
+	rows := 0
+	err := h.donationRepo.Export(r.Context(), filter, func(d models.Donation) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		rows++
+		return writer.Write(d)
+	})
+	if err == nil && writer == nil {
+		err = start()
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		if writer == nil {
+			response.FromError(w, r, "exporting donations", err)
+			return
+		}
+		log.Printf("Error exporting donations after %d rows: %v", rows, err)
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ombima56/transpacharity/internal/middleware"
+)
+
+// JWKSHandler publishes the public keys that verify our access tokens
+type JWKSHandler struct {
+	keys *middleware.KeySet
+}
+
+// NewJWKSHandler creates a new JWKSHandler
+func NewJWKSHandler(keys *middleware.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// Get returns the JSON Web Key Set
+func (h *JWKSHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.keys.JWKS())
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+// LedgerHandler serves the public ledger of donations and withdrawals
+type LedgerHandler struct {
+	ledgerRepo  *repository.LedgerRepository
+	explorerURL string
+}
+
+// NewLedgerHandler creates a new LedgerHandler. Entries with a transaction
+// hash link to it on explorerURL, e.g. https://etherscan.io.
+func NewLedgerHandler(ledgerRepo *repository.LedgerRepository, explorerURL string) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerRepo:  ledgerRepo,
+		explorerURL: explorerURL,
+	}
+}
+
+// GetAll gets one page of the ledger, filtered by cause_id, currency, type,
+// from and to and ordered by sort
+func (h *LedgerHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	// Parse the query string
+	q := queryParams{values: r.URL.Query()}
+	filter := models.LedgerFilter{
+		PageRequest: q.page(models.LedgerSortKeys, "-occurred_at"),
+		CauseID:     q.int("cause_id"),
+		Currency:    q.currency("currency"),
+		Type:        q.values.Get("type"),
+		From:        q.time("from", false),
+		To:          q.time("to", true),
+	}
+	if filter.Type != "" && filter.Type != models.LedgerEntryDonation && filter.Type != models.LedgerEntryWithdrawal {
+		q.invalid("type", "must be donation or withdrawal")
+	}
+	if q.err != "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, q.err)
+		return
+	}
+
+	// Get the entries
+	page, err := h.ledgerRepo.List(r.Context(), filter)
+	if err != nil {
+		response.FromError(w, r, "getting ledger", err)
+		return
+	}
+	for i := range page.Items {
+		if hash := page.Items[i].TransactionHash; hash != "" && h.explorerURL != "" {
+			page.Items[i].ExplorerURL = h.explorerURL + "/tx/" + hash
+		}
+	}
+
+	// Return the entries
+	writePage(w, r, page)
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// queryParams reads optional list parameters from a query string, remembering
+// the first invalid one
+type queryParams struct {
+	values url.Values
+	err    string
+}
+
+// invalid records a problem with a parameter unless one was already found
+func (q *queryParams) invalid(name, problem string) {
+	if q.err == "" {
+		q.err = fmt.Sprintf("Invalid %s: %s", name, problem)
+	}
+}
+
+// int returns a positive integer parameter, or nil if it is absent
+func (q *queryParams) int(name string) *int {
+	s := q.values.Get(name)
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		q.invalid(name, "must be a positive integer")
+		return nil
+	}
+	return &n
+}
+
+// bool returns a boolean parameter, or nil if it is absent
+func (q *queryParams) bool(name string) *bool {
+	s := q.values.Get(name)
+	if s == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		q.invalid(name, "must be true or false")
+		return nil
+	}
+	return &b
+}
+
+// decimal returns a non-negative decimal parameter, or "" if it is absent
+func (q *queryParams) decimal(name string) string {
+	s := q.values.Get(name)
+	if s == "" {
+		return ""
+	}
+	n, ok := new(big.Rat).SetString(s)
+	if !ok || strings.Contains(s, "/") || n.Sign() < 0 {
+		q.invalid(name, "must be a non-negative decimal number")
+		return ""
+	}
+	return s
+}
+
+// time returns an RFC 3339 timestamp or YYYY-MM-DD date parameter, or nil if
+// it is absent. With endOfDay a date means the end of that day, so that date
+// ranges include their last day.
+func (q *queryParams) time(name string, endOfDay bool) *time.Time {
+	s := q.values.Get(name)
+	if s == "" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		t = t.UTC()
+		return &t
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		q.invalid(name, "must be a date (2006-01-02) or RFC 3339 timestamp")
+		return nil
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t
+}
+
+// currency returns a currency parameter, or "" if it is absent
+func (q *queryParams) currency(name string) models.Currency {
+	s := q.values.Get(name)
+	if s == "" {
+		return ""
+	}
+	currency, err := models.ParseCurrency(s)
+	if err != nil {
+		q.invalid(name, "must be one of USD, USDC, ETH")
+		return ""
+	}
+	return currency
+}
+
+// page returns the limit, cursor and sort parameters. sort must be one of
+// keys, optionally prefixed with "-" for descending order.
+func (q *queryParams) page(keys []string, defaultSort string) models.PageRequest {
+	page := models.PageRequest{Limit: q.limit(), Cursor: q.values.Get("cursor")}
+
+	sort := q.values.Get("sort")
+	if sort == "" {
+		sort = defaultSort
+	}
+	page.SortKey = strings.TrimPrefix(sort, "-")
+	page.Descending = strings.HasPrefix(sort, "-")
+	if !contains(keys, page.SortKey) {
+		q.invalid("sort", "must be one of "+strings.Join(keys, ", ")+", optionally prefixed with -")
+	}
+
+	return page
+}
+
+// limit returns the page size parameter, or the default page size if it is
+// absent
+func (q *queryParams) limit() int {
+	s := q.values.Get("limit")
+	if s == "" {
+		return models.DefaultPageLimit
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 || limit > models.MaxPageLimit {
+		q.invalid("limit", fmt.Sprintf("must be between 1 and %d", models.MaxPageLimit))
+		return models.DefaultPageLimit
+	}
+	return limit
+}
+
+// writePage writes the items of a page as a JSON array. X-Total-Count holds
+// the number of matching items and Link points to the first and next pages.
+func writePage[T any](w http.ResponseWriter, r *http.Request, page *models.Page[T]) {
+	links := []string{pageLink(r, "", "first")}
+	if page.NextCursor != "" {
+		links = append(links, pageLink(r, page.NextCursor, "next"))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+
+	items := page.Items
+	if items == nil {
+		items = []T{}
+	}
+	response.JSON(w, http.StatusOK, items)
+}
+
+// pageLink returns a Link header entry for the current request with its
+// cursor replaced
+func pageLink(r *http.Request, cursor, rel string) string {
+	query := r.URL.Query()
+	query.Del("cursor")
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel)
+}
+
+// contains reports whether values contains s
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
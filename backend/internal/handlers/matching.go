@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/middleware"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+	"github.com/ombima56/transpacharity/internal/validation"
+)
+
+// MatchingPledgeHandler handles sponsors' pledges to match donations to a
+// cause
+type MatchingPledgeHandler struct {
+	pledgeRepo *repository.MatchingPledgeRepository
+	causeRepo  *repository.CauseRepository
+}
+
+// NewMatchingPledgeHandler creates a new MatchingPledgeHandler
+func NewMatchingPledgeHandler(pledgeRepo *repository.MatchingPledgeRepository, causeRepo *repository.CauseRepository) *MatchingPledgeHandler {
+	return &MatchingPledgeHandler{
+		pledgeRepo: pledgeRepo,
+		causeRepo:  causeRepo,
+	}
+}
+
+// GetByCauseID gets the matching pledges of a cause
+func (h *MatchingPledgeHandler) GetByCauseID(w http.ResponseWriter, r *http.Request) {
+	// Get the cause ID from the URL
+	idStr := chi.URLParam(r, "id")
+	causeID, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid cause ID")
+		return
+	}
+
+	cause, err := h.causeRepo.GetByID(r.Context(), causeID)
+	if err != nil {
+		response.FromError(w, r, "getting cause", err)
+		return
+	}
+	if cause == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Cause not found")
+		return
+	}
+
+	// Get the pledges
+	pledges, err := h.pledgeRepo.GetByCauseID(r.Context(), cause.ID)
+	if err != nil {
+		response.FromError(w, r, "getting matching pledges", err)
+		return
+	}
+
+	// Return the pledges
+	response.JSON(w, http.StatusOK, pledges)
+}
+
+// Create creates a matching pledge for a cause
+func (h *MatchingPledgeHandler) Create(w http.ResponseWriter, r *http.Request) {
+	// Get the cause ID from the URL
+	idStr := chi.URLParam(r, "id")
+	causeID, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid cause ID")
+		return
+	}
+
+	cause, err := h.causeRepo.GetByID(r.Context(), causeID)
+	if err != nil {
+		response.FromError(w, r, "getting cause", err)
+		return
+	}
+	if cause == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Cause not found")
+		return
+	}
+
+	// Parse the request body
+	var input models.MatchingPledgeInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	if input.Currency == "" {
+		input.Currency = string(cause.Currency)
+	}
+
+	errs := validation.Struct(&input)
+	if currency, ok := validateCurrency(&errs, "currency", input.Currency); ok {
+		validateAmount(&errs, "cap", input.Cap, currency)
+	}
+	switch {
+	case input.EndsAt.IsZero():
+		errs.Add("ends_at", validation.CodeRequired, "is required")
+	case !input.EndsAt.After(time.Now()):
+		errs.Add("ends_at", validation.CodeInvalid, "must be in the future")
+	case input.StartsAt != nil && !input.EndsAt.After(*input.StartsAt):
+		errs.Add("ends_at", validation.CodeInvalid, "must be after starts_at")
+	}
+	if len(errs) > 0 {
+		response.ValidationFailed(w, r, errs)
+		return
+	}
+
+	// The admin creating the pledge is recorded with it
+	var createdBy *int
+	if userID, err := middleware.GetUserIDFromContext(r.Context()); err == nil {
+		createdBy = &userID
+	}
+
+	// Create the pledge
+	pledge, err := h.pledgeRepo.Create(r.Context(), cause.ID, createdBy, input)
+	if err != nil {
+		response.FromError(w, r, "creating matching pledge", err)
+		return
+	}
+
+	// Return the pledge
+	response.JSON(w, http.StatusCreated, pledge)
+}
+
+// Close stops a matching pledge from matching further donations
+func (h *MatchingPledgeHandler) Close(w http.ResponseWriter, r *http.Request) {
+	// Get the pledge ID from the URL
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid pledge ID")
+		return
+	}
+
+	// Close the pledge
+	pledge, err := h.pledgeRepo.Close(r.Context(), id)
+	if err != nil {
+		response.FromError(w, r, "closing matching pledge", err)
+		return
+	}
+	if pledge == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Matching pledge not found")
+		return
+	}
+
+	// Return the closed pledge
+	response.JSON(w, http.StatusOK, pledge)
+}
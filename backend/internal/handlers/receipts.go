@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/middleware"
+	"github.com/ombima56/transpacharity/internal/receipt"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+// firstStatementYear is the earliest year a statement can be requested for
+const firstStatementYear = 2020
+
+// ReceiptHandler handles donation receipts and annual statements
+type ReceiptHandler struct {
+	receiptRepo  *repository.ReceiptRepository
+	donationRepo *repository.DonationRepository
+	userRepo     *repository.UserRepository
+	renderer     *receipt.Renderer
+}
+
+// NewReceiptHandler creates a new ReceiptHandler
+func NewReceiptHandler(
+	receiptRepo *repository.ReceiptRepository,
+	donationRepo *repository.DonationRepository,
+	userRepo *repository.UserRepository,
+	renderer *receipt.Renderer,
+) *ReceiptHandler {
+	return &ReceiptHandler{
+		receiptRepo:  receiptRepo,
+		donationRepo: donationRepo,
+		userRepo:     userRepo,
+		renderer:     renderer,
+	}
+}
+
+// GetDonationReceipt gets the PDF receipt for a completed donation. Only the
+// donor and admins may download it. The receipt is numbered the first time
+// it is requested.
+func (h *ReceiptHandler) GetDonationReceipt(w http.ResponseWriter, r *http.Request) {
+	// Get the donation ID from the URL
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid donation ID")
+		return
+	}
+
+	// Get the donation
+	donation, err := h.donationRepo.GetByID(r.Context(), id)
+	if err != nil {
+		response.FromError(w, r, "getting donation", err)
+		return
+	}
+	if donation == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Donation not found")
+		return
+	}
+	_, ok := middleware.AuthorizeOwnerOrAdmin(w, r, h.userRepo, donation.UserID,
+		"Forbidden: only the donor and admins may download a donation's receipt")
+	if !ok {
+		return
+	}
+
+	// Get or issue the receipt
+	issued, err := h.receiptRepo.Issue(r.Context(), id)
+	if err != nil {
+		response.FromError(w, r, "issuing receipt", err)
+		return
+	}
+	if issued == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Donation not found")
+		return
+	}
+
+	// Return the receipt
+	var buf bytes.Buffer
+	if err := h.renderer.Receipt(&buf, issued); err != nil {
+		response.Internal(w, r, "rendering receipt", err)
+		return
+	}
+	writePDF(w, fmt.Sprintf("receipt-%s.pdf", issued.ReceiptNumber), buf.Bytes())
+}
+
+// GetMyStatement gets the current user's PDF statement of the donations they
+// completed in a year, issuing any receipts not yet issued
+func (h *ReceiptHandler) GetMyStatement(w http.ResponseWriter, r *http.Request) {
+	// Get the user ID from the context
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get the year from the URL
+	year, err := strconv.Atoi(chi.URLParam(r, "year"))
+	if err != nil || year < firstStatementYear || year > time.Now().UTC().Year() {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter,
+			fmt.Sprintf("Year must be between %d and the current year", firstStatementYear))
+		return
+	}
+
+	// Get the statement
+	statement, err := h.receiptRepo.Statement(r.Context(), userID, year)
+	if err != nil {
+		response.FromError(w, r, "getting donation statement", err)
+		return
+	}
+
+	// Return the statement
+	var buf bytes.Buffer
+	if err := h.renderer.Statement(&buf, statement); err != nil {
+		response.Internal(w, r, "rendering donation statement", err)
+		return
+	}
+	writePDF(w, fmt.Sprintf("donation-statement-%d.pdf", year), buf.Bytes())
+}
+
+// Verify gets the details of the receipt with the verification code printed
+// on it, so that anyone holding a receipt can check that it is genuine
+func (h *ReceiptHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	// Get the receipt
+	issued, err := h.receiptRepo.GetByVerificationCode(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		response.FromError(w, r, "verifying receipt", err)
+		return
+	}
+	if issued == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Receipt not found")
+		return
+	}
+
+	// Return the receipt
+	response.JSON(w, http.StatusOK, issued)
+}
+
+// writePDF writes a PDF document as a download named filename
+func writePDF(w http.ResponseWriter, filename string, document []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(document)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/middleware"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+	"github.com/ombima56/transpacharity/internal/validation"
+)
+
+// RecurringDonationHandler handles the current user's recurring donations
+type RecurringDonationHandler struct {
+	recurringRepo *repository.RecurringDonationRepository
+	causeRepo     *repository.CauseRepository
+}
+
+// NewRecurringDonationHandler creates a new RecurringDonationHandler
+func NewRecurringDonationHandler(recurringRepo *repository.RecurringDonationRepository, causeRepo *repository.CauseRepository) *RecurringDonationHandler {
+	return &RecurringDonationHandler{
+		recurringRepo: recurringRepo,
+		causeRepo:     causeRepo,
+	}
+}
+
+// GetMine gets the current user's recurring donations
+func (h *RecurringDonationHandler) GetMine(w http.ResponseWriter, r *http.Request) {
+	// Get the user ID from the context
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get the recurring donations
+	recurring, err := h.recurringRepo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		response.FromError(w, r, "getting recurring donations", err)
+		return
+	}
+
+	// Return the recurring donations
+	response.JSON(w, http.StatusOK, recurring)
+}
+
+// GetByID gets one of the current user's recurring donations by ID
+func (h *RecurringDonationHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := recurringIDs(w, r)
+	if !ok {
+		return
+	}
+
+	// Get the recurring donation
+	recurring, err := h.recurringRepo.GetByID(r.Context(), userID, id)
+	if err != nil {
+		response.FromError(w, r, "getting recurring donation", err)
+		return
+	}
+	if recurring == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Recurring donation not found")
+		return
+	}
+
+	// Return the recurring donation
+	response.JSON(w, http.StatusOK, recurring)
+}
+
+// Create creates a recurring donation for the current user. Its first
+// donation is made at start_at, or straight away if start_at is not set.
+func (h *RecurringDonationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	// Get the user ID from the context
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse the request body
+	var input models.RecurringDonationInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	errs, err := h.validate(r.Context(), input)
+	if err != nil {
+		response.FromError(w, r, "validating recurring donation", err)
+		return
+	}
+	if input.StartAt != nil && input.StartAt.Before(time.Now().Add(-time.Minute)) {
+		errs.Add("start_at", validation.CodeInvalid, "must not be in the past")
+	}
+	if len(errs) > 0 {
+		response.ValidationFailed(w, r, errs)
+		return
+	}
+
+	// Create the recurring donation
+	recurring, err := h.recurringRepo.Create(r.Context(), userID, input)
+	if err != nil {
+		response.FromError(w, r, "creating recurring donation", err)
+		return
+	}
+
+	// Return the recurring donation
+	response.JSON(w, http.StatusCreated, recurring)
+}
+
+// Update changes the cause, amount, anonymity and interval of one of the
+// current user's recurring donations. start_at is ignored.
+func (h *RecurringDonationHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := recurringIDs(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse the request body
+	var input models.RecurringDonationInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	errs, err := h.validate(r.Context(), input)
+	if err != nil {
+		response.FromError(w, r, "validating recurring donation", err)
+		return
+	}
+	if len(errs) > 0 {
+		response.ValidationFailed(w, r, errs)
+		return
+	}
+
+	// Update the recurring donation
+	recurring, err := h.recurringRepo.Update(r.Context(), userID, id, input)
+	if err != nil {
+		response.FromError(w, r, "updating recurring donation", err)
+		return
+	}
+	if recurring == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Recurring donation not found")
+		return
+	}
+
+	// Return the updated recurring donation
+	response.JSON(w, http.StatusOK, recurring)
+}
+
+// Pause stops one of the current user's recurring donations until it is
+// resumed
+func (h *RecurringDonationHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, models.RecurringStatusPaused)
+}
+
+// Resume restarts a paused recurring donation from its next run after now.
+// Runs that fell due while it was paused are skipped.
+func (h *RecurringDonationHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, models.RecurringStatusActive)
+}
+
+// Cancel stops one of the current user's recurring donations for good. The
+// donations it already made are kept.
+func (h *RecurringDonationHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, models.RecurringStatusCancelled)
+}
+
+// setStatus moves the recurring donation in the URL to status
+func (h *RecurringDonationHandler) setStatus(w http.ResponseWriter, r *http.Request, status models.RecurringStatus) {
+	userID, id, ok := recurringIDs(w, r)
+	if !ok {
+		return
+	}
+
+	// Update the status
+	recurring, err := h.recurringRepo.SetStatus(r.Context(), userID, id, status)
+	if err != nil {
+		response.FromError(w, r, "updating recurring donation status", err)
+		return
+	}
+	if recurring == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "Recurring donation not found")
+		return
+	}
+
+	// Return the updated recurring donation
+	response.JSON(w, http.StatusOK, recurring)
+}
+
+// recurringIDs gets the current user's ID and the recurring donation ID in
+// the URL. It writes an error response and returns false if either is
+// missing.
+func recurringIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	// Get the user ID from the context
+	userID, err := middleware.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+
+	// Get the recurring donation ID from the URL
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid recurring donation ID")
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+// validate checks a recurring donation input beyond its struct tags: each of
+// its donations must be a valid donation to an existing cause
+func (h *RecurringDonationHandler) validate(ctx context.Context, input models.RecurringDonationInput) (validation.Errors, error) {
+	errs := validation.Struct(&input)
+
+	if currency, ok := validateCurrency(&errs, "currency", input.Currency); ok {
+		if amount, ok := validateAmount(&errs, "amount", input.Amount, currency); ok {
+			validateMaxDonation(&errs, "amount", amount)
+		}
+	}
+
+	if !errs.Has("cause_id") {
+		cause, err := h.causeRepo.GetByID(ctx, input.CauseID)
+		if err != nil {
+			return nil, err
+		}
+		if cause == nil {
+			errs.Add("cause_id", validation.CodeNotFound, "does not match an existing cause")
+		}
+	}
+
+	return errs, nil
+}
@@ -0,0 +1,163 @@
+// Package response writes JSON responses and the error envelope shared by
+// every API handler:
+//
+//	{"error": {"code": "...", "message": "...", "details": ..., "request_id": "..."}}
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+	"github.com/ombima56/transpacharity/internal/validation"
+)
+
+// Error codes. Clients should branch on these rather than on messages.
+const (
+	CodeInvalidBody             = "invalid_body"
+	CodeInvalidParameter        = "invalid_parameter"
+	CodeValidationFailed        = "validation_failed"
+	CodeUnauthorized            = "unauthorized"
+	CodeInvalidCredentials      = "invalid_credentials"
+	CodeInvalidToken            = "invalid_token"
+	CodeSessionRevoked          = "session_revoked"
+	CodeRefreshTokenReused      = "refresh_token_reused"
+	CodeForbidden               = "forbidden"
+	CodeNotFound                = "not_found"
+	CodeMethodNotAllowed        = "method_not_allowed"
+	CodeConflict                = "conflict"
+	CodeEmailTaken              = "email_taken"
+	CodeCategoryNameTaken       = "category_name_taken"
+	CodeInUse                   = "in_use"
+	CodeInvalidReference        = "invalid_reference"
+	CodeLastAdmin               = "last_admin"
+	CodeInvalidStatusTransition = "invalid_status_transition"
+	CodeTransactionPending      = "transaction_pending"
+	CodeVerificationFailed      = "verification_failed"
+	CodeProofPending            = "proof_pending"
+	CodeExceedsWithdrawal       = "exceeds_withdrawal"
+	CodeTooManyRequests         = "too_many_requests"
+	CodeIdempotencyKeyReused    = "idempotency_key_reused"
+	CodeIdempotencyInProgress   = "idempotency_in_progress"
+	CodeInternal                = "internal_error"
+	CodeBadGateway              = "bad_gateway"
+	CodeServiceUnavailable      = "service_unavailable"
+)
+
+// ErrorBody is the content of the error envelope
+type ErrorBody struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// sentinel maps an error returned by a repository or model to a response
+type sentinel struct {
+	err    error
+	status int
+	code   string
+}
+
+// sentinels lists the errors FromError reports to clients. Their messages are
+// written for clients; anything else is logged and reported as a 500.
+var sentinels = []sentinel{
+	{repository.ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{repository.ErrEmailTaken, http.StatusConflict, CodeEmailTaken},
+	{repository.ErrCategoryNameTaken, http.StatusConflict, CodeCategoryNameTaken},
+	{repository.ErrInUse, http.StatusConflict, CodeInUse},
+	{repository.ErrTransactionClaimed, http.StatusConflict, CodeConflict},
+	{repository.ErrConflict, http.StatusConflict, CodeConflict},
+	{repository.ErrInvalidReference, http.StatusUnprocessableEntity, CodeInvalidReference},
+	{repository.ErrInvalidCursor, http.StatusBadRequest, CodeInvalidParameter},
+	{repository.ErrLastAdmin, http.StatusConflict, CodeLastAdmin},
+	{repository.ErrInvalidUserToken, http.StatusBadRequest, CodeInvalidToken},
+	{repository.ErrRefreshTokenReused, http.StatusUnauthorized, CodeRefreshTokenReused},
+	{repository.ErrSessionNotFound, http.StatusUnauthorized, CodeInvalidToken},
+	{repository.ErrSessionExpired, http.StatusUnauthorized, CodeInvalidToken},
+	{repository.ErrSessionRevoked, http.StatusUnauthorized, CodeSessionRevoked},
+	{models.ErrInvalidStatusTransition, http.StatusConflict, CodeInvalidStatusTransition},
+	{models.ErrExceedsWithdrawal, http.StatusUnprocessableEntity, CodeExceedsWithdrawal},
+	{models.ErrInvalidRecurringTransition, http.StatusConflict, CodeInvalidStatusTransition},
+	{models.ErrRecurringCancelled, http.StatusConflict, CodeConflict},
+	{models.ErrPledgeClosed, http.StatusConflict, CodeConflict},
+	{models.ErrReceiptUnavailable, http.StatusConflict, CodeConflict},
+}
+
+// JSON writes v as a JSON response with the given status
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// Error writes an error envelope with the given status, code and message
+func Error(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	write(w, r, status, ErrorBody{Code: code, Message: message})
+}
+
+// ValidationFailed writes a 422 listing every invalid field
+func ValidationFailed(w http.ResponseWriter, r *http.Request, errs validation.Errors) {
+	write(w, r, http.StatusUnprocessableEntity, ErrorBody{
+		Code:    CodeValidationFailed,
+		Message: "Validation failed",
+		Details: errs,
+	})
+}
+
+// FromError reports err to the client. Known sentinel errors get their own
+// status and code; any other error is logged with action, e.g. "getting
+// causes", and answered with a 500 that does not reveal its details.
+func FromError(w http.ResponseWriter, r *http.Request, action string, err error) {
+	for _, s := range sentinels {
+		if errors.Is(err, s.err) {
+			Error(w, r, s.status, s.code, capitalize(s.err.Error()))
+			return
+		}
+	}
+
+	var errs validation.Errors
+	if errors.As(err, &errs) {
+		ValidationFailed(w, r, errs)
+		return
+	}
+
+	Internal(w, r, action, err)
+}
+
+// Internal logs err and writes a generic 500
+func Internal(w http.ResponseWriter, r *http.Request, action string, err error) {
+	log.Printf("[%s] Error %s: %v", chimiddleware.GetReqID(r.Context()), action, err)
+	Error(w, r, http.StatusInternalServerError, CodeInternal, "An internal error occurred")
+}
+
+// NotFound is an http.HandlerFunc for unknown routes
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, http.StatusNotFound, CodeNotFound, "Route not found")
+}
+
+// MethodNotAllowed is an http.HandlerFunc for known routes called with the
+// wrong method
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+}
+
+// write writes the error envelope, tagging it with the request ID
+func write(w http.ResponseWriter, r *http.Request, status int, body ErrorBody) {
+	body.RequestID = chimiddleware.GetReqID(r.Context())
+	JSON(w, status, map[string]ErrorBody{"error": body})
+}
+
+// capitalize upper-cases the first letter of a sentinel error message
+func capitalize(s string) string {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return s
+	}
+	return string(s[0]-'a'+'A') + s[1:]
+}
@@ -1,28 +1,62 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/mailer"
 	"github.com/ombima56/transpacharity/internal/middleware"
 	"github.com/ombima56/transpacharity/internal/models"
 	"github.com/ombima56/transpacharity/internal/repository"
+	"github.com/ombima56/transpacharity/internal/throttle"
 )
 
 // UserHandler handles user-related requests
 type UserHandler struct {
-	userRepo *repository.UserRepository
-	jwtCfg   *config.JWTConfig
+	userRepo    *repository.UserRepository
+	sessionRepo *repository.SessionRepository
+	tokenRepo   *repository.UserTokenRepository
+	attemptRepo *repository.LoginAttemptRepository
+	keys        *middleware.KeySet
+	jwtCfg      *config.JWTConfig
+	limiter     *throttle.Limiter
+	mailer      mailer.Mailer
+	appURL      string
 }
 
+// dummyPasswordHash is checked against when a login names an unknown email,
+// so the response takes as long as for a wrong password
+var dummyPasswordHash, _ = models.HashPassword("not the password of any user")
+
 // NewUserHandler creates a new UserHandler
-func NewUserHandler(userRepo *repository.UserRepository, jwtCfg *config.JWTConfig) *UserHandler {
+func NewUserHandler(
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	tokenRepo *repository.UserTokenRepository,
+	attemptRepo *repository.LoginAttemptRepository,
+	keys *middleware.KeySet,
+	limiter *throttle.Limiter,
+	mail mailer.Mailer,
+	appURL string,
+) *UserHandler {
 	return &UserHandler{
-		userRepo: userRepo,
-		jwtCfg:   jwtCfg,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		tokenRepo:   tokenRepo,
+		attemptRepo: attemptRepo,
+		keys:        keys,
+		jwtCfg:      keys.Config(),
+		limiter:     limiter,
+		mailer:      mail,
+		appURL:      appURL,
 	}
 }
 
@@ -30,86 +64,238 @@ func NewUserHandler(userRepo *repository.UserRepository, jwtCfg *config.JWTConfi
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	// Parse the request body
 	var input models.UserInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeAndValidate(w, r, &input) {
 		return
 	}
 
 	// Check if the email is already in use
 	existingUser, err := h.userRepo.GetByEmail(r.Context(), input.Email)
 	if err != nil {
-		http.Error(w, "Error checking email: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "checking email", err)
 		return
 	}
 	if existingUser != nil {
-		http.Error(w, "Email already in use", http.StatusBadRequest)
+		response.FromError(w, r, "registering", repository.ErrEmailTaken)
 		return
 	}
 
 	// Create the user
 	user, err := h.userRepo.Create(r.Context(), input)
 	if err != nil {
-		http.Error(w, "Error creating user: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "creating user", err)
 		return
 	}
 
-	// Generate a JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Email, h.jwtCfg)
+	// Ask the user to confirm their email address; registration still succeeds
+	// if the email cannot be sent since it can be requested again
+	if err := h.sendVerificationEmail(r.Context(), &user); err != nil {
+		log.Printf("Error sending verification email to user %d: %v", user.ID, err)
+	}
+
+	// Start a session
+	tokens, err := h.startSession(r, &user)
 	if err != nil {
-		http.Error(w, "Error generating token: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "generating token", err)
 		return
 	}
 
-	// Return the user and token
-	response := map[string]interface{}{
-		"user":  user,
-		"token": token,
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	// Return the user and tokens
+	response.JSON(w, http.StatusCreated, tokens)
 }
 
 // Login handles user login
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Parse the request body
 	var input models.UserLoginInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	// Refuse attempts while the account or client IP is throttled
+	ip := clientIP(r)
+	wait, err := h.limiter.Check(r.Context(), input.Email, ip)
+	if err != nil {
+		response.FromError(w, r, "checking login attempts", err)
+		return
+	}
+	if wait > 0 {
+		h.recordLogin(r, input.Email, nil, models.LoginResultThrottled)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		response.Error(w, r, http.StatusTooManyRequests, response.CodeTooManyRequests, "Too many login attempts; please try again later")
 		return
 	}
 
 	// Get the user by email
 	user, err := h.userRepo.GetByEmail(r.Context(), input.Email)
 	if err != nil {
-		http.Error(w, "Error getting user: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting user", err)
 		return
 	}
-	if user == nil {
-		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+
+	// Check the password. Unknown emails are checked against a dummy hash and
+	// counted like wrong passwords so they cannot be told apart.
+	passwordHash := dummyPasswordHash
+	if user != nil {
+		passwordHash = user.PasswordHash
+	}
+	if !models.CheckPassword(input.Password, passwordHash) || user == nil {
+		h.recordLogin(r, input.Email, user, models.LoginResultInvalidCredentials)
+		if err := h.limiter.Failure(r.Context(), input.Email, ip); err != nil {
+			log.Printf("Error recording failed login: %v", err)
+		}
+		response.Error(w, r, http.StatusUnauthorized, response.CodeInvalidCredentials, "Invalid email or password")
 		return
 	}
 
-	// Check the password
-	if !models.CheckPassword(input.Password, user.PasswordHash) {
-		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+	h.recordLogin(r, input.Email, user, models.LoginResultSuccess)
+	if err := h.limiter.Success(r.Context(), input.Email); err != nil {
+		log.Printf("Error clearing failed logins: %v", err)
+	}
+
+	// Start a session
+	tokens, err := h.startSession(r, user)
+	if err != nil {
+		response.FromError(w, r, "generating token", err)
+		return
+	}
+
+	// Return the user and tokens
+	response.JSON(w, http.StatusOK, tokens)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token. Each
+// refresh token can only be used once; reusing one logs out the whole session.
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	// Parse the request body
+	var input models.RefreshTokenInput
+	if !decodeAndValidate(w, r, &input) {
 		return
 	}
 
-	// Generate a JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Email, h.jwtCfg)
+	refreshToken, refreshHash, err := middleware.GenerateOpaqueToken()
 	if err != nil {
-		http.Error(w, "Error generating token: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "generating token", err)
 		return
 	}
 
-	// Return the user and token
-	response := map[string]interface{}{
-		"user":  user,
-		"token": token,
+	// Rotate the refresh token
+	session, err := h.sessionRepo.Rotate(
+		r.Context(), middleware.HashOpaqueToken(input.RefreshToken), refreshHash,
+		time.Now().Add(h.jwtCfg.RefreshTokenTTL()),
+	)
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenReused) {
+			log.Printf("Refresh token reuse detected; session revoked")
+		}
+		response.FromError(w, r, "refreshing session", err)
+		return
+	}
+
+	// Get the user
+	user, err := h.userRepo.GetByID(r.Context(), session.UserID)
+	if err != nil {
+		response.FromError(w, r, "getting user", err)
+		return
+	}
+	if user == nil {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeInvalidToken, "Invalid refresh token")
+		return
+	}
+
+	tokens, err := h.tokenResponse(user, session.FamilyID, refreshToken)
+	if err != nil {
+		response.FromError(w, r, "generating token", err)
+		return
+	}
+
+	// Return the user and tokens
+	response.JSON(w, http.StatusOK, tokens)
+}
+
+// Logout revokes the session of a refresh token, invalidating every access
+// and refresh token issued for it
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	// Parse the request body
+	var input models.RefreshTokenInput
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	// Logging out an unknown session is a no-op
+	err := h.sessionRepo.RevokeByToken(r.Context(), middleware.HashOpaqueToken(input.RefreshToken), repository.SessionRevokedLogout)
+	if err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		response.FromError(w, r, "logging out", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startSession creates a new session family for user and returns the login
+// response with its tokens
+func (h *UserHandler) startSession(r *http.Request, user *models.User) (map[string]interface{}, error) {
+	familyID, err := middleware.NewSessionFamilyID()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refreshHash, err := middleware.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = h.sessionRepo.Create(r.Context(), models.Session{
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(h.jwtCfg.RefreshTokenTTL()),
+		UserAgent: r.UserAgent(),
+		IPAddress: clientIP(r),
+	}, refreshHash)
+	if err != nil {
+		return nil, err
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	return h.tokenResponse(user, familyID, refreshToken)
+}
+
+// recordLogin adds a login attempt to the audit log. Failures are only
+// logged so that auditing never blocks a login.
+func (h *UserHandler) recordLogin(r *http.Request, email string, user *models.User, result string) {
+	attempt := models.LoginAttempt{
+		Email:     email,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+		Result:    result,
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+	if err := h.attemptRepo.Record(r.Context(), attempt); err != nil {
+		log.Printf("Error recording login attempt: %v", err)
+	}
+}
+
+// clientIP returns the IP address of the client without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// tokenResponse builds the response returned when tokens are issued
+func (h *UserHandler) tokenResponse(user *models.User, familyID, refreshToken string) (map[string]interface{}, error) {
+	token, err := middleware.GenerateToken(user.ID, user.Email, user.Role, familyID, h.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"user":          user,
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.jwtCfg.AccessTokenTTL().Seconds()),
+	}, nil
 }
 
 // GetMe gets the current user
@@ -117,24 +303,23 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	// Get the user ID from the context
 	userID, err := middleware.GetUserIDFromContext(r.Context())
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Get the user
 	user, err := h.userRepo.GetByID(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Error getting user: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting user", err)
 		return
 	}
 	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "User not found")
 		return
 	}
 
 	// Return the user
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	response.JSON(w, http.StatusOK, user)
 }
 
 // UpdateMe updates the current user
@@ -142,31 +327,29 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	// Get the user ID from the context
 	userID, err := middleware.GetUserIDFromContext(r.Context())
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Parse the request body
-	var input models.UserInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	var input models.UserUpdateInput
+	if !decodeAndValidate(w, r, &input) {
 		return
 	}
 
 	// Update the user
 	user, err := h.userRepo.Update(r.Context(), userID, input)
 	if err != nil {
-		http.Error(w, "Error updating user: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "updating user", err)
 		return
 	}
 	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "User not found")
 		return
 	}
 
 	// Return the updated user
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	response.JSON(w, http.StatusOK, user)
 }
 
 // GetUserByID gets a user by ID
@@ -175,22 +358,64 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid user ID")
 		return
 	}
 
 	// Get the user
 	user, err := h.userRepo.GetByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Error getting user: "+err.Error(), http.StatusInternalServerError)
+		response.FromError(w, r, "getting user", err)
 		return
 	}
 	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "User not found")
 		return
 	}
 
 	// Return the user
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	response.JSON(w, http.StatusOK, user)
+}
+
+// ListUsers lists all users for admins
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.userRepo.GetAll(r.Context())
+	if err != nil {
+		response.FromError(w, r, "getting users", err)
+		return
+	}
+
+	// Return the users
+	response.JSON(w, http.StatusOK, users)
+}
+
+// UpdateRole promotes or demotes a user
+func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	// Get the user ID from the URL
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter, "Invalid user ID")
+		return
+	}
+
+	// Parse the request body
+	var input models.UserRoleInput
+	if !decodeAndValidate(w, r, &input) {
+		return
+	}
+
+	// Update the role
+	user, err := h.userRepo.UpdateRole(r.Context(), id, input.Role)
+	if err != nil {
+		response.FromError(w, r, "updating role", err)
+		return
+	}
+	if user == nil {
+		response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "User not found")
+		return
+	}
+
+	// Return the updated user
+	response.JSON(w, http.StatusOK, user)
 }
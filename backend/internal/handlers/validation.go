@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/validation"
+)
+
+// decodeJSON decodes the JSON request body into input. It writes a 400
+// response and returns false if the body is malformed.
+func decodeJSON(w http.ResponseWriter, r *http.Request, input interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "Invalid request body")
+		return false
+	}
+	return true
+}
+
+// decodeAndValidate decodes the JSON request body into input and checks its
+// validate tags. It writes a 400 response for a malformed body or a 422
+// response listing the invalid fields, and returns false in either case.
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, input interface{}) bool {
+	if !decodeJSON(w, r, input) {
+		return false
+	}
+	if errs := validation.Struct(input); len(errs) > 0 {
+		response.ValidationFailed(w, r, errs)
+		return false
+	}
+	return true
+}
+
+// validateCurrency adds an error for field if code is not a supported
+// currency and returns the parsed currency
+func validateCurrency(errs *validation.Errors, field, code string) (models.Currency, bool) {
+	currency, err := models.ParseCurrency(code)
+	if err != nil {
+		*errs = append(*errs, validation.FieldError{
+			Field: field, Code: validation.CodeOneOf, Param: "USD USDC ETH",
+			Message: "must be one of: USD, USDC, ETH",
+		})
+		return "", false
+	}
+	return currency, true
+}
+
+// validateAmount adds an error for field if amount is not a valid positive
+// amount in currency. Fields that already failed their tags are skipped.
+func validateAmount(errs *validation.Errors, field string, amount models.Decimal, currency models.Currency) (models.Money, bool) {
+	if errs.Has(field) {
+		return models.Money{}, false
+	}
+	money, err := amount.Money(currency)
+	if err != nil {
+		errs.Add(field, validation.CodeInvalid, err.Error())
+		return models.Money{}, false
+	}
+	return money, true
+}
+
+// validateMaxDonation adds an error for field if amount is more than a single
+// donation may be
+func validateMaxDonation(errs *validation.Errors, field string, amount models.Money) {
+	limit := models.MaxDonation(amount.Currency)
+	if cmp, _ := amount.Cmp(limit); cmp > 0 {
+		*errs = append(*errs, validation.FieldError{
+			Field: field, Code: validation.CodeMax, Param: limit.String(),
+			Message: fmt.Sprintf("must be at most %s %s", limit, amount.Currency),
+		})
+	}
+}
+
+// validateHex adds an error for field if value is set but is not size bytes
+// of hex, with or without a 0x prefix, such as a transaction hash (32 bytes)
+// or an address (20 bytes)
+func validateHex(errs *validation.Errors, field, value string, size int) {
+	if value == "" || errs.Has(field) {
+		return
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil || len(b) != size {
+		errs.Add(field, validation.CodeInvalid, fmt.Sprintf("must be %d bytes of hex", size))
+	}
+}
@@ -0,0 +1,303 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/ethereum"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+// Events emitted by the CharityDonation contract that the indexer ingests
+const (
+	EventDonationMade  = "DonationMade"
+	EventUsdcWithdrawn = "UsdcWithdrawn"
+	EventEthWithdrawn  = "EthWithdrawn"
+)
+
+// Store persists indexed events and checkpoints. It is implemented by
+// repository.ChainRepository and can be replaced in tests.
+type Store interface {
+	GetCheckpoint(ctx context.Context, name string) (*models.IndexerCheckpoint, error)
+	ApplyBlockRange(ctx context.Context, checkpoint models.IndexerCheckpoint, donations []models.ChainDonation, withdrawals []models.ChainWithdrawal) error
+	Rewind(ctx context.Context, name string, fromBlock uint64, lastGood *models.IndexerCheckpoint) error
+}
+
+var _ Store = (*repository.ChainRepository)(nil)
+
+// Indexer polls the chain for CharityDonation events and stores them
+type Indexer struct {
+	client    *ethereum.Client
+	abi       *ethereum.ABI
+	chainRepo Store
+	cfg       *config.ChainConfig
+	contract  string
+	name      string
+	topics    []string
+}
+
+// New creates a new Indexer for the configured contract
+func New(client *ethereum.Client, abi *ethereum.ABI, chainRepo Store, cfg *config.ChainConfig) (*Indexer, error) {
+	contract, err := ethereum.NormalizeAddress(cfg.ContractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CONTRACT_ADDRESS: %w", err)
+	}
+
+	topics, err := abi.Topics(EventDonationMade, EventUsdcWithdrawn, EventEthWithdrawn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Indexer{
+		client:    client,
+		abi:       abi,
+		chainRepo: chainRepo,
+		cfg:       cfg,
+		contract:  contract,
+		name:      "charity_donation:" + contract,
+		topics:    topics,
+	}, nil
+}
+
+// Run polls until the context is cancelled. Errors are logged and retried on
+// the next tick so a flaky RPC endpoint does not stop the worker.
+func (ix *Indexer) Run(ctx context.Context) error {
+	interval := time.Duration(ix.cfg.PollIntervalSeconds) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := ix.Poll(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Indexer poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll processes every confirmed block since the last checkpoint
+func (ix *Indexer) Poll(ctx context.Context) error {
+	head, err := ix.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting block number: %w", err)
+	}
+	if head < ix.cfg.Confirmations {
+		return nil
+	}
+	safeHead := head - ix.cfg.Confirmations
+
+	checkpoint, err := ix.chainRepo.GetCheckpoint(ctx, ix.name)
+	if err != nil {
+		return fmt.Errorf("error getting checkpoint: %w", err)
+	}
+
+	from := ix.cfg.StartBlock
+	if checkpoint != nil {
+		reorged, err := ix.handleReorg(ctx, checkpoint)
+		if err != nil {
+			return err
+		}
+		if reorged {
+			// Re-read the chain from the rewound checkpoint on the next poll
+			return nil
+		}
+		from = checkpoint.BlockNumber + 1
+	}
+
+	for from <= safeHead {
+		to := from + ix.cfg.BatchSize - 1
+		if to > safeHead {
+			to = safeHead
+		}
+
+		if err := ix.processRange(ctx, from, to); err != nil {
+			return err
+		}
+		from = to + 1
+	}
+
+	return nil
+}
+
+// handleReorg checks that the checkpointed block is still canonical. If it is
+// not, everything from one confirmation window before it is discarded.
+func (ix *Indexer) handleReorg(ctx context.Context, checkpoint *models.IndexerCheckpoint) (bool, error) {
+	header, err := ix.client.HeaderByNumber(ctx, checkpoint.BlockNumber)
+	if err != nil && !errors.Is(err, ethereum.ErrNotFound) {
+		return false, fmt.Errorf("error getting block %d: %w", checkpoint.BlockNumber, err)
+	}
+	if header != nil && strings.EqualFold(header.Hash, checkpoint.BlockHash) {
+		return false, nil
+	}
+
+	depth := ix.cfg.Confirmations
+	if depth == 0 {
+		depth = 1
+	}
+
+	var fromBlock uint64
+	if checkpoint.BlockNumber+1 > depth {
+		fromBlock = checkpoint.BlockNumber + 1 - depth
+	}
+
+	var lastGood *models.IndexerCheckpoint
+	if fromBlock <= ix.cfg.StartBlock {
+		fromBlock = ix.cfg.StartBlock
+	} else {
+		header, err := ix.client.HeaderByNumber(ctx, fromBlock-1)
+		if err != nil {
+			return false, fmt.Errorf("error getting block %d: %w", fromBlock-1, err)
+		}
+		lastGood = &models.IndexerCheckpoint{Name: ix.name, BlockNumber: fromBlock - 1, BlockHash: header.Hash}
+	}
+
+	log.Printf("Reorg detected at block %d; rewinding to block %d", checkpoint.BlockNumber, fromBlock)
+	if err := ix.chainRepo.Rewind(ctx, ix.name, fromBlock, lastGood); err != nil {
+		return false, fmt.Errorf("error rewinding to block %d: %w", fromBlock, err)
+	}
+	return true, nil
+}
+
+// processRange fetches, decodes and stores the logs of blocks from..to
+func (ix *Indexer) processRange(ctx context.Context, from, to uint64) error {
+	logs, err := ix.client.GetLogs(ctx, ethereum.FilterQuery{
+		FromBlock: from,
+		ToBlock:   to,
+		Addresses: []string{ix.contract},
+		Topics:    [][]string{ix.topics},
+	})
+	if err != nil {
+		return fmt.Errorf("error getting logs for blocks %d-%d: %w", from, to, err)
+	}
+
+	var donations []models.ChainDonation
+	var withdrawals []models.ChainWithdrawal
+	for _, l := range logs {
+		if l.Removed {
+			continue
+		}
+
+		event, values, err := ix.abi.Decode(l)
+		if err != nil {
+			log.Printf("Skipping undecodable log %s#%d: %v", l.TransactionHash, l.LogIndex, err)
+			continue
+		}
+
+		switch event.Name {
+		case EventDonationMade:
+			donation, err := DonationFromLog(l, values)
+			if err != nil {
+				log.Printf("Skipping donation log %s#%d: %v", l.TransactionHash, l.LogIndex, err)
+				continue
+			}
+			donations = append(donations, donation)
+		case EventUsdcWithdrawn, EventEthWithdrawn:
+			currency := models.CurrencyUSDC
+			if event.Name == EventEthWithdrawn {
+				currency = models.CurrencyETH
+			}
+			withdrawal, err := withdrawalFromLog(l, values, currency)
+			if err != nil {
+				log.Printf("Skipping withdrawal log %s#%d: %v", l.TransactionHash, l.LogIndex, err)
+				continue
+			}
+			withdrawals = append(withdrawals, withdrawal)
+		}
+	}
+
+	header, err := ix.client.HeaderByNumber(ctx, to)
+	if err != nil {
+		return fmt.Errorf("error getting block %d: %w", to, err)
+	}
+
+	checkpoint := models.IndexerCheckpoint{Name: ix.name, BlockNumber: to, BlockHash: header.Hash}
+	if err := ix.chainRepo.ApplyBlockRange(ctx, checkpoint, donations, withdrawals); err != nil {
+		return err
+	}
+
+	if len(donations) > 0 || len(withdrawals) > 0 {
+		log.Printf("Indexed blocks %d-%d: %d donation(s), %d withdrawal(s)", from, to, len(donations), len(withdrawals))
+	}
+	return nil
+}
+
+// DonationFromLog converts decoded DonationMade values into a ChainDonation
+func DonationFromLog(l ethereum.Log, values map[string]interface{}) (models.ChainDonation, error) {
+	charityID, err := bigValue(values, "charityId")
+	if err != nil {
+		return models.ChainDonation{}, err
+	}
+	if !charityID.IsInt64() {
+		return models.ChainDonation{}, fmt.Errorf("charityId %s out of range", charityID)
+	}
+	donationID, err := bigValue(values, "donationId")
+	if err != nil {
+		return models.ChainDonation{}, err
+	}
+	amount, err := bigValue(values, "amount")
+	if err != nil {
+		return models.ChainDonation{}, err
+	}
+	donor, _ := values["donor"].(string)
+
+	currency := models.CurrencyUSDC
+	if isEth, _ := values["isEth"].(bool); isEth {
+		currency = models.CurrencyETH
+	}
+
+	return models.ChainDonation{
+		ChainCharityID:  charityID.Int64(),
+		ChainDonationID: donationID,
+		DonorAddress:    donor,
+		Amount:          models.NewMoney(amount, currency),
+		TransactionHash: strings.ToLower(l.TransactionHash),
+		BlockNumber:     uint64(l.BlockNumber),
+		LogIndex:        uint64(l.LogIndex),
+	}, nil
+}
+
+// withdrawalFromLog converts decoded withdrawal values into a ChainWithdrawal
+func withdrawalFromLog(l ethereum.Log, values map[string]interface{}, currency models.Currency) (models.ChainWithdrawal, error) {
+	charityID, err := bigValue(values, "charityId")
+	if err != nil {
+		return models.ChainWithdrawal{}, err
+	}
+	if !charityID.IsInt64() {
+		return models.ChainWithdrawal{}, fmt.Errorf("charityId %s out of range", charityID)
+	}
+	amount, err := bigValue(values, "amount")
+	if err != nil {
+		return models.ChainWithdrawal{}, err
+	}
+	wallet, _ := values["wallet"].(string)
+
+	return models.ChainWithdrawal{
+		ChainCharityID:  charityID.Int64(),
+		WalletAddress:   wallet,
+		Amount:          models.NewMoney(amount, currency),
+		Currency:        currency,
+		TransactionHash: strings.ToLower(l.TransactionHash),
+		BlockNumber:     uint64(l.BlockNumber),
+		LogIndex:        uint64(l.LogIndex),
+	}, nil
+}
+
+// bigValue extracts an integer argument from decoded event values
+func bigValue(values map[string]interface{}, name string) (*big.Int, error) {
+	v, ok := values[name].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	return v, nil
+}
@@ -0,0 +1,466 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/ethereum"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+const (
+	testContract = "0x00000000000000000000000000000000000000c0"
+	testDonor    = "0x00000000000000000000000000000000000000d0"
+	otherAddress = "0x00000000000000000000000000000000000000e0"
+)
+
+// testABI declares the events the indexer ingests, as in CharityDonation.json
+const testABI = `[
+	{"type": "event", "name": "DonationMade", "inputs": [
+		{"name": "donationId", "type": "uint256", "indexed": true},
+		{"name": "donor", "type": "address", "indexed": true},
+		{"name": "charityId", "type": "uint256", "indexed": true},
+		{"name": "amount", "type": "uint256", "indexed": false},
+		{"name": "isEth", "type": "bool", "indexed": false}
+	]},
+	{"type": "event", "name": "UsdcWithdrawn", "inputs": [
+		{"name": "charityId", "type": "uint256", "indexed": true},
+		{"name": "wallet", "type": "address", "indexed": true},
+		{"name": "amount", "type": "uint256", "indexed": false}
+	]},
+	{"type": "event", "name": "EthWithdrawn", "inputs": [
+		{"name": "charityId", "type": "uint256", "indexed": true},
+		{"name": "wallet", "type": "address", "indexed": true},
+		{"name": "amount", "type": "uint256", "indexed": false}
+	]}
+]`
+
+// rpcStub is a stand-in JSON-RPC node serving a scripted chain
+type rpcStub struct {
+	mu       sync.Mutex
+	head     uint64
+	hashes   map[uint64]string
+	logs     []ethereum.Log
+	receipts map[string]*ethereum.Receipt
+}
+
+func newRPCStub(t *testing.T, head uint64) (*rpcStub, *ethereum.Client) {
+	stub := &rpcStub{head: head, hashes: map[uint64]string{}, receipts: map[string]*ethereum.Receipt{}}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return stub, ethereum.NewClient(server.URL)
+}
+
+// blockHash returns the hash of block n, which changes when the block is
+// reorganised away
+func (s *rpcStub) blockHash(n uint64) string {
+	if hash, ok := s.hashes[n]; ok {
+		return hash
+	}
+	return fmt.Sprintf("0x%064x", n)
+}
+
+func (s *rpcStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     uint64            `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result interface{}
+	switch req.Method {
+	case "eth_blockNumber":
+		result = ethereum.Quantity(s.head)
+	case "eth_getBlockByNumber":
+		var number ethereum.Quantity
+		json.Unmarshal(req.Params[0], &number)
+		if uint64(number) <= s.head {
+			result = ethereum.Header{Number: number, Hash: s.blockHash(uint64(number))}
+		}
+	case "eth_getLogs":
+		var filter struct {
+			FromBlock ethereum.Quantity `json:"fromBlock"`
+			ToBlock   ethereum.Quantity `json:"toBlock"`
+			Address   []string          `json:"address"`
+		}
+		json.Unmarshal(req.Params[0], &filter)
+		logs := []ethereum.Log{}
+		for _, l := range s.logs {
+			if l.BlockNumber >= filter.FromBlock && l.BlockNumber <= filter.ToBlock && contains(filter.Address, l.Address) {
+				logs = append(logs, l)
+			}
+		}
+		result = logs
+	case "eth_getTransactionReceipt":
+		var hash string
+		json.Unmarshal(req.Params[0], &hash)
+		if receipt, ok := s.receipts[hash]; ok {
+			result = receipt
+		}
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0", "id": req.ID, "error": map[string]interface{}{"code": -32601, "message": "method not found"},
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeStore keeps what the indexer stores in memory, like ChainRepository
+type fakeStore struct {
+	checkpoint  *models.IndexerCheckpoint
+	donations   []models.ChainDonation
+	withdrawals []models.ChainWithdrawal
+	rewinds     []rewind
+}
+
+type rewind struct {
+	fromBlock uint64
+	lastGood  *models.IndexerCheckpoint
+}
+
+func (s *fakeStore) GetCheckpoint(ctx context.Context, name string) (*models.IndexerCheckpoint, error) {
+	return s.checkpoint, nil
+}
+
+func (s *fakeStore) ApplyBlockRange(ctx context.Context, checkpoint models.IndexerCheckpoint, donations []models.ChainDonation, withdrawals []models.ChainWithdrawal) error {
+	s.checkpoint = &checkpoint
+	s.donations = append(s.donations, donations...)
+	s.withdrawals = append(s.withdrawals, withdrawals...)
+	return nil
+}
+
+// Rewind discards everything from fromBlock on, as ChainRepository.Rewind does
+func (s *fakeStore) Rewind(ctx context.Context, name string, fromBlock uint64, lastGood *models.IndexerCheckpoint) error {
+	s.rewinds = append(s.rewinds, rewind{fromBlock, lastGood})
+	s.checkpoint = lastGood
+
+	var donations []models.ChainDonation
+	for _, d := range s.donations {
+		if d.BlockNumber < fromBlock {
+			donations = append(donations, d)
+		}
+	}
+	s.donations = donations
+
+	var withdrawals []models.ChainWithdrawal
+	for _, w := range s.withdrawals {
+		if w.BlockNumber < fromBlock {
+			withdrawals = append(withdrawals, w)
+		}
+	}
+	s.withdrawals = withdrawals
+	return nil
+}
+
+func loadTestABI(t *testing.T) *ethereum.ABI {
+	t.Helper()
+	abi, err := ethereum.ParseABI(strings.NewReader(testABI))
+	if err != nil {
+		t.Fatalf("ParseABI: %v", err)
+	}
+	return abi
+}
+
+// word returns v as a hex encoded 32-byte ABI word
+func word(v uint64) string {
+	return fmt.Sprintf("0x%064x", v)
+}
+
+// addressWord returns an address as an indexed topic
+func addressWord(address string) string {
+	return "0x" + strings.Repeat("0", 24) + strings.TrimPrefix(address, "0x")
+}
+
+func txHash(n uint64) string {
+	return fmt.Sprintf("0x%064x", 0xabc000+n)
+}
+
+// donationLog returns a DonationMade log from contract in block
+func donationLog(abi *ethereum.ABI, contract string, block, donationID, charityID, amount uint64, isEth bool) ethereum.Log {
+	eth := uint64(0)
+	if isEth {
+		eth = 1
+	}
+	return ethereum.Log{
+		Address:         contract,
+		Topics:          []string{abi.Events[EventDonationMade].Topic, word(donationID), addressWord(testDonor), word(charityID)},
+		Data:            word(amount) + strings.TrimPrefix(word(eth), "0x"),
+		BlockNumber:     ethereum.Quantity(block),
+		TransactionHash: txHash(block),
+	}
+}
+
+// withdrawalLog returns a withdrawal log of the given event in block
+func withdrawalLog(abi *ethereum.ABI, event string, block, charityID, amount uint64) ethereum.Log {
+	return ethereum.Log{
+		Address:         testContract,
+		Topics:          []string{abi.Events[event].Topic, word(charityID), addressWord(otherAddress)},
+		Data:            word(amount),
+		BlockNumber:     ethereum.Quantity(block),
+		TransactionHash: txHash(block),
+		LogIndex:        1,
+	}
+}
+
+func newTestIndexer(t *testing.T, client *ethereum.Client, store Store) *Indexer {
+	t.Helper()
+	ix, err := New(client, loadTestABI(t), store, &config.ChainConfig{
+		ContractAddress: testContract,
+		StartBlock:      1,
+		Confirmations:   2,
+		BatchSize:       3,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return ix
+}
+
+func TestPollDecodesLogsAndAdvancesCheckpoint(t *testing.T) {
+	abi := loadTestABI(t)
+	stub, client := newRPCStub(t, 10)
+	removed := donationLog(abi, testContract, 4, 2, 7, 5, false)
+	removed.Removed = true
+	stub.logs = []ethereum.Log{
+		donationLog(abi, testContract, 3, 1, 7, 1500000, false),
+		removed,
+		donationLog(abi, otherAddress, 4, 3, 7, 5, false),
+		withdrawalLog(abi, EventEthWithdrawn, 5, 7, 2000000000000000000),
+		donationLog(abi, testContract, 9, 4, 8, 1000000000000000000, true),
+	}
+
+	store := &fakeStore{}
+	ix := newTestIndexer(t, client, store)
+	if err := ix.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+
+	// Blocks 1-8 are confirmed; block 9 is not
+	if store.checkpoint == nil || store.checkpoint.BlockNumber != 8 || store.checkpoint.BlockHash != stub.blockHash(8) {
+		t.Fatalf("checkpoint = %+v, want block 8", store.checkpoint)
+	}
+	if len(store.donations) != 1 {
+		t.Fatalf("indexed %d donations, want 1", len(store.donations))
+	}
+	d := store.donations[0]
+	if d.ChainCharityID != 7 || d.ChainDonationID.Cmp(big.NewInt(1)) != 0 || d.DonorAddress != testDonor ||
+		d.BlockNumber != 3 || d.TransactionHash != txHash(3) {
+		t.Errorf("donation = %+v", d)
+	}
+	if d.Amount.Currency != models.CurrencyUSDC || d.Amount.String() != "1.500000" {
+		t.Errorf("donation amount = %s %s, want 1.500000 USDC", d.Amount, d.Amount.Currency)
+	}
+	if len(store.withdrawals) != 1 {
+		t.Fatalf("indexed %d withdrawals, want 1", len(store.withdrawals))
+	}
+	if w := store.withdrawals[0]; w.Currency != models.CurrencyETH || w.WalletAddress != otherAddress || w.Amount.String() != "2.000000000000000000" {
+		t.Errorf("withdrawal = %+v", w)
+	}
+
+	// Once block 9 is confirmed it is picked up from the checkpoint
+	stub.head = 11
+	if err := ix.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if store.checkpoint.BlockNumber != 9 {
+		t.Fatalf("checkpoint = %d, want 9", store.checkpoint.BlockNumber)
+	}
+	if len(store.donations) != 2 || store.donations[1].Amount.Currency != models.CurrencyETH {
+		t.Fatalf("donations = %+v, want the ETH donation of block 9 added", store.donations)
+	}
+	if len(store.rewinds) != 0 {
+		t.Errorf("rewound %d times without a reorg", len(store.rewinds))
+	}
+}
+
+func TestPollRewindsOnReorg(t *testing.T) {
+	abi := loadTestABI(t)
+	stub, client := newRPCStub(t, 10)
+	stub.logs = []ethereum.Log{
+		donationLog(abi, testContract, 3, 1, 7, 100, false),
+		donationLog(abi, testContract, 7, 2, 7, 200, false),
+	}
+
+	store := &fakeStore{}
+	ix := newTestIndexer(t, client, store)
+	if err := ix.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if len(store.donations) != 2 {
+		t.Fatalf("indexed %d donations, want 2", len(store.donations))
+	}
+
+	// Block 8 is replaced, and with it the donation in block 7
+	stub.hashes[8] = fmt.Sprintf("0x%064x", 0xf008)
+	stub.hashes[7] = fmt.Sprintf("0x%064x", 0xf007)
+	stub.logs = stub.logs[:1]
+	if err := ix.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+
+	// Everything from one confirmation window before block 8 is discarded
+	if len(store.rewinds) != 1 {
+		t.Fatalf("rewound %d times, want 1", len(store.rewinds))
+	}
+	rw := store.rewinds[0]
+	if rw.fromBlock != 7 {
+		t.Errorf("rewound from block %d, want 7", rw.fromBlock)
+	}
+	if rw.lastGood == nil || rw.lastGood.BlockNumber != 6 || rw.lastGood.BlockHash != stub.blockHash(6) {
+		t.Errorf("last good checkpoint = %+v, want block 6", rw.lastGood)
+	}
+	if len(store.donations) != 1 {
+		t.Fatalf("%d donations after rewind, want 1", len(store.donations))
+	}
+
+	// The next poll re-reads the new chain from the rewound checkpoint
+	if err := ix.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if store.checkpoint.BlockNumber != 8 || store.checkpoint.BlockHash != stub.hashes[8] {
+		t.Errorf("checkpoint = %+v, want the new block 8", store.checkpoint)
+	}
+	if len(store.donations) != 1 || len(store.rewinds) != 1 {
+		t.Errorf("donations = %d, rewinds = %d after re-reading, want 1 and 1", len(store.donations), len(store.rewinds))
+	}
+}
+
+func TestVerifyDonation(t *testing.T) {
+	abi := loadTestABI(t)
+	charityID := int64(7)
+	cause := &models.Cause{ChainCharityID: &charityID}
+	donation := &models.Donation{Amount: models.NewMoney(big.NewInt(2500000), models.CurrencyUSDC)}
+
+	tests := []struct {
+		name    string
+		donor   string
+		receipt *ethereum.Receipt
+		status  VerificationStatus
+		err     error
+	}{
+		{
+			name:    "confirmed",
+			receipt: &ethereum.Receipt{To: testContract, Status: 1, BlockNumber: 5, Logs: []ethereum.Log{donationLog(abi, testContract, 5, 1, 7, 2500000, false)}},
+			status:  VerificationConfirmed,
+		},
+		{
+			name:    "not deep enough",
+			receipt: &ethereum.Receipt{To: testContract, Status: 1, BlockNumber: 9, Logs: []ethereum.Log{donationLog(abi, testContract, 9, 1, 7, 2500000, false)}},
+			status:  VerificationPending,
+		},
+		{
+			name:   "unknown transaction",
+			status: VerificationPending,
+		},
+		{
+			name:    "reverted call to our contract",
+			receipt: &ethereum.Receipt{To: testContract, Status: 0, BlockNumber: 5},
+			status:  VerificationReverted,
+		},
+		{
+			name:    "reverted call from the donor's wallet",
+			donor:   testDonor,
+			receipt: &ethereum.Receipt{From: testDonor, To: testContract, Status: 0, BlockNumber: 5},
+			status:  VerificationReverted,
+		},
+		{
+			name:    "reverted call from another wallet",
+			donor:   testDonor,
+			receipt: &ethereum.Receipt{From: otherAddress, To: testContract, Status: 0, BlockNumber: 5},
+			err:     ErrVerificationMismatch,
+		},
+		{
+			name:    "donation from another wallet",
+			donor:   testDonor,
+			receipt: &ethereum.Receipt{From: otherAddress, To: testContract, Status: 1, BlockNumber: 5, Logs: []ethereum.Log{donationLog(abi, testContract, 5, 1, 7, 2500000, false)}},
+			err:     ErrVerificationMismatch,
+		},
+		{
+			name:    "reverted call to another contract",
+			receipt: &ethereum.Receipt{To: otherAddress, Status: 0, BlockNumber: 5},
+			err:     ErrVerificationMismatch,
+		},
+		{
+			name:    "successful call to another contract",
+			receipt: &ethereum.Receipt{To: otherAddress, Status: 1, BlockNumber: 5, Logs: []ethereum.Log{donationLog(abi, testContract, 5, 1, 7, 2500000, false)}},
+			err:     ErrVerificationMismatch,
+		},
+		{
+			name:    "log from another contract",
+			receipt: &ethereum.Receipt{To: testContract, Status: 1, BlockNumber: 5, Logs: []ethereum.Log{donationLog(abi, otherAddress, 5, 1, 7, 2500000, false)}},
+			err:     ErrVerificationMismatch,
+		},
+		{
+			name:    "wrong charity",
+			receipt: &ethereum.Receipt{To: testContract, Status: 1, BlockNumber: 5, Logs: []ethereum.Log{donationLog(abi, testContract, 5, 1, 8, 2500000, false)}},
+			err:     ErrVerificationMismatch,
+		},
+		{
+			name:    "wrong amount",
+			receipt: &ethereum.Receipt{To: testContract, Status: 1, BlockNumber: 5, Logs: []ethereum.Log{donationLog(abi, testContract, 5, 1, 7, 2500001, false)}},
+			err:     ErrVerificationMismatch,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub, client := newRPCStub(t, 10)
+			hash := txHash(uint64(i))
+			if tt.receipt != nil {
+				stub.receipts[hash] = tt.receipt
+			}
+			verifier, err := NewVerifier(client, abi, testContract, 2)
+			if err != nil {
+				t.Fatalf("NewVerifier: %v", err)
+			}
+
+			donation := *donation
+			donation.DonorAddress = tt.donor
+			verification, err := verifier.VerifyDonation(context.Background(), &donation, cause, hash)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyDonation: %v", err)
+			}
+			if verification.Status != tt.status {
+				t.Fatalf("status = %s, want %s", verification.Status, tt.status)
+			}
+			if tt.receipt != nil && verification.From != tt.receipt.From {
+				t.Errorf("from = %s, want %s", verification.From, tt.receipt.From)
+			}
+			if tt.status == VerificationConfirmed && (verification.Event == nil || verification.Event.ChainCharityID != 7) {
+				t.Errorf("event = %+v, want the DonationMade event", verification.Event)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ombima56/transpacharity/internal/ethereum"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// ErrVerificationMismatch is returned when a mined transaction does not
+// contain a DonationMade log matching the donation being confirmed
+var ErrVerificationMismatch = errors.New("transaction does not match donation")
+
+// VerificationStatus is the outcome of checking a donation transaction
+type VerificationStatus string
+
+const (
+	// VerificationConfirmed means a matching DonationMade log is buried under enough blocks
+	VerificationConfirmed VerificationStatus = "confirmed"
+	// VerificationReverted means the transaction was mined but failed
+	VerificationReverted VerificationStatus = "reverted"
+	// VerificationPending means the transaction is unknown, unmined or not yet deep enough
+	VerificationPending VerificationStatus = "pending"
+)
+
+// Verification is the result of verifying a donation transaction. From is
+// the address that sent it.
+type Verification struct {
+	Status VerificationStatus
+	Event  *models.ChainDonation
+	From   string
+}
+
+// Verifier checks donation transactions against their on-chain receipts
+type Verifier struct {
+	client        *ethereum.Client
+	abi           *ethereum.ABI
+	contract      string
+	confirmations uint64
+}
+
+// NewVerifier creates a Verifier for the given contract
+func NewVerifier(client *ethereum.Client, abi *ethereum.ABI, contractAddress string, confirmations uint64) (*Verifier, error) {
+	contract, err := ethereum.NormalizeAddress(contractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CONTRACT_ADDRESS: %w", err)
+	}
+	if _, ok := abi.Events[EventDonationMade]; !ok {
+		return nil, fmt.Errorf("event %s not found in ABI", EventDonationMade)
+	}
+
+	return &Verifier{
+		client:        client,
+		abi:           abi,
+		contract:      contract,
+		confirmations: confirmations,
+	}, nil
+}
+
+// VerifyDonation fetches the receipt of txHash and checks that it contains a
+// DonationMade log emitted by our contract for the donation's cause and amount.
+// Transactions sent to any other address, or from another address than the
+// donor's wallet when it is known, are a mismatch, even if they reverted.
+func (v *Verifier) VerifyDonation(ctx context.Context, donation *models.Donation, cause *models.Cause, txHash string) (*Verification, error) {
+	if cause.ChainCharityID == nil {
+		return nil, fmt.Errorf("%w: cause is not linked to an on-chain charity", ErrVerificationMismatch)
+	}
+
+	receipt, err := v.client.TransactionReceipt(ctx, txHash)
+	if errors.Is(err, ethereum.ErrNotFound) {
+		return &Verification{Status: VerificationPending}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Only a reverted call to our contract fails the donation; any other
+	// transaction cannot be the one that made it
+	if !strings.EqualFold(receipt.To, v.contract) {
+		return nil, fmt.Errorf("%w: transaction was sent to %s, not contract %s", ErrVerificationMismatch, receipt.To, v.contract)
+	}
+	if donation.DonorAddress != "" && !strings.EqualFold(receipt.From, donation.DonorAddress) {
+		return nil, fmt.Errorf("%w: transaction was sent from %s, not the donor's wallet %s", ErrVerificationMismatch, receipt.From, donation.DonorAddress)
+	}
+	if !receipt.Succeeded() {
+		return &Verification{Status: VerificationReverted, From: receipt.From}, nil
+	}
+
+	head, err := v.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(receipt.BlockNumber)+v.confirmations > head {
+		return &Verification{Status: VerificationPending}, nil
+	}
+
+	topic := v.abi.Events[EventDonationMade].Topic
+	var reasons []string
+	for _, l := range receipt.Logs {
+		if !strings.EqualFold(l.Address, v.contract) || len(l.Topics) == 0 || !strings.EqualFold(l.Topics[0], topic) {
+			continue
+		}
+
+		_, values, err := v.abi.Decode(l)
+		if err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		event, err := DonationFromLog(l, values)
+		if err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+
+		if event.ChainCharityID != *cause.ChainCharityID {
+			reasons = append(reasons, fmt.Sprintf("donation is for charity %d, expected %d", event.ChainCharityID, *cause.ChainCharityID))
+			continue
+		}
+		if !donation.Amount.Equal(event.Amount) {
+			reasons = append(reasons, fmt.Sprintf("donated %s %s, expected %s %s",
+				event.Amount, event.Amount.Currency, donation.Amount, donation.Amount.Currency))
+			continue
+		}
+
+		return &Verification{Status: VerificationConfirmed, Event: &event, From: receipt.From}, nil
+	}
+
+	if len(reasons) == 0 {
+		return nil, fmt.Errorf("%w: no DonationMade log from contract %s", ErrVerificationMismatch, v.contract)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrVerificationMismatch, strings.Join(reasons, "; "))
+}
@@ -0,0 +1,34 @@
+// Package mailer sends transactional emails such as password resets
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ombima56/transpacharity/internal/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New creates the mailer selected by cfg.Driver
+func New(cfg *config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg), nil
+	case "file":
+		return NewFileMailer(cfg.FileDir, cfg.From)
+	case "log", "":
+		return NewLogMailer(cfg.From), nil
+	}
+	return nil, fmt.Errorf("unknown MAIL_DRIVER %q: use smtp, file or log", cfg.Driver)
+}
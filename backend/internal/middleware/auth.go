@@ -5,17 +5,21 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
 )
 
 // UserClaims represents the JWT claims
 type UserClaims struct {
 	UserID int    `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role"`
+	// SessionID is the family ID of the session the token was issued for
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -25,45 +29,50 @@ type contextKey string
 // UserIDKey is the key for user ID in the request context
 const UserIDKey contextKey = "userID"
 
-// GenerateToken generates a JWT token for a user
-func GenerateToken(userID int, email string, cfg *config.JWTConfig) (string, error) {
+// UserRoleKey is the key for the user's role in the request context
+const UserRoleKey contextKey = "userRole"
+
+// SessionIDKey is the key for the session family ID in the request context
+const SessionIDKey contextKey = "sessionID"
+
+// GenerateToken generates a short-lived JWT access token for a user's session
+func GenerateToken(userID int, email, role, sessionID string, keys *KeySet) (string, error) {
+	now := time.Now()
+
 	// Create the claims
 	claims := UserClaims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.ExpirationHours) * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    keys.cfg.Issuer,
+			Subject:   strconv.Itoa(userID),
+			ExpiresAt: jwt.NewNumericDate(now.Add(keys.cfg.AccessTokenTTL())),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	// Create the token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
 	// Sign the token
-	tokenString, err := token.SignedString([]byte(cfg.Secret))
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+	return keys.sign(claims)
 }
 
-// AuthMiddleware authenticates requests using JWT
-func AuthMiddleware(cfg *config.JWTConfig) func(http.Handler) http.Handler {
+// AuthMiddleware authenticates requests using JWT, rejecting tokens whose
+// session has been revoked
+func AuthMiddleware(keys *KeySet, sessions SessionChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get the Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Authorization header required")
 				return
 			}
 
 			// Check if the Authorization header has the correct format
 			if !strings.HasPrefix(authHeader, "Bearer ") {
-				http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+				response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Invalid Authorization header format")
 				return
 			}
 
@@ -71,33 +80,69 @@ func AuthMiddleware(cfg *config.JWTConfig) func(http.Handler) http.Handler {
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 			// Parse the token
-			token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
-				// Validate the signing method
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(cfg.Secret), nil
-			})
-
+			claims, err := parseToken(tokenString, keys)
 			if err != nil {
-				http.Error(w, "Invalid token: "+err.Error(), http.StatusUnauthorized)
+				response.Error(w, r, http.StatusUnauthorized, response.CodeInvalidToken, "Invalid or expired token")
 				return
 			}
 
-			// Get the claims
-			claims, ok := token.Claims.(*UserClaims)
-			if !ok || !token.Valid {
-				http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+			// Check that the session has not been logged out or revoked
+			active, err := sessionActive(r.Context(), sessions, claims)
+			if err != nil {
+				response.Internal(w, r, "checking session "+claims.SessionID, err)
+				return
+			}
+			if !active {
+				response.Error(w, r, http.StatusUnauthorized, response.CodeSessionRevoked, "Session has been revoked")
 				return
 			}
 
-			// Add the user ID to the request context
-			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// OptionalAuthMiddleware lets requests without an Authorization header
+// through anonymously and authenticates the rest like AuthMiddleware. A token
+// that is invalid, expired or revoked is a 401 rather than being ignored, so
+// that clients refresh it instead of acting as a guest by mistake.
+func OptionalAuthMiddleware(keys *KeySet, sessions SessionChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := AuthMiddleware(keys, sessions)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			authenticated.ServeHTTP(w, r)
 		})
 	}
 }
 
+// withClaims adds the user ID, role and session ID to the request context
+func withClaims(ctx context.Context, claims *UserClaims) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, UserRoleKey, claims.Role)
+	return context.WithValue(ctx, SessionIDKey, claims.SessionID)
+}
+
+// parseToken validates a JWT and returns its claims
+func parseToken(tokenString string, keys *KeySet) (*UserClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, keys.keyFunc, jwt.WithIssuer(keys.cfg.Issuer))
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid token: %w", err)
+	}
+
+	// Get the claims
+	claims, ok := token.Claims.(*UserClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("Invalid token claims")
+	}
+
+	return claims, nil
+}
+
 // GetUserIDFromContext gets the user ID from the request context
 func GetUserIDFromContext(ctx context.Context) (int, error) {
 	userID, ok := ctx.Value(UserIDKey).(int)
@@ -106,3 +151,21 @@ func GetUserIDFromContext(ctx context.Context) (int, error) {
 	}
 	return userID, nil
 }
+
+// GetUserRoleFromContext gets the role claimed by the user's token
+func GetUserRoleFromContext(ctx context.Context) (string, error) {
+	role, ok := ctx.Value(UserRoleKey).(string)
+	if !ok {
+		return "", errors.New("user role not found in context")
+	}
+	return role, nil
+}
+
+// GetSessionIDFromContext gets the session family ID of the request's token
+func GetSessionIDFromContext(ctx context.Context) (string, error) {
+	sessionID, ok := ctx.Value(SessionIDKey).(string)
+	if !ok || sessionID == "" {
+		return "", errors.New("session ID not found in context")
+	}
+	return sessionID, nil
+}
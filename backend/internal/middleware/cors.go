@@ -14,9 +14,10 @@ func CorsMiddleware(cfg *config.ServerConfig) func(http.Handler) http.Handler {
 
 			if cfg.Environment == "development" {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
+				w.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed, Link, Retry-After, X-Request-ID, X-Total-Count")
 			} else {
 				allowed := false
 				for _, allowedOrigin := range cfg.AllowedOrigins {
@@ -28,9 +29,10 @@ func CorsMiddleware(cfg *config.ServerConfig) func(http.Handler) http.Handler {
 
 				if allowed {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
-					w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-					w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+					w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+					w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
 					w.Header().Set("Access-Control-Allow-Credentials", "true")
+					w.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed, Link, Retry-After, X-Request-ID, X-Total-Count")
 				}
 			}
 
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// IdempotencyKeyHeader is the request header clients send to make a mutating
+// request safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed from an earlier
+// request with the same key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength is the longest Idempotency-Key accepted
+const maxIdempotencyKeyLength = 255
+
+// idempotencyLockTimeout is how long a request holds its key. It outlasts the
+// API's request timeout, so a key is only taken over from a request that died
+// without releasing it.
+const idempotencyLockTimeout = time.Minute
+
+// guestIdempotencyTTL is how long responses to guests' requests are kept.
+// Guest keys only guard against double submits, so they need not last long.
+const guestIdempotencyTTL = 10 * time.Minute
+
+// IdempotencyStore keeps the responses to requests sent with an
+// Idempotency-Key
+type IdempotencyStore interface {
+	Claim(ctx context.Context, scope, key, fingerprint string, ttl, lock time.Duration) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, scope, key string, record models.IdempotencyRecord) error
+	Release(ctx context.Context, scope, key, fingerprint string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// Idempotency makes POST, PUT, PATCH and DELETE requests sent with an
+// Idempotency-Key header safe to retry. The first request with a key is
+// handled and its response kept for ttl; retries with the same key and body
+// get that response back instead of being handled again. Reusing a key for a
+// different request, or while the first is still running, is a 409.
+//
+// Keys are scoped to the authenticated user, so it must run after
+// AuthMiddleware or OptionalAuthMiddleware. Guests' keys are scoped to the
+// request body instead and kept for at most guestIdempotencyTTL, so a key only
+// replays a response to a guest who sent the same key and the same body.
+// Server errors are not kept, so a request that failed can be retried with the
+// same key.
+func Idempotency(store IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				response.Error(w, r, http.StatusBadRequest, response.CodeInvalidParameter,
+					fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+				return
+			}
+
+			// Read the body to fingerprint the request, then put it back for
+			// the handler
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "Invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint := requestFingerprint(r, body)
+			scope, keep := "guest:"+fingerprint, min(ttl, guestIdempotencyTTL)
+			if userID, err := GetUserIDFromContext(r.Context()); err == nil {
+				scope, keep = "user:"+strconv.Itoa(userID), ttl
+			}
+
+			record, err := store.Claim(r.Context(), scope, key, fingerprint, keep, idempotencyLockTimeout)
+			if err != nil {
+				response.Internal(w, r, "claiming idempotency key", err)
+				return
+			}
+			if record != nil {
+				replay(w, r, record, fingerprint)
+				return
+			}
+
+			// Keep the response when the handler finishes, or release the key
+			// if it fails or panics. The request context may already be
+			// cancelled by then.
+			ctx := context.WithoutCancel(r.Context())
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			var buf bytes.Buffer
+			ww.Tee(&buf)
+
+			handled := false
+			defer func() {
+				if handled {
+					return
+				}
+				if err := store.Release(ctx, scope, key, fingerprint); err != nil {
+					log.Printf("Error releasing idempotency key: %v", err)
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				return
+			}
+
+			handled = true
+			err = store.Complete(ctx, scope, key, models.IdempotencyRecord{
+				Fingerprint: fingerprint,
+				StatusCode:  status,
+				ContentType: ww.Header().Get("Content-Type"),
+				Body:        buf.Bytes(),
+			})
+			if err != nil {
+				log.Printf("Error storing idempotent response: %v", err)
+			}
+		})
+	}
+}
+
+// replay answers a request whose key has already been used
+func replay(w http.ResponseWriter, r *http.Request, record *models.IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		response.Error(w, r, http.StatusConflict, response.CodeIdempotencyKeyReused,
+			"Idempotency-Key has already been used for a different request")
+		return
+	}
+	if record.InProgress() {
+		w.Header().Set("Retry-After", "1")
+		response.Error(w, r, http.StatusConflict, response.CodeIdempotencyInProgress,
+			"A request with this Idempotency-Key is still being processed")
+		return
+	}
+
+	if record.ContentType != "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+// PurgeIdempotencyKeys deletes expired idempotency keys every interval until
+// ctx is cancelled
+func PurgeIdempotencyKeys(ctx context.Context, store IdempotencyStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := store.DeleteExpired(ctx); err != nil {
+				log.Printf("Error deleting expired idempotency keys: %v", err)
+			}
+		}
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.Path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isMutating reports whether method may change server state
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ombima56/transpacharity/internal/config"
+)
+
+// verificationKey is a public key accepted for tokens with a given kid
+type verificationKey struct {
+	id     string
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+}
+
+// KeySet holds the key tokens are signed with and every key they may be
+// verified with. With only a shared secret configured it falls back to HS256.
+type KeySet struct {
+	cfg           *config.JWTConfig
+	signingMethod jwt.SigningMethod
+	signingKey    interface{}
+	signingKeyID  string
+	verification  map[string]verificationKey
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet loads the signing and verification keys named in cfg
+func LoadKeySet(cfg *config.JWTConfig) (*KeySet, error) {
+	ks := &KeySet{cfg: cfg, verification: make(map[string]verificationKey)}
+
+	if cfg.SigningKeyFile == "" {
+		ks.signingMethod = jwt.SigningMethodHS256
+		ks.signingKey = []byte(cfg.Secret)
+		return ks, nil
+	}
+
+	private, err := readPrivateKey(cfg.SigningKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading JWT_SIGNING_KEY_FILE: %w", err)
+	}
+	public := private.(crypto.Signer).Public()
+
+	method, err := methodForKey(public)
+	if err != nil {
+		return nil, err
+	}
+	kid := cfg.SigningKeyID
+	if kid == "" {
+		if kid, err = keyID(public); err != nil {
+			return nil, err
+		}
+	}
+
+	ks.signingMethod = method
+	ks.signingKey = private
+	ks.signingKeyID = kid
+	ks.verification[kid] = verificationKey{id: kid, method: method, key: public}
+
+	for _, entry := range cfg.VerificationKeyFiles {
+		kid, path, hasID := strings.Cut(entry, "=")
+		if !hasID {
+			kid, path = "", entry
+		}
+
+		public, err := readPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("error loading verification key %s: %w", path, err)
+		}
+		method, err := methodForKey(public)
+		if err != nil {
+			return nil, err
+		}
+		if kid == "" {
+			if kid, err = keyID(public); err != nil {
+				return nil, err
+			}
+		}
+		if _, exists := ks.verification[kid]; exists {
+			return nil, fmt.Errorf("duplicate JWT key ID %q", kid)
+		}
+		ks.verification[kid] = verificationKey{id: kid, method: method, key: public}
+	}
+
+	return ks, nil
+}
+
+// Config returns the JWT configuration the key set was loaded from
+func (ks *KeySet) Config() *config.JWTConfig {
+	return ks.cfg
+}
+
+// sign signs claims with the current signing key
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signingMethod, claims)
+	if ks.signingKeyID != "" {
+		token.Header["kid"] = ks.signingKeyID
+	}
+	return token.SignedString(ks.signingKey)
+}
+
+// keyFunc picks the verification key for a token from its kid header and
+// checks that the token's algorithm matches that key
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if ks.signingKeyID == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return ks.signingKey, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.key, nil
+}
+
+// JWKS returns the public verification keys. It is empty when tokens are
+// signed with a shared secret.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.verification {
+		jwk := JWK{KeyID: key.id, Use: "sig", Algorithm: key.method.Alg()}
+		switch pub := key.key.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	// Keep the output stable so clients and caches see identical documents
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// methodForKey returns the signing method used with a public key
+func methodForKey(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T: use RSA or Ed25519", key)
+}
+
+// keyID derives a stable key ID from the SHA-256 hash of the public key
+func keyID(key crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:16], nil
+}
+
+// readPEM reads the first PEM block of a file
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	return block, nil
+}
+
+// readPrivateKey reads a PKCS#8 or PKCS#1 private key
+func readPrivateKey(path string) (crypto.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *rsa.PrivateKey, ed25519.PrivateKey:
+			return key, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T: use RSA or Ed25519", key)
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// readPublicKey reads a PKIX public key, or the public half of a private key
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	private, err := readPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return private.(crypto.Signer).Public(), nil
+}
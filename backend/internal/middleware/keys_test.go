@@ -0,0 +1,331 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ombima56/transpacharity/internal/config"
+)
+
+// The test keys are generated once, since RSA key generation is slow
+var (
+	testRSAKey     = mustRSAKey()
+	testOldRSAKey  = mustRSAKey()
+	_, testEd25519 = mustEd25519Key()
+)
+
+func mustRSAKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func mustEd25519Key() (ed25519.PublicKey, ed25519.PrivateKey) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return public, private
+}
+
+// writePEM writes der as a PEM block of the given type and returns its path
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writePrivateKey writes key in PKCS#8 format and returns its path
+func writePrivateKey(t *testing.T, key crypto.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, "PRIVATE KEY", der)
+}
+
+// writePublicKey writes the public half of key in PKIX format and returns its
+// path
+func writePublicKey(t *testing.T, key crypto.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.(crypto.Signer).Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, "PUBLIC KEY", der)
+}
+
+func testJWTConfig() *config.JWTConfig {
+	return &config.JWTConfig{Secret: "test-secret", AccessTokenMinutes: 15, Issuer: "transpacharity-test"}
+}
+
+func loadKeySet(t *testing.T, cfg *config.JWTConfig) *KeySet {
+	t.Helper()
+	ks, err := LoadKeySet(cfg)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	return ks
+}
+
+func mustToken(t *testing.T, ks *KeySet) string {
+	t.Helper()
+	token, err := GenerateToken(42, "jane@example.com", "user", "family", ks)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
+
+// forgeToken signs test claims with method and key under the given kid
+func forgeToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, UserClaims{
+		UserID: 42,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "transpacharity-test",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+	return s
+}
+
+// header decodes the header of a token
+func header(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &UserClaims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	return parsed.Header
+}
+
+func TestKeySetSignsAndVerifies(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		path func(t *testing.T) string
+		alg  string
+		kid  string
+	}{
+		{"RSA PKCS#1", func(t *testing.T) string {
+			return writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(testRSAKey))
+		}, "RS256", ""},
+		{"RSA PKCS#8", func(t *testing.T) string { return writePrivateKey(t, testRSAKey) }, "RS256", ""},
+		{"Ed25519", func(t *testing.T) string { return writePrivateKey(t, testEd25519) }, "EdDSA", ""},
+		{"configured kid", func(t *testing.T) string { return writePrivateKey(t, testEd25519) }, "EdDSA", "2026-10"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testJWTConfig()
+			cfg.SigningKeyFile = tt.path(t)
+			cfg.SigningKeyID = tt.kid
+			ks := loadKeySet(t, cfg)
+
+			token := mustToken(t, ks)
+			h := header(t, token)
+			if h["alg"] != tt.alg {
+				t.Errorf("alg = %v, want %s", h["alg"], tt.alg)
+			}
+			wantKID := tt.kid
+			if wantKID == "" {
+				wantKID, _ = keyID(ks.signingKey.(crypto.Signer).Public())
+			}
+			if h["kid"] != wantKID || len(wantKID) == 0 {
+				t.Errorf("kid = %v, want %q", h["kid"], wantKID)
+			}
+
+			claims, err := parseToken(token, ks)
+			if err != nil {
+				t.Fatalf("parseToken: %v", err)
+			}
+			if claims.UserID != 42 || claims.SessionID != "family" {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	// Tokens issued before the rotation were signed with the old key
+	oldCfg := testJWTConfig()
+	oldCfg.SigningKeyFile = writePrivateKey(t, testOldRSAKey)
+	oldCfg.SigningKeyID = "old"
+	oldToken := mustToken(t, loadKeySet(t, oldCfg))
+
+	cfg := testJWTConfig()
+	cfg.SigningKeyFile = writePrivateKey(t, testEd25519)
+	cfg.SigningKeyID = "new"
+	cfg.VerificationKeyFiles = []string{"old=" + writePublicKey(t, testOldRSAKey)}
+	ks := loadKeySet(t, cfg)
+
+	if _, err := parseToken(oldToken, ks); err != nil {
+		t.Errorf("token signed with the rotated-out key: %v", err)
+	}
+	if _, err := parseToken(mustToken(t, ks), ks); err != nil {
+		t.Errorf("token signed with the new key: %v", err)
+	}
+
+	// Once the old key is retired, its tokens are rejected
+	cfg.VerificationKeyFiles = nil
+	if _, err := parseToken(oldToken, loadKeySet(t, cfg)); err == nil || !strings.Contains(err.Error(), `unknown signing key "old"`) {
+		t.Errorf("token signed with a retired key: %v", err)
+	}
+}
+
+func TestKeySetRejects(t *testing.T) {
+	cfg := testJWTConfig()
+	cfg.SigningKeyFile = writePrivateKey(t, testRSAKey)
+	cfg.SigningKeyID = "rsa"
+	cfg.VerificationKeyFiles = []string{"ed=" + writePublicKey(t, testEd25519)}
+	ks := loadKeySet(t, cfg)
+	publicPEM, _ := os.ReadFile(writePublicKey(t, testRSAKey))
+
+	for _, tt := range []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{"unknown kid", forgeToken(t, jwt.SigningMethodRS256, testRSAKey, "nope"), "unknown signing key"},
+		{"missing kid", forgeToken(t, jwt.SigningMethodRS256, testRSAKey, ""), "unknown signing key"},
+		{"EdDSA under an RSA kid", forgeToken(t, jwt.SigningMethodEdDSA, testEd25519, "rsa"), "unexpected signing method"},
+		{"RS256 under an Ed25519 kid", forgeToken(t, jwt.SigningMethodRS256, testRSAKey, "ed"), "unexpected signing method"},
+		{"HS256 keyed with the public key", forgeToken(t, jwt.SigningMethodHS256, publicPEM, "rsa"), "unexpected signing method"},
+		{"signed by another key", forgeToken(t, jwt.SigningMethodRS256, testOldRSAKey, "rsa"), "signature is invalid"},
+	} {
+		if _, err := parseToken(tt.token, ks); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestKeySetSharedSecret(t *testing.T) {
+	ks := loadKeySet(t, testJWTConfig())
+
+	token := mustToken(t, ks)
+	if h := header(t, token); h["alg"] != "HS256" || h["kid"] != nil {
+		t.Errorf("header = %v, want HS256 without a kid", h)
+	}
+	if _, err := parseToken(token, ks); err != nil {
+		t.Errorf("parseToken: %v", err)
+	}
+	if _, err := parseToken(forgeToken(t, jwt.SigningMethodHS256, []byte("other-secret"), ""), ks); err == nil {
+		t.Error("accepted a token signed with another secret")
+	}
+	if _, err := parseToken(forgeToken(t, jwt.SigningMethodRS256, testRSAKey, ""), ks); err == nil || !strings.Contains(err.Error(), "unexpected signing method") {
+		t.Errorf("RS256 token with a shared secret: %v", err)
+	}
+	if jwks := ks.JWKS(); len(jwks.Keys) != 0 {
+		t.Errorf("JWKS = %+v, want no keys", jwks)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	cfg := testJWTConfig()
+	cfg.SigningKeyFile = writePrivateKey(t, testRSAKey)
+	cfg.SigningKeyID = "b-rsa"
+	cfg.VerificationKeyFiles = []string{"a-ed=" + writePublicKey(t, testEd25519)}
+	jwks := loadKeySet(t, cfg).JWKS()
+
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != "a-ed" || jwks.Keys[1].KeyID != "b-rsa" {
+		t.Fatalf("JWKS = %+v, want a-ed then b-rsa", jwks)
+	}
+
+	ed := jwks.Keys[0]
+	x, _ := base64.RawURLEncoding.DecodeString(ed.X)
+	if ed.KeyType != "OKP" || ed.Curve != "Ed25519" || ed.Algorithm != "EdDSA" || ed.Use != "sig" ||
+		!bytes.Equal(x, testEd25519.Public().(ed25519.PublicKey)) || ed.N != "" {
+		t.Errorf("Ed25519 key = %+v", ed)
+	}
+
+	rsaKey := jwks.Keys[1]
+	n, _ := base64.RawURLEncoding.DecodeString(rsaKey.N)
+	e, _ := base64.RawURLEncoding.DecodeString(rsaKey.E)
+	if rsaKey.KeyType != "RSA" || rsaKey.Algorithm != "RS256" || rsaKey.Use != "sig" || rsaKey.X != "" ||
+		new(big.Int).SetBytes(n).Cmp(testRSAKey.N) != 0 || new(big.Int).SetBytes(e).Int64() != int64(testRSAKey.E) {
+		t.Errorf("RSA key = %+v", rsaKey)
+	}
+	if rsaKey.E != "AQAB" {
+		t.Errorf("e = %q, want AQAB", rsaKey.E)
+	}
+}
+
+func TestReadPublicKey(t *testing.T) {
+	for name, path := range map[string]string{
+		"PKIX":           writePublicKey(t, testRSAKey),
+		"PKCS#1":         writePEM(t, "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&testRSAKey.PublicKey)),
+		"RSA private":    writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(testRSAKey)),
+		"PKCS#8 private": writePrivateKey(t, testRSAKey),
+	} {
+		key, err := readPublicKey(path)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if !testRSAKey.PublicKey.Equal(key) {
+			t.Errorf("%s: read a different key", name)
+		}
+	}
+
+	key, err := readPublicKey(writePublicKey(t, testEd25519))
+	if err != nil || !testEd25519.Public().(ed25519.PublicKey).Equal(key) {
+		t.Errorf("Ed25519: %v", err)
+	}
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notPEM := filepath.Join(t.TempDir(), "key.txt")
+	os.WriteFile(notPEM, []byte("not a key"), 0o600)
+
+	for _, tt := range []struct {
+		name         string
+		signing      string
+		kid          string
+		verification []string
+		err          string
+	}{
+		{"missing file", filepath.Join(t.TempDir(), "missing.pem"), "", nil, "JWT_SIGNING_KEY_FILE"},
+		{"not PEM", notPEM, "", nil, "no PEM data found"},
+		{"ECDSA signing key", writePrivateKey(t, ecKey), "", nil, "unsupported private key type"},
+		{"certificate", writePEM(t, "CERTIFICATE", []byte{1}), "", nil, "unsupported PEM block"},
+		{"ECDSA verification key", writePrivateKey(t, testRSAKey), "", []string{writePublicKey(t, ecKey)}, "unsupported key type"},
+		{"duplicate kid", writePrivateKey(t, testRSAKey), "same", []string{"same=" + writePublicKey(t, testEd25519)}, `duplicate JWT key ID "same"`},
+		{"same key twice", writePrivateKey(t, testRSAKey), "", []string{writePublicKey(t, testRSAKey)}, "duplicate JWT key ID"},
+	} {
+		cfg := testJWTConfig()
+		cfg.SigningKeyFile = tt.signing
+		cfg.SigningKeyID = tt.kid
+		cfg.VerificationKeyFiles = tt.verification
+		if _, err := LoadKeySet(cfg); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
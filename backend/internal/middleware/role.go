@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ombima56/transpacharity/internal/handlers/response"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// RoleLookup returns a user's current role, or "" if the user does not exist
+type RoleLookup interface {
+	GetRole(ctx context.Context, userID int) (string, error)
+}
+
+// RequireRole only lets through users holding one of the given roles. It must
+// run after AuthMiddleware. The role claimed by the token is re-checked against
+// the database so that a demoted user loses access before their token expires.
+func RequireRole(lookup RoleLookup, roles ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, err := GetUserIDFromContext(r.Context())
+			if err != nil {
+				response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
+				return
+			}
+
+			// Reject early when the token does not even claim the role
+			claimed, err := GetUserRoleFromContext(r.Context())
+			if err != nil || !allowed[claimed] {
+				response.Error(w, r, http.StatusForbidden, response.CodeForbidden, "Forbidden: insufficient role")
+				return
+			}
+
+			current, err := lookup.GetRole(r.Context(), userID)
+			if err != nil {
+				response.Internal(w, r, fmt.Sprintf("looking up role of user %d", userID), err)
+				return
+			}
+			if !allowed[current] {
+				response.Error(w, r, http.StatusForbidden, response.CodeForbidden, "Forbidden: insufficient role")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthorizeOwnerOrAdmin checks that the current user is ownerID, if it is not
+// nil, or an admin, and returns their ID. Otherwise it writes a 401 response,
+// or a 403 response with the forbidden message, and returns false. Like
+// RequireRole, the role is read from the database rather than the token.
+func AuthorizeOwnerOrAdmin(w http.ResponseWriter, r *http.Request, lookup RoleLookup, ownerID *int, forbidden string) (int, bool) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "Unauthorized")
+		return 0, false
+	}
+	if ownerID != nil && *ownerID == userID {
+		return userID, true
+	}
+
+	role, err := lookup.GetRole(r.Context(), userID)
+	if err != nil {
+		response.Internal(w, r, fmt.Sprintf("looking up role of user %d", userID), err)
+		return 0, false
+	}
+	if role != models.RoleAdmin {
+		response.Error(w, r, http.StatusForbidden, response.CodeForbidden, forbidden)
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// SessionChecker reports whether a session family may still be used
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, familyID string) (bool, error)
+}
+
+// sessionActive checks the session a token was issued for. Tokens without a
+// session ID predate sessions and are rejected.
+func sessionActive(ctx context.Context, sessions SessionChecker, claims *UserClaims) (bool, error) {
+	if claims.SessionID == "" {
+		return false, nil
+	}
+	return sessions.IsSessionActive(ctx, claims.SessionID)
+}
+
+// NewSessionFamilyID generates a random ID for a new login session
+func NewSessionFamilyID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GenerateOpaqueToken returns a random opaque token, such as a refresh token
+// or a password reset token, and the hash to store in its place
+func GenerateOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken hashes an opaque token for storage and lookup
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit log entry types
+const (
+	// AuditDonationCreated records a donation created through the API or
+	// discovered by the indexer
+	AuditDonationCreated = "donation_created"
+	// AuditDonationStatusChanged records a status transition
+	AuditDonationStatusChanged = "donation_status_changed"
+	// AuditDonationLinked records the indexer linking a donation to its
+	// DonationMade event
+	AuditDonationLinked = "donation_linked"
+	// AuditDonationUnlinked records a chain reorganisation removing the
+	// event a donation was linked to
+	AuditDonationUnlinked = "donation_unlinked"
+	// AuditDonationRemoved records a chain reorganisation removing an
+	// on-chain donation
+	AuditDonationRemoved = "donation_removed"
+	// AuditDonationImported records a donation that existed before the audit
+	// log did
+	AuditDonationImported = "donation_imported"
+)
+
+// DonationSnapshot is the state of a donation as recorded in the audit log
+type DonationSnapshot struct {
+	ID              int            `json:"id"`
+	CauseID         int            `json:"cause_id"`
+	UserID          *int           `json:"user_id"`
+	Amount          string         `json:"amount"`
+	Currency        Currency       `json:"currency"`
+	IsAnonymous     bool           `json:"is_anonymous"`
+	Status          DonationStatus `json:"status"`
+	Source          string         `json:"source"`
+	TransactionHash string         `json:"transaction_hash"`
+	BlockNumber     *int64         `json:"block_number"`
+	LogIndex        *int64         `json:"log_index"`
+}
+
+// AuditRecord is the payload of an audit log entry. Its JSON encoding is what
+// the entry's hash covers.
+type AuditRecord struct {
+	Type       string           `json:"type"`
+	Donation   DonationSnapshot `json:"donation"`
+	ActorType  string           `json:"actor_type,omitempty"`
+	ActorID    *int             `json:"actor_id,omitempty"`
+	Reason     string           `json:"reason,omitempty"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+// AuditEntry is one link of the hash-chained audit log. Hash is the SHA-256
+// of PrevHash followed by Payload, so changing any entry breaks every hash
+// after it.
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	DonationID int       `json:"donation_id"`
+	Payload    string    `json:"payload"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditRoot is the Merkle root of a batch of consecutive audit log entries.
+// AnchorTransactionHash is set once the root has been published on-chain.
+type AuditRoot struct {
+	ID                    int       `json:"id"`
+	FirstEntryID          int64     `json:"first_entry_id"`
+	LastEntryID           int64     `json:"last_entry_id"`
+	EntryCount            int       `json:"entry_count"`
+	Root                  string    `json:"root"`
+	AnchorTransactionHash string    `json:"anchor_transaction_hash,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+// AuditAnchorInput represents the data needed to record an on-chain anchor
+type AuditAnchorInput struct {
+	TransactionHash string `json:"transaction_hash" validate:"required"`
+}
+
+// AuditRootSortKeys are the sort keys accepted by the audit root list
+var AuditRootSortKeys = []string{"created_at"}
@@ -6,28 +6,84 @@ import (
 
 // Cause represents a cause
 type Cause struct {
-	ID           int       `json:"id"`
-	Title        string    `json:"title"`
-	Organization string    `json:"organization"`
-	Description  string    `json:"description"`
-	ImageURL     string    `json:"image_url"`
-	RaisedAmount float64   `json:"raised_amount"`
-	GoalAmount   float64   `json:"goal_amount"`
-	CategoryID   int       `json:"category_id"`
-	Featured     int       `json:"featured"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	CategoryName string    `json:"category_name,omitempty"`
-	Category     string    `json:"category,omitempty"`
+	ID             int       `json:"id"`
+	Title          string    `json:"title"`
+	Organization   string    `json:"organization"`
+	Description    string    `json:"description"`
+	ImageURL       string    `json:"image_url"`
+	RaisedAmount   Money     `json:"raised_amount"`
+	GoalAmount     Money     `json:"goal_amount"`
+	Currency       Currency  `json:"currency"`
+	CategoryID     int       `json:"category_id"`
+	Featured       int       `json:"featured"`
+	ChainCharityID *int64    `json:"chain_charity_id,omitempty"`
+	OwnerID        *int      `json:"owner_id,omitempty"`
+	TaxID          string    `json:"tax_id,omitempty"`
+	TaxDeductible  bool      `json:"tax_deductible"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	CategoryName   string    `json:"category_name,omitempty"`
+	Category       string    `json:"category,omitempty"`
+	// MatchedAmount is the part of RaisedAmount given by matching pledges.
+	// It and MatchingPledges are only set when a single cause is requested.
+	MatchedAmount   *Money           `json:"matched_amount,omitempty"`
+	MatchingPledges []MatchingPledge `json:"matching_pledges,omitempty"`
 }
 
 // CauseInput represents the data needed to create or update a cause
 type CauseInput struct {
-	Title        string  `json:"title" validate:"required"`
-	Organization string  `json:"organization" validate:"required"`
-	Description  string  `json:"description" validate:"required"`
-	ImageURL     string  `json:"image_url" validate:"required"`
-	GoalAmount   float64 `json:"goal_amount" validate:"required,gt=0"`
-	CategoryID   int     `json:"category_id" validate:"required"`
-	Featured     int     `json:"featured"` // Changed from bool to int
+	Title          string  `json:"title" validate:"required"`
+	Organization   string  `json:"organization" validate:"required"`
+	Description    string  `json:"description" validate:"required"`
+	ImageURL       string  `json:"image_url" validate:"required,url"`
+	GoalAmount     Decimal `json:"goal_amount" validate:"required,gt=0"`
+	Currency       string  `json:"currency"` // USD, USDC or ETH; defaults to USD
+	CategoryID     int     `json:"category_id" validate:"required"`
+	Featured       int     `json:"featured"`         // Changed from bool to int
+	ChainCharityID *int64  `json:"chain_charity_id"` // Charity ID in the CharityDonation contract
+	OwnerID        *int    `json:"owner_id"`         // User who may report how the funds are spent
+	TaxID          string  `json:"tax_id" validate:"max=100"`
+	TaxDeductible  bool    `json:"tax_deductible"` // Whether receipts state the donation is deductible
+}
+
+// GoalMoney parses the goal amount in the input currency
+func (in CauseInput) GoalMoney() (Money, error) {
+	currency, err := ParseCurrency(in.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return in.GoalAmount.Money(currency)
+}
+
+// CauseSearchFilter selects the causes matching a full-text search. Results
+// are ordered by rank, so PageRequest.SortKey is ignored.
+type CauseSearchFilter struct {
+	PageRequest
+	Query      string
+	CategoryID *int
+	Featured   *bool
+}
+
+// CauseSearchResult is a cause matching a search. Headline is the title and
+// Snippet an excerpt of the description, both HTML-escaped with the matching
+// words wrapped in <mark> tags.
+type CauseSearchResult struct {
+	Cause
+	Rank     float32 `json:"rank"`
+	Headline string  `json:"headline"`
+	Snippet  string  `json:"snippet"`
+}
+
+// RaisedAmountDrift compares a cause's stored raised amount with the total of
+// its completed donations
+type RaisedAmountDrift struct {
+	CauseID  int    `json:"cause_id"`
+	Title    string `json:"title"`
+	Stored   Money  `json:"stored"`
+	Computed Money  `json:"computed"`
+}
+
+// HasDrift reports whether the stored and computed amounts differ
+func (d RaisedAmountDrift) HasDrift() bool {
+	return !d.Stored.Equal(d.Computed)
 }
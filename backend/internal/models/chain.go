@@ -0,0 +1,44 @@
+package models
+
+import (
+	"math/big"
+	"time"
+)
+
+// Donation sources
+const (
+	DonationSourceOffchain = "offchain"
+	DonationSourceOnchain  = "onchain"
+)
+
+// ChainDonation is a decoded DonationMade event
+type ChainDonation struct {
+	ChainCharityID  int64
+	ChainDonationID *big.Int
+	DonorAddress    string
+	Amount          Money
+	TransactionHash string
+	BlockNumber     uint64
+	LogIndex        uint64
+}
+
+// ChainWithdrawal is a decoded UsdcWithdrawn or EthWithdrawn event
+type ChainWithdrawal struct {
+	ID              int       `json:"id"`
+	ChainCharityID  int64     `json:"chain_charity_id"`
+	CauseID         *int      `json:"cause_id"`
+	WalletAddress   string    `json:"wallet_address"`
+	Amount          Money     `json:"amount"`
+	Currency        Currency  `json:"currency"`
+	TransactionHash string    `json:"transaction_hash"`
+	BlockNumber     uint64    `json:"block_number"`
+	LogIndex        uint64    `json:"log_index"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// IndexerCheckpoint records the last block an indexer has fully processed
+type IndexerCheckpoint struct {
+	Name        string
+	BlockNumber uint64
+	BlockHash   string
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrExceedsWithdrawal is returned when the disbursements reported against a
+// withdrawal would add up to more than was withdrawn
+var ErrExceedsWithdrawal = errors.New("disbursements exceed the withdrawn amount")
+
+// DisbursementSortKeys are the sort keys accepted by the disbursement list
+var DisbursementSortKeys = []string{"spent_at", "amount"}
+
+// Disbursement reports money spent from a cause's funds. WithdrawalID links
+// it to the on-chain withdrawal the money came from, if any.
+type Disbursement struct {
+	ID              int                    `json:"id"`
+	CauseID         int                    `json:"cause_id"`
+	WithdrawalID    *int                   `json:"withdrawal_id"`
+	Amount          Money                  `json:"amount"`
+	Currency        Currency               `json:"currency"`
+	TransactionHash string                 `json:"transaction_hash,omitempty"`
+	RecipientWallet string                 `json:"recipient_wallet,omitempty"`
+	Purpose         string                 `json:"purpose"`
+	SpentAt         time.Time              `json:"spent_at"`
+	ReportedBy      *int                   `json:"reported_by,omitempty"`
+	Documents       []DisbursementDocument `json:"documents"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+}
+
+// DisbursementDocument is a receipt or invoice backing a disbursement.
+// SHA256 is the hex hash of the document's content, if known.
+type DisbursementDocument struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// DisbursementInput represents the data needed to report or update a
+// disbursement. Documents replace any the disbursement already has.
+type DisbursementInput struct {
+	WithdrawalID    *int                        `json:"withdrawal_id"`
+	Amount          Decimal                     `json:"amount" validate:"required,gt=0"`
+	Currency        string                      `json:"currency"` // USD, USDC or ETH; defaults to USD
+	TransactionHash string                      `json:"transaction_hash"`
+	RecipientWallet string                      `json:"recipient_wallet"`
+	Purpose         string                      `json:"purpose" validate:"required,max=2000"`
+	SpentAt         *time.Time                  `json:"spent_at"` // defaults to now
+	Documents       []DisbursementDocumentInput `json:"documents"`
+}
+
+// Money parses the input amount in its currency
+func (in DisbursementInput) Money() (Money, error) {
+	currency, err := ParseCurrency(in.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return in.Amount.Money(currency)
+}
+
+// DisbursementDocumentInput represents a document attached to a disbursement
+type DisbursementDocumentInput struct {
+	Name   string `json:"name" validate:"required,max=255"`
+	URL    string `json:"url" validate:"required,url"`
+	SHA256 string `json:"sha256"`
+}
+
+// DisbursementFilter selects the disbursements to list
+type DisbursementFilter struct {
+	PageRequest
+	CauseID      *int
+	WithdrawalID *int
+	Currency     Currency
+}
+
+// CauseFunds compares the money a cause raised, withdrew and reported spent
+// in one currency. Unreported is the part of Withdrawn that no disbursement
+// has been reported against yet.
+type CauseFunds struct {
+	Currency   Currency `json:"currency"`
+	Raised     Money    `json:"raised"`
+	Withdrawn  Money    `json:"withdrawn"`
+	Spent      Money    `json:"spent"`
+	Unreported Money    `json:"unreported"`
+}
+
+// CauseFundsSummary is the funds of a cause in every currency it has used
+type CauseFundsSummary struct {
+	CauseID    int          `json:"cause_id"`
+	Currencies []CauseFunds `json:"currencies"`
+}
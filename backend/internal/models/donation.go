@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 )
 
@@ -13,16 +14,69 @@ const (
 	DonationStatusFailed    DonationStatus = "failed"
 )
 
+// ErrInvalidStatusTransition is returned when a donation cannot move to the requested status
+var ErrInvalidStatusTransition = errors.New("invalid donation status transition")
+
+// donationStatusTransitions lists the statuses each status may move to.
+// Completed and failed are terminal.
+var donationStatusTransitions = map[DonationStatus][]DonationStatus{
+	DonationStatusPending: {DonationStatusCompleted, DonationStatusFailed},
+}
+
+// IsValid reports whether the status is a known donation status
+func (s DonationStatus) IsValid() bool {
+	switch s {
+	case DonationStatusPending, DonationStatusCompleted, DonationStatusFailed:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether a donation may move from s to next
+func (s DonationStatus) CanTransitionTo(next DonationStatus) bool {
+	for _, allowed := range donationStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Actors that can change a donation's status
+const (
+	StatusActorUser    = "user"
+	StatusActorAdmin   = "admin"
+	StatusActorIndexer = "indexer"
+	StatusActorSystem  = "system"
+)
+
+// DonationStatusChange records a single status transition of a donation
+type DonationStatusChange struct {
+	ID              int             `json:"id"`
+	DonationID      int             `json:"donation_id"`
+	FromStatus      *DonationStatus `json:"from_status"`
+	ToStatus        DonationStatus  `json:"to_status"`
+	ActorType       string          `json:"actor_type"`
+	ActorID         *int            `json:"actor_id,omitempty"`
+	Reason          string          `json:"reason,omitempty"`
+	TransactionHash string          `json:"transaction_hash,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
 // Donation represents a donation
 type Donation struct {
 	ID                int            `json:"id"`
 	UserID            *int           `json:"user_id"`
 	CauseID           int            `json:"cause_id"`
-	Amount            float64        `json:"amount"`
+	Amount            Money          `json:"amount"`
+	Currency          Currency       `json:"currency"`
 	IsAnonymous       bool           `json:"is_anonymous"`
 	Status            DonationStatus `json:"status"`
 	TransactionID     string         `json:"transaction_id,omitempty"`
 	TransactionHash   string         `json:"transaction_hash,omitempty"`
+	Source            string         `json:"source,omitempty"`
+	BlockNumber       *int64         `json:"block_number,omitempty"`
+	DonorAddress      string         `json:"donor_address,omitempty"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	CauseTitle        string         `json:"cause_title,omitempty"`
@@ -35,6 +89,41 @@ type Donation struct {
 type DonationInput struct {
 	UserID      *int    `json:"user_id"`
 	CauseID     int     `json:"cause_id" validate:"required"`
-	Amount      float64 `json:"amount" validate:"required,gt=0"`
+	Amount      Decimal `json:"amount" validate:"required,gt=0"`
+	Currency    string  `json:"currency"` // USD, USDC or ETH; defaults to USD
 	IsAnonymous bool    `json:"is_anonymous"`
 }
+
+// Money parses the input amount in its currency
+func (in DonationInput) Money() (Money, error) {
+	currency, err := ParseCurrency(in.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return in.Amount.Money(currency)
+}
+
+// maxDonationAmounts caps a single donation per currency so that typos such
+// as an extra zero are rejected before they reach a cause's total
+var maxDonationAmounts = map[Currency]string{
+	CurrencyUSD:  "1000000",
+	CurrencyUSDC: "1000000",
+	CurrencyETH:  "500",
+}
+
+// MaxDonation returns the largest amount accepted for a single donation
+func MaxDonation(currency Currency) Money {
+	limit, _ := ParseMoney(maxDonationAmounts[currency], currency)
+	return limit
+}
+
+// DonationConfirmInput represents the data needed to confirm a donation on-chain
+type DonationConfirmInput struct {
+	TransactionHash string `json:"transaction_hash" validate:"required"`
+}
+
+// DonationStatusInput represents an admin's manual status change
+type DonationStatusInput struct {
+	Status DonationStatus `json:"status" validate:"required,oneof=completed failed"`
+	Reason string         `json:"reason" validate:"required"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// IdempotencyRecord is what is stored against an Idempotency-Key. Fingerprint
+// identifies the request the key was first used with. StatusCode is 0 while
+// that request is still being handled.
+type IdempotencyRecord struct {
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// InProgress reports whether the first request with the key has not finished
+func (r *IdempotencyRecord) InProgress() bool {
+	return r.StatusCode == 0
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Ledger entry types
+const (
+	LedgerEntryDonation   = "donation"
+	LedgerEntryWithdrawal = "withdrawal"
+)
+
+// Ledger entry directions
+const (
+	LedgerDirectionIn  = "in"
+	LedgerDirectionOut = "out"
+)
+
+// LedgerSortKeys are the sort keys accepted by the ledger
+var LedgerSortKeys = []string{"occurred_at"}
+
+// LedgerEntry is one movement of money in or out of a cause: a completed
+// donation or an on-chain withdrawal. Balance is the cause's balance in the
+// entry's currency once the entry is applied.
+type LedgerEntry struct {
+	Type            string    `json:"type"`
+	ID              int       `json:"id"`
+	Direction       string    `json:"direction"`
+	CauseID         *int      `json:"cause_id"`
+	CauseTitle      string    `json:"cause_title,omitempty"`
+	Amount          Money     `json:"amount"`
+	Currency        Currency  `json:"currency"`
+	Balance         Money     `json:"balance"`
+	Source          string    `json:"source"`
+	Donor           string    `json:"donor,omitempty"`
+	WalletAddress   string    `json:"wallet_address,omitempty"`
+	TransactionHash string    `json:"transaction_hash,omitempty"`
+	ExplorerURL     string    `json:"explorer_url,omitempty"`
+	BlockNumber     *int64    `json:"block_number,omitempty"`
+	OccurredAt      time.Time `json:"occurred_at"`
+}
+
+// LedgerFilter selects the ledger entries to list
+type LedgerFilter struct {
+	PageRequest
+	CauseID  *int
+	Currency Currency
+	Type     string
+	From     *time.Time
+	To       *time.Time
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+// Page size limits for list endpoints
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+)
+
+// Sort keys accepted by the cause and donation lists. Prefix a key with "-"
+// to sort in descending order.
+var (
+	CauseSortKeys    = []string{"created_at", "title", "goal_amount", "raised_amount"}
+	DonationSortKeys = []string{"created_at", "amount"}
+)
+
+// PageRequest selects one page of a list. Cursor is the NextCursor of the
+// previous page, or empty for the first page.
+type PageRequest struct {
+	Limit      int
+	Cursor     string
+	SortKey    string
+	Descending bool
+}
+
+// Sort returns the sort as written in the query string, e.g. "-created_at"
+func (p PageRequest) Sort() string {
+	if p.Descending {
+		return "-" + p.SortKey
+	}
+	return p.SortKey
+}
+
+// Page is one page of a list. NextCursor is empty on the last page and Total
+// counts every item matching the filters, not just those on this page.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+	Total      int
+}
+
+// CauseFilter selects the causes to list
+type CauseFilter struct {
+	PageRequest
+	CategoryID *int
+	Featured   *bool
+	Currency   Currency
+}
+
+// DonationFilter selects the donations to list. MinAmount and MaxAmount are
+// compared numerically regardless of currency, so combine them with Currency
+// when causes accept more than one.
+type DonationFilter struct {
+	PageRequest
+	CauseID   *int
+	UserID    *int
+	Status    DonationStatus
+	Currency  Currency
+	MinAmount string
+	MaxAmount string
+	From      *time.Time
+	To        *time.Time
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Results recorded for login attempts
+const (
+	LoginResultSuccess            = "success"
+	LoginResultInvalidCredentials = "invalid_credentials"
+	LoginResultThrottled          = "throttled"
+)
+
+// LoginAttempt is an audit record of one login attempt
+type LoginAttempt struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	UserID    *int      `json:"user_id,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Result    string    `json:"result"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// DonationSourceMatching marks donations made by a matching pledge
+const DonationSourceMatching = "matching"
+
+// Matching pledge statuses, derived from the pledge's dates, cap and closure
+const (
+	PledgeStatusScheduled = "scheduled"
+	PledgeStatusOpen      = "open"
+	PledgeStatusExhausted = "exhausted"
+	PledgeStatusEnded     = "ended"
+	PledgeStatusClosed    = "closed"
+)
+
+// ErrPledgeClosed is returned when a matching pledge that is already closed
+// is closed again
+var ErrPledgeClosed = errors.New("matching pledge is already closed")
+
+// MatchingPledge is a sponsor's promise to match every completed donation to
+// a cause in Currency, one for one, until MatchedAmount reaches Cap or EndsAt
+// passes. Each match is recorded as a completed donation of its own.
+type MatchingPledge struct {
+	ID            int        `json:"id"`
+	CauseID       int        `json:"cause_id"`
+	SponsorName   string     `json:"sponsor_name"`
+	Currency      Currency   `json:"currency"`
+	Cap           Money      `json:"cap"`
+	MatchedAmount Money      `json:"matched_amount"`
+	Remaining     Money      `json:"remaining"`
+	Status        string     `json:"status"`
+	StartsAt      time.Time  `json:"starts_at"`
+	EndsAt        time.Time  `json:"ends_at"`
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+	CreatedBy     *int       `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// PledgeStatus returns the status of a pledge at now
+func (p *MatchingPledge) PledgeStatus(now time.Time) string {
+	switch {
+	case p.ClosedAt != nil:
+		return PledgeStatusClosed
+	case !now.Before(p.EndsAt):
+		return PledgeStatusEnded
+	case p.Remaining.Sign() <= 0:
+		return PledgeStatusExhausted
+	case now.Before(p.StartsAt):
+		return PledgeStatusScheduled
+	}
+	return PledgeStatusOpen
+}
+
+// MatchingPledgeInput represents the data needed to create a matching pledge.
+// Currency defaults to the cause's currency and StartsAt to now.
+type MatchingPledgeInput struct {
+	SponsorName string     `json:"sponsor_name" validate:"required,max=200"`
+	Cap         Decimal    `json:"cap" validate:"required,gt=0"`
+	Currency    string     `json:"currency"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+}
+
+// CapMoney parses the input cap in its currency
+func (in MatchingPledgeInput) CapMoney() (Money, error) {
+	currency, err := ParseCurrency(in.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return in.Cap.Money(currency)
+}
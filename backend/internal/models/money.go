@@ -0,0 +1,241 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Currency is the code of a currency or token that amounts are held in
+type Currency string
+
+const (
+	CurrencyUSD  Currency = "USD"
+	CurrencyUSDC Currency = "USDC"
+	CurrencyETH  Currency = "ETH"
+)
+
+// DefaultCurrency is used when a request does not specify a currency
+const DefaultCurrency = CurrencyUSD
+
+var (
+	// ErrUnsupportedCurrency is returned for currency codes we do not handle
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrInvalidAmount is returned for amounts that are not plain decimal numbers
+	ErrInvalidAmount = errors.New("invalid amount")
+	// ErrCurrencyMismatch is returned when combining amounts in different currencies
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// ParseCurrency parses a currency code, defaulting to DefaultCurrency when empty
+func ParseCurrency(code string) (Currency, error) {
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+	c := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if !c.IsValid() {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedCurrency, code)
+	}
+	return c, nil
+}
+
+// IsValid reports whether the currency is supported
+func (c Currency) IsValid() bool {
+	switch c {
+	case CurrencyUSD, CurrencyUSDC, CurrencyETH:
+		return true
+	}
+	return false
+}
+
+// Decimals returns the number of decimal places of the currency's minor unit:
+// cents for USD, micro-units for USDC and wei for ETH
+func (c Currency) Decimals() int {
+	switch c {
+	case CurrencyUSDC:
+		return 6
+	case CurrencyETH:
+		return 18
+	default:
+		return 2
+	}
+}
+
+// Money is an exact amount held as an integer number of minor units
+type Money struct {
+	Minor    *big.Int
+	Currency Currency
+}
+
+// NewMoney creates an amount from a number of minor units
+func NewMoney(minor *big.Int, currency Currency) Money {
+	return Money{Minor: new(big.Int).Set(minor), Currency: currency}
+}
+
+// ZeroMoney returns a zero amount in the currency
+func ZeroMoney(currency Currency) Money {
+	return Money{Minor: new(big.Int), Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "12.50" in the given currency.
+// Digits beyond the currency's precision are rejected unless they are zeros,
+// so no value is ever silently rounded.
+func ParseMoney(s string, currency Currency) (Money, error) {
+	if !currency.IsValid() {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "-") {
+		negative = true
+		s = s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" && (!hasPoint || frac == "") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	decimals := currency.Decimals()
+	if len(frac) > decimals {
+		if strings.Trim(frac[decimals:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %s supports at most %d decimal places", ErrInvalidAmount, currency, decimals)
+		}
+		frac = frac[:decimals]
+	}
+	frac += strings.Repeat("0", decimals-len(frac))
+
+	minor, ok := new(big.Int).SetString(whole+frac, 10)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if negative {
+		minor.Neg(minor)
+	}
+
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// isDigits reports whether s only contains ASCII digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// minor returns the minor units, treating a zero value Money as zero
+func (m Money) minor() *big.Int {
+	if m.Minor == nil {
+		return new(big.Int)
+	}
+	return m.Minor
+}
+
+// String formats the amount as a decimal with the currency's full precision
+func (m Money) String() string {
+	decimals := m.Currency.Decimals()
+	minor := m.minor()
+
+	digits := new(big.Int).Abs(minor).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	sign := ""
+	if minor.Sign() < 0 {
+		sign = "-"
+	}
+
+	point := len(digits) - decimals
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the amount
+func (m Money) Sign() int {
+	return m.minor().Sign()
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Sign() == 0
+}
+
+// Add returns m + other
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Minor: new(big.Int).Add(m.minor(), other.minor()), Currency: m.Currency}, nil
+}
+
+// Sub returns m - other
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Minor: new(big.Int).Sub(m.minor(), other.minor()), Currency: m.Currency}, nil
+}
+
+// Cmp compares two amounts in the same currency
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return m.minor().Cmp(other.minor()), nil
+}
+
+// Equal reports whether two amounts have the same currency and value
+func (m Money) Equal(other Money) bool {
+	return m.Currency == other.Currency && m.minor().Cmp(other.minor()) == 0
+}
+
+// MarshalJSON encodes the amount as a decimal string so clients never round it
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// Value stores the amount in a NUMERIC column as its decimal value
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Decimal is a decimal number received in JSON. It accepts both strings and
+// numbers, keeping the literal text so that no precision is lost before the
+// value is parsed with ParseMoney.
+type Decimal string
+
+// UnmarshalJSON keeps the literal text of a JSON string or number
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*d = Decimal(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("%w: must be a number or decimal string", ErrInvalidAmount)
+	}
+	*d = Decimal(n.String())
+	return nil
+}
+
+// Money parses the decimal in the given currency
+func (d Decimal) Money(currency Currency) (Money, error) {
+	return ParseMoney(string(d), currency)
+}
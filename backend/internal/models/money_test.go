@@ -0,0 +1,180 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency Currency
+		minor    string
+		str      string
+	}{
+		{"12.5", CurrencyUSD, "1250", "12.50"},
+		{"0.01", CurrencyUSD, "1", "0.01"},
+		{"100", CurrencyUSD, "10000", "100.00"},
+		{".5", CurrencyUSD, "50", "0.50"},
+		{"7.", CurrencyUSD, "700", "7.00"},
+		{"+3.25", CurrencyUSD, "325", "3.25"},
+		{" 2.00 ", CurrencyUSD, "200", "2.00"},
+		{"1.230000", CurrencyUSD, "123", "1.23"},
+		{"0.000001", CurrencyUSDC, "1", "0.000001"},
+		{"12.5", CurrencyUSDC, "12500000", "12.500000"},
+		{"0.000000000000000001", CurrencyETH, "1", "0.000000000000000001"},
+		{"1.5", CurrencyETH, "1500000000000000000", "1.500000000000000000"},
+		{"123456789012345678901234567890.123456789012345678", CurrencyETH,
+			"123456789012345678901234567890123456789012345678", "123456789012345678901234567890.123456789012345678"},
+		{"0", CurrencyUSD, "0", "0.00"},
+		{"0.00", CurrencyETH, "0", "0.000000000000000000"},
+		{"-4.5", CurrencyUSD, "-450", "-4.50"},
+		{"-0.000001", CurrencyUSDC, "-1", "-0.000001"},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.input, tt.currency)
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %s): %v", tt.input, tt.currency, err)
+			continue
+		}
+		if m.Currency != tt.currency || m.Minor.String() != tt.minor {
+			t.Errorf("ParseMoney(%q, %s) = %s minor units of %s, want %s", tt.input, tt.currency, m.Minor, m.Currency, tt.minor)
+		}
+		if m.String() != tt.str {
+			t.Errorf("ParseMoney(%q, %s).String() = %q, want %q", tt.input, tt.currency, m.String(), tt.str)
+		}
+	}
+}
+
+func TestParseMoneySign(t *testing.T) {
+	tests := []struct {
+		input string
+		sign  int
+	}{
+		{"0", 0},
+		{"-0.00", 0},
+		{"0.01", 1},
+		{"-0.01", -1},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.input, CurrencyUSD)
+		if err != nil {
+			t.Fatalf("ParseMoney(%q): %v", tt.input, err)
+		}
+		if m.Sign() != tt.sign || m.IsZero() != (tt.sign == 0) {
+			t.Errorf("ParseMoney(%q): sign %d, zero %t; want sign %d", tt.input, m.Sign(), m.IsZero(), tt.sign)
+		}
+	}
+}
+
+func TestParseMoneyRejects(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency Currency
+		err      error
+	}{
+		{"1.001", CurrencyUSD, ErrInvalidAmount},
+		{"0.0000001", CurrencyUSDC, ErrInvalidAmount},
+		{"1.0000000000000000001", CurrencyETH, ErrInvalidAmount},
+		{"", CurrencyUSD, ErrInvalidAmount},
+		{".", CurrencyUSD, ErrInvalidAmount},
+		{"-", CurrencyUSD, ErrInvalidAmount},
+		{"--1", CurrencyUSD, ErrInvalidAmount},
+		{"1e3", CurrencyUSD, ErrInvalidAmount},
+		{"1,000.00", CurrencyUSD, ErrInvalidAmount},
+		{"1.2.3", CurrencyUSD, ErrInvalidAmount},
+		{"0x10", CurrencyUSD, ErrInvalidAmount},
+		{"ten", CurrencyUSD, ErrInvalidAmount},
+		{"1", Currency("EUR"), ErrUnsupportedCurrency},
+	}
+	for _, tt := range tests {
+		if _, err := ParseMoney(tt.input, tt.currency); !errors.Is(err, tt.err) {
+			t.Errorf("ParseMoney(%q, %s) error = %v, want %v", tt.input, tt.currency, err, tt.err)
+		}
+	}
+}
+
+func TestMoneyJSONRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		input    string
+		currency Currency
+		json     string
+	}{
+		{"19.99", CurrencyUSD, `"19.99"`},
+		{"0.1", CurrencyUSDC, `"0.100000"`},
+		{"1234.000000000000000001", CurrencyETH, `"1234.000000000000000001"`},
+		{"-2", CurrencyUSD, `"-2.00"`},
+	} {
+		m, err := ParseMoney(tt.input, tt.currency)
+		if err != nil {
+			t.Fatalf("ParseMoney(%q): %v", tt.input, err)
+		}
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if string(data) != tt.json {
+			t.Errorf("Marshal(%s %s) = %s, want %s", tt.input, tt.currency, data, tt.json)
+		}
+
+		var d Decimal
+		if err := json.Unmarshal(data, &d); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		back, err := d.Money(tt.currency)
+		if err != nil {
+			t.Fatalf("Decimal(%s).Money: %v", d, err)
+		}
+		if !back.Equal(m) {
+			t.Errorf("round trip of %s %s gave %s", m, tt.currency, back)
+		}
+	}
+}
+
+func TestDecimalAcceptsNumbers(t *testing.T) {
+	var input struct {
+		Amount Decimal `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 0.1000000000000000055511151231257827}`), &input); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if input.Amount != "0.1000000000000000055511151231257827" {
+		t.Errorf("Decimal = %q, want the literal number", input.Amount)
+	}
+	if _, err := input.Amount.Money(CurrencyETH); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Money of a 34-decimal amount in ETH: err = %v, want %v", err, ErrInvalidAmount)
+	}
+	if err := json.Unmarshal([]byte(`{"amount": true}`), &input); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Unmarshal of a boolean: err = %v, want %v", err, ErrInvalidAmount)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	a := NewMoney(big.NewInt(150), CurrencyUSD)
+	b := NewMoney(big.NewInt(25), CurrencyUSD)
+
+	sum, err := a.Add(b)
+	if err != nil || sum.String() != "1.75" {
+		t.Errorf("Add = %s, %v; want 1.75", sum, err)
+	}
+	diff, err := b.Sub(a)
+	if err != nil || diff.String() != "-1.25" {
+		t.Errorf("Sub = %s, %v; want -1.25", diff, err)
+	}
+	if cmp, err := a.Cmp(b); err != nil || cmp != 1 {
+		t.Errorf("Cmp = %d, %v; want 1", cmp, err)
+	}
+
+	eth := NewMoney(big.NewInt(150), CurrencyETH)
+	if _, err := a.Add(eth); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add across currencies: err = %v, want %v", err, ErrCurrencyMismatch)
+	}
+	if a.Equal(eth) {
+		t.Error("amounts in different currencies are equal")
+	}
+	if (Money{Currency: CurrencyUSD}).String() != "0.00" {
+		t.Error("zero value Money does not format as zero")
+	}
+}
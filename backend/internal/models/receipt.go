@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrReceiptUnavailable is returned when a receipt is requested for a
+// donation that has not completed
+var ErrReceiptUnavailable = errors.New("receipts are only issued for completed donations")
+
+// DonationReceipt is the receipt issued for a completed donation, with the
+// details printed on it. Voided is set when a chain reorganisation removed
+// the donation after the receipt was issued; the other donation details are
+// then empty.
+type DonationReceipt struct {
+	Number           int64     `json:"number"`
+	ReceiptNumber    string    `json:"receipt_number"`
+	VerificationCode string    `json:"-"`
+	IssuedAt         time.Time `json:"issued_at"`
+	Voided           bool      `json:"voided"`
+
+	DonationID      int        `json:"donation_id,omitempty"`
+	Amount          *Money     `json:"amount,omitempty"`
+	Currency        Currency   `json:"currency,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	TransactionHash string     `json:"transaction_hash,omitempty"`
+	DonorName       string     `json:"donor_name,omitempty"`
+	DonorAddress    string     `json:"donor_address,omitempty"`
+
+	CauseID       int    `json:"cause_id,omitempty"`
+	CauseTitle    string `json:"cause_title,omitempty"`
+	Organization  string `json:"organization,omitempty"`
+	TaxID         string `json:"tax_id,omitempty"`
+	TaxDeductible bool   `json:"tax_deductible"`
+}
+
+// FormatReceiptNumber formats a receipt's sequence number as printed
+func FormatReceiptNumber(number int64) string {
+	return fmt.Sprintf("TC-%08d", number)
+}
+
+// DonationStatement is a donor's consolidated statement of the receipts for
+// the donations they completed in a calendar year
+type DonationStatement struct {
+	UserID    int
+	DonorName string
+	Year      int
+	Receipts  []DonationReceipt
+}
+
+// Totals returns the total of the statement's receipts in each currency, and
+// of those marked tax deductible, in the order the currencies first appear
+func (s *DonationStatement) Totals() (all, deductible []Money, err error) {
+	add := func(totals []Money, amount Money) ([]Money, error) {
+		for i := range totals {
+			if totals[i].Currency == amount.Currency {
+				sum, err := totals[i].Add(amount)
+				totals[i] = sum
+				return totals, err
+			}
+		}
+		return append(totals, amount), nil
+	}
+
+	for _, receipt := range s.Receipts {
+		if receipt.Amount == nil {
+			continue
+		}
+		if all, err = add(all, *receipt.Amount); err != nil {
+			return nil, nil, err
+		}
+		if receipt.TaxDeductible {
+			if deductible, err = add(deductible, *receipt.Amount); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return all, deductible, nil
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// RecurringInterval is how often a recurring donation is made
+type RecurringInterval string
+
+const (
+	RecurringWeekly  RecurringInterval = "weekly"
+	RecurringMonthly RecurringInterval = "monthly"
+)
+
+// IsValid reports whether the interval is a known recurring interval
+func (i RecurringInterval) IsValid() bool {
+	return i == RecurringWeekly || i == RecurringMonthly
+}
+
+// Occurrence returns the time of the nth run of a schedule starting at start.
+// Monthly runs keep start's day of the month, falling back to the last day of
+// shorter months, so a schedule starting on the 31st never drifts.
+func (i RecurringInterval) Occurrence(start time.Time, n int) time.Time {
+	if i == RecurringWeekly {
+		return start.AddDate(0, 0, 7*n)
+	}
+
+	year, month, day := start.Date()
+	first := time.Date(year, month+time.Month(n), 1,
+		start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// RecurringStatus represents the status of a recurring donation
+type RecurringStatus string
+
+const (
+	RecurringStatusActive    RecurringStatus = "active"
+	RecurringStatusPaused    RecurringStatus = "paused"
+	RecurringStatusCancelled RecurringStatus = "cancelled"
+)
+
+// ErrInvalidRecurringTransition is returned when a recurring donation cannot
+// move to the requested status
+var ErrInvalidRecurringTransition = errors.New("invalid recurring donation status transition")
+
+// ErrRecurringCancelled is returned when a cancelled recurring donation is
+// updated
+var ErrRecurringCancelled = errors.New("recurring donation is cancelled")
+
+// recurringStatusTransitions lists the statuses each status may move to.
+// Cancelled is terminal.
+var recurringStatusTransitions = map[RecurringStatus][]RecurringStatus{
+	RecurringStatusActive: {RecurringStatusPaused, RecurringStatusCancelled},
+	RecurringStatusPaused: {RecurringStatusActive, RecurringStatusCancelled},
+}
+
+// CanTransitionTo reports whether a recurring donation may move from s to next
+func (s RecurringStatus) CanTransitionTo(next RecurringStatus) bool {
+	for _, allowed := range recurringStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry policy for runs that fail to create their donation. The nth
+// consecutive failure is retried after RecurringRetryBase * 2^(n-1), capped
+// at RecurringRetryMax; after RecurringMaxFailures the recurring donation is
+// paused until the donor resumes it.
+const (
+	RecurringRetryBase   = 5 * time.Minute
+	RecurringRetryMax    = 24 * time.Hour
+	RecurringMaxFailures = 8
+)
+
+// RecurringRetryDelay returns how long to wait after the nth consecutive
+// failure before trying again
+func RecurringRetryDelay(failures int) time.Duration {
+	delay := RecurringRetryBase
+	for i := 1; i < failures && delay < RecurringRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > RecurringRetryMax {
+		delay = RecurringRetryMax
+	}
+	return delay
+}
+
+// RecurringDonation is a donor's standing instruction to give to a cause
+// every interval. Each run creates a pending donation for the donor to pay.
+// NextRunAt is when the next donation is due and NextAttemptAt when the
+// scheduler will next try to create it, which is later after a failure.
+type RecurringDonation struct {
+	ID             int               `json:"id"`
+	UserID         int               `json:"user_id"`
+	CauseID        int               `json:"cause_id"`
+	CauseTitle     string            `json:"cause_title,omitempty"`
+	Amount         Money             `json:"amount"`
+	Currency       Currency          `json:"currency"`
+	IsAnonymous    bool              `json:"is_anonymous"`
+	Interval       RecurringInterval `json:"interval"`
+	Status         RecurringStatus   `json:"status"`
+	StartAt        time.Time         `json:"start_at"`
+	NextRunAt      time.Time         `json:"next_run_at"`
+	NextAttemptAt  time.Time         `json:"next_attempt_at"`
+	LastRunAt      *time.Time        `json:"last_run_at,omitempty"`
+	LastDonationID *int              `json:"last_donation_id,omitempty"`
+	FailureCount   int               `json:"failure_count"`
+	LastError      string            `json:"last_error,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	CancelledAt    *time.Time        `json:"cancelled_at,omitempty"`
+}
+
+// RecurringDonationInput represents the data needed to create or update a
+// recurring donation. StartAt defaults to now and is ignored on update.
+type RecurringDonationInput struct {
+	CauseID     int               `json:"cause_id" validate:"required"`
+	Amount      Decimal           `json:"amount" validate:"required,gt=0"`
+	Currency    string            `json:"currency"` // USD, USDC or ETH; defaults to USD
+	IsAnonymous bool              `json:"is_anonymous"`
+	Interval    RecurringInterval `json:"interval" validate:"required,oneof=weekly monthly"`
+	StartAt     *time.Time        `json:"start_at"`
+}
+
+// Money parses the input amount in its currency
+func (in RecurringDonationInput) Money() (Money, error) {
+	currency, err := ParseCurrency(in.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return in.Amount.Money(currency)
+}
+
+// RecurringRun is the outcome of the scheduler processing one due recurring
+// donation. DonationID is nil if the run failed or its donation already
+// existed; Err is the failure.
+type RecurringRun struct {
+	RecurringDonationID int
+	ScheduledFor        time.Time
+	DonationID          *int
+	Paused              bool
+	Err                 error
+}
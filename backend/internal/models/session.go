@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Session is a refresh token issued to a user. Tokens descending from the
+// same login share a FamilyID.
+type Session struct {
+	ID            int        `json:"id"`
+	FamilyID      string     `json:"family_id"`
+	UserID        int        `json:"user_id"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RotatedAt     *time.Time `json:"rotated_at,omitempty"`
+	ReplacedBy    *int       `json:"replaced_by,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+	UserAgent     string     `json:"user_agent,omitempty"`
+	IPAddress     string     `json:"ip_address,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// RefreshTokenInput represents a request carrying a refresh token
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
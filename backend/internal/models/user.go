@@ -8,13 +8,14 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID           int       `json:"id"`
-	Name         string    `json:"name"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	Role         string    `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	PasswordHash  string    `json:"-"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 // UserInput represents the data needed to create or update a user
@@ -22,7 +23,29 @@ type UserInput struct {
 	Name     string `json:"name" validate:"required,min=2,max=100"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
-	Role     string `json:"role,omitempty"` // Added role field, optional
+}
+
+// UserUpdateInput represents a partial profile update. Fields left out of
+// the request are not changed.
+type UserUpdateInput struct {
+	Name *string `json:"name" validate:"omitempty,min=2,max=100"`
+}
+
+// ChangePasswordInput represents the data needed to change a password
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+// ChangeEmailInput represents the data needed to change an email address
+type ChangeEmailInput struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// UserRoleInput represents an admin's change of a user's role
+type UserRoleInput struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
 }
 
 // UserLoginInput represents the data needed for user login
@@ -51,3 +74,8 @@ const (
 	RoleUser  = "user"
 	RoleAdmin = "admin"
 )
+
+// IsValidRole reports whether role is a known role
+func IsValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// TokenPurpose is what a single-use user token may be redeemed for
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+)
+
+// TTL returns how long a token issued for the purpose stays valid
+func (p TokenPurpose) TTL() time.Duration {
+	if p == TokenPurposePasswordReset {
+		return time.Hour
+	}
+	return 48 * time.Hour
+}
+
+// ForgotPasswordInput represents a request for a password reset email
+type ForgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordInput represents the data needed to reset a password
+type ResetPasswordInput struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// VerifyEmailInput represents the data needed to verify an email address
+type VerifyEmailInput struct {
+	Token string `json:"token" validate:"required"`
+}
@@ -0,0 +1,123 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Receipt #42", "Receipt #42"},
+		{"", ""},
+		{"(draft)", `\(draft\)`},
+		{"unbalanced ) (", `unbalanced \) \(`},
+		{`C:\receipts\`, `C:\\receipts\\`},
+		{`\(`, `\\\(`},
+		{"café", `caf\351`},
+		{"Ünïcödé", `\334n\357c\366d\351`},
+		{"\u00a0£©", `\240\243\251`},
+		{"€100", `\200100`},
+		{"“quoted” – ‘fine’…", `\223quoted\224 \226 \221fine\222\205`},
+		{"Œuvre ™ Ÿ", `\214uvre \231 \237`},
+		{"中文", "??"},
+		{"Ā Ł ₿", "? ? ?"},
+		{"gift 🎁", "gift ?"},
+		{"line\nbreak\ttab\x7f", "line?break?tab?"},
+		{"\u0080\u009f", "??"},
+		{"bad \xff byte", "bad ? byte"},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.input); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeCoversWinAnsi(t *testing.T) {
+	for r, code := range winAnsi {
+		if code < 0x80 || code > 0x9F {
+			t.Errorf("%q maps to %#x, outside the Windows-1252 extensions", r, code)
+		}
+		if got, want := escape(string(r)), fmt.Sprintf(`\%03o`, code); got != want {
+			t.Errorf("escape(%q) = %q, want %q", r, got, want)
+		}
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	doc := New("Receipt (copy) for Zoë")
+	page := doc.AddPage()
+	page.Text(50, 60, HelveticaBold, 18, "Donation receipt")
+	page.Line(50, 70, 545, 70, 0.5)
+	page.Rect(50, 80, 10, 10)
+	doc.AddPage().Text(50, 60, Courier, 10, `C:\path`)
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	out := buf.Bytes()
+	if n != int64(len(out)) {
+		t.Errorf("WriteTo reported %d bytes, wrote %d", n, len(out))
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+
+	for _, want := range []string{
+		"/Title (Receipt \\(copy\\) for Zo\\353)",
+		"/Type /Pages /Kids [6 0 R 8 0 R] /Count 2",
+		"BT /F2 18 Tf 50 781.89 Td (Donation receipt) Tj ET",
+		"0.5 w 50 771.89 m 545 771.89 l S",
+		"50 751.89 10 10 re f",
+		"(C:\\\\path) Tj",
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+
+	// Ten objects follow the free entry. Every entry must point at its
+	// object, and startxref at the table.
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the table", xref)
+	}
+	lines := strings.Split(string(out[xref:]), "\n")
+	var count int
+	fmt.Sscanf(lines[1], "0 %d", &count)
+	if count != 11 {
+		t.Errorf("%d cross-reference entries, want 11", count)
+	}
+	for id := 1; id < count; id++ {
+		offset, _ := strconv.Atoi(lines[2+id][:10])
+		if obj := fmt.Sprintf("%d 0 obj\n", id); !bytes.HasPrefix(out[offset:], []byte(obj)) {
+			t.Errorf("entry %d points at %q", id, out[offset:offset+10])
+		}
+	}
+
+	// Stream lengths must match their contents
+	for _, m := range regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n(.*?)\nendstream`).FindAllSubmatch(out, -1) {
+		if length, _ := strconv.Atoi(string(m[1])); length != len(m[2]) {
+			t.Errorf("stream /Length %d, but it holds %d bytes", length, len(m[2]))
+		}
+	}
+}
+
+func TestCourierWidth(t *testing.T) {
+	if got := CourierWidth("é€x", 10); got != 18 {
+		t.Errorf("CourierWidth = %v, want 18", got)
+	}
+}
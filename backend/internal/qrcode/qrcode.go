@@ -0,0 +1,472 @@
+// Package qrcode encodes short byte strings, such as URLs, as QR codes
+// (ISO/IEC 18004) at error correction level M. Only versions 1 to 10 are
+// supported, which hold up to 213 bytes.
+package qrcode
+
+import (
+	"errors"
+)
+
+// ErrTooLong is returned for data that does not fit a version 10 code
+var ErrTooLong = errors.New("data too long for a QR code")
+
+// Code is an encoded QR code. Modules are indexed from the top left corner.
+type Code struct {
+	Size    int
+	modules [][]bool
+}
+
+// Dark reports whether the module at column x and row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// version describes the codeword layout of one version at level M
+type version struct {
+	totalCodewords int
+	eccPerBlock    int
+	blocks         int
+	alignment      []int
+}
+
+// versions lists versions 1 to 10 at error correction level M
+var versions = []version{
+	{26, 10, 1, nil},
+	{44, 16, 1, []int{6, 18}},
+	{70, 26, 1, []int{6, 22}},
+	{100, 18, 2, []int{6, 26}},
+	{134, 24, 2, []int{6, 30}},
+	{172, 16, 4, []int{6, 34}},
+	{196, 18, 4, []int{6, 22, 38}},
+	{242, 22, 4, []int{6, 24, 42}},
+	{292, 22, 5, []int{6, 26, 46}},
+	{346, 26, 5, []int{6, 28, 50}},
+}
+
+// dataCodewords returns how many codewords of v carry data
+func (v version) dataCodewords() int {
+	return v.totalCodewords - v.eccPerBlock*v.blocks
+}
+
+// Encode encodes data in byte mode in the smallest version that holds it,
+// choosing the mask with the lowest penalty
+func Encode(data []byte) (*Code, error) {
+	for i, v := range versions {
+		number := i + 1
+		countBits := 8
+		if number >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) > 8*v.dataCodewords() {
+			continue
+		}
+
+		codewords := v.interleave(encodeData(data, countBits, v.dataCodewords()))
+
+		var best *Code
+		bestPenalty := 0
+		for mask := 0; mask < 8; mask++ {
+			code := newCode(number, v)
+			code.placeCodewords(codewords)
+			code.applyMask(mask)
+			code.drawFormat(mask)
+			if penalty := code.penalty(); best == nil || penalty < bestPenalty {
+				best, bestPenalty = code.Code, penalty
+			}
+		}
+		return best, nil
+	}
+	return nil, ErrTooLong
+}
+
+// encodeData builds the data codewords: mode indicator, length, data,
+// terminator and padding
+func encodeData(data []byte, countBits, capacity int) []byte {
+	var b bitBuffer
+	b.append(0x4, 4)
+	b.append(uint(len(data)), countBits)
+	for _, c := range data {
+		b.append(uint(c), 8)
+	}
+
+	terminator := 8*capacity - b.len
+	if terminator > 4 {
+		terminator = 4
+	}
+	b.append(0, terminator)
+	if rem := b.len % 8; rem != 0 {
+		b.append(0, 8-rem)
+	}
+
+	for pad := byte(0xEC); len(b.bytes) < capacity; pad ^= 0xEC ^ 0x11 {
+		b.bytes = append(b.bytes, pad)
+		b.len += 8
+	}
+	return b.bytes
+}
+
+// interleave splits data into blocks, appends each block's error correction
+// codewords and interleaves the blocks. When the codewords do not divide
+// evenly, the last blocks hold one more data codeword than the first.
+func (v version) interleave(data []byte) []byte {
+	shortBlocks := v.blocks - v.totalCodewords%v.blocks
+	shortLen := v.totalCodewords/v.blocks - v.eccPerBlock
+	divisor := rsDivisor(v.eccPerBlock)
+
+	dataBlocks := make([][]byte, v.blocks)
+	eccBlocks := make([][]byte, v.blocks)
+	offset := 0
+	for i := range dataBlocks {
+		n := shortLen
+		if i >= shortBlocks {
+			n++
+		}
+		dataBlocks[i] = data[offset : offset+n]
+		eccBlocks[i] = rsRemainder(dataBlocks[i], divisor)
+		offset += n
+	}
+
+	result := make([]byte, 0, v.totalCodewords)
+	for i := 0; i <= shortLen; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.eccPerBlock; i++ {
+		for _, block := range eccBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// builder is a code under construction. function marks the modules of the
+// finder, timing, alignment, format and version patterns, which data and
+// masks skip.
+type builder struct {
+	*Code
+	function [][]bool
+}
+
+// newCode returns a code of version number with its function patterns drawn
+func newCode(number int, v version) builder {
+	size := 17 + 4*number
+	b := builder{Code: &Code{Size: size}}
+	b.modules = make([][]bool, size)
+	b.function = make([][]bool, size)
+	for y := range b.modules {
+		b.modules[y] = make([]bool, size)
+		b.function[y] = make([]bool, size)
+	}
+
+	// Timing patterns
+	for i := 0; i < size; i++ {
+		b.set(6, i, i%2 == 0)
+		b.set(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns and their separators
+	b.drawFinder(3, 3)
+	b.drawFinder(size-4, 3)
+	b.drawFinder(3, size-4)
+
+	// Alignment patterns, except where they would overlap a finder
+	last := len(v.alignment) - 1
+	for i, x := range v.alignment {
+		for j, y := range v.alignment {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			b.drawAlignment(x, y)
+		}
+	}
+
+	// Reserve the format areas until the mask is chosen
+	b.drawFormat(0)
+	b.drawVersion(number)
+	return b
+}
+
+// set sets a function module
+func (b builder) set(x, y int, dark bool) {
+	b.modules[y][x] = dark
+	b.function[y][x] = true
+}
+
+// drawFinder draws a finder pattern centred on x, y with its separator
+func (b builder) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= b.Size || yy < 0 || yy >= b.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			b.set(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern centred on x, y
+func (b builder) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			b.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information for level M and
+// mask, and the dark module
+func (b builder) drawFormat(mask int) {
+	data := uint(mask) // level M is 00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	for i := 0; i <= 5; i++ {
+		b.set(8, i, bit(bits, i))
+	}
+	b.set(8, 7, bit(bits, 6))
+	b.set(8, 8, bit(bits, 7))
+	b.set(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		b.set(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		b.set(b.Size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		b.set(8, b.Size-15+i, bit(bits, i))
+	}
+	b.set(8, b.Size-8, true)
+}
+
+// drawVersion draws both copies of the version information of versions 7
+// and up
+func (b builder) drawVersion(number int) {
+	if number < 7 {
+		return
+	}
+	rem := uint(number)
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := uint(number)<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		a, c := b.Size-11+i%3, i/3
+		b.set(a, c, bit(bits, i))
+		b.set(c, a, bit(bits, i))
+	}
+}
+
+// placeCodewords fills the non-function modules with codewords in the
+// zigzag order of two-column strips, right to left, skipping the vertical
+// timing pattern
+func (b builder) placeCodewords(codewords []byte) {
+	i := 0
+	for right := b.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < b.Size; vert++ {
+			y := vert
+			if upward {
+				y = b.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if b.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				b.modules[y][x] = codewords[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask inverts the non-function modules selected by mask
+func (b builder) applyMask(mask int) {
+	for y := 0; y < b.Size; y++ {
+		for x := 0; x < b.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !b.function[y][x] {
+				b.modules[y][x] = !b.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the code is to read, by the four rules of the
+// standard: runs of one colour, 2x2 blocks, finder-like patterns and
+// imbalance between dark and light
+func (c *Code) penalty() int {
+	penalty := 0
+	dark := 0
+	for i := 0; i < c.Size; i++ {
+		row := make([]bool, c.Size)
+		col := make([]bool, c.Size)
+		for j := 0; j < c.Size; j++ {
+			row[j] = c.modules[i][j]
+			col[j] = c.modules[j][i]
+			if row[j] {
+				dark++
+			}
+		}
+		penalty += linePenalty(row) + linePenalty(col)
+	}
+
+	for y := 0; y < c.Size-1; y++ {
+		for x := 0; x < c.Size-1; x++ {
+			m := c.modules[y][x]
+			if m == c.modules[y][x+1] && m == c.modules[y+1][x] && m == c.modules[y+1][x+1] {
+				penalty += 3
+			}
+		}
+	}
+
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	if k > 0 {
+		penalty += k * 10
+	}
+	return penalty
+}
+
+// finderLike is the 1:1:3:1:1 pattern, with four light modules on one side,
+// that rule 3 penalises
+var finderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// linePenalty scores one row or column for rules 1 and 3
+func linePenalty(line []bool) int {
+	penalty := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += run - 2
+		}
+		run = 1
+	}
+
+	for i := 0; i+len(finderLike[0]) <= len(line); i++ {
+		for _, pattern := range finderLike {
+			match := true
+			for j, m := range pattern {
+				if line[i+j] != m {
+					match = false
+					break
+				}
+			}
+			if match {
+				penalty += 40
+			}
+		}
+	}
+	return penalty
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the given
+// degree, highest coefficient first and without its leading 1
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords of data
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMul(x, y byte) byte {
+	var z uint
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= uint(y>>i&1) * uint(x)
+	}
+	return byte(z)
+}
+
+// bitBuffer accumulates bits most significant first
+type bitBuffer struct {
+	bytes []byte
+	len   int
+}
+
+// append appends the low n bits of v
+func (b *bitBuffer) append(v uint, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if b.len%8 == 0 {
+			b.bytes = append(b.bytes, 0)
+		}
+		if v>>i&1 == 1 {
+			b.bytes[len(b.bytes)-1] |= 0x80 >> (b.len % 8)
+		}
+		b.len++
+	}
+}
+
+// bit reports whether bit i of v is set
+func bit(v uint, i int) bool {
+	return v>>i&1 == 1
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,440 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// capacities is how many bytes versions 1 to 10 hold at level M
+var capacities = []int{14, 26, 42, 62, 84, 106, 122, 152, 180, 213}
+
+// formatInfo lists the format information of level M by mask, from table
+// C.1 of the standard, most significant bit first
+var formatInfo = []string{
+	"101010000010010",
+	"101000100100101",
+	"101111001111100",
+	"101101101001011",
+	"100010111111001",
+	"100000011001110",
+	"100111110010111",
+	"100101010100000",
+}
+
+// versionInfo lists the version information of versions 7 to 10, from
+// table D.1 of the standard
+var versionInfo = map[int]uint{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}
+
+// alignmentCentres lists the alignment pattern coordinates of versions 1
+// to 10, from table E.1 of the standard
+var alignmentCentres = [][]int{
+	nil, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34}, {6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50},
+}
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" as a version 1-M code, from the worked example at
+	// thonky.com/qr-code-tutorial
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+func TestEncodeChoosesSmallestVersion(t *testing.T) {
+	for i, capacity := range capacities {
+		for _, n := range []int{capacity, capacity + 1} {
+			want := i + 1
+			if n > capacity {
+				want++
+			}
+			if want > len(capacities) {
+				continue
+			}
+			code, err := Encode(bytes.Repeat([]byte{'a'}, n))
+			if err != nil {
+				t.Fatalf("Encode(%d bytes): %v", n, err)
+			}
+			if code.Size != 17+4*want {
+				t.Errorf("Encode(%d bytes) has size %d, want version %d", n, code.Size, want)
+			}
+		}
+	}
+
+	if _, err := Encode(make([]byte, 214)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode(214 bytes): err = %v, want %v", err, ErrTooLong)
+	}
+}
+
+func TestEncodeDecodes(t *testing.T) {
+	inputs := [][]byte{
+		{},
+		[]byte("TranspaCharity"),
+		[]byte("https://transpacharity.org/receipts/verify?donation=1024&hash=9f86d081884c7d659a2feaa0c55ad015"),
+		[]byte("\x00\xff\x10binary\x80"),
+	}
+	for _, capacity := range capacities {
+		data := make([]byte, capacity)
+		for i := range data {
+			data[i] = byte(i * 37)
+		}
+		inputs = append(inputs, data)
+	}
+
+	for _, input := range inputs {
+		code, err := Encode(input)
+		if err != nil {
+			t.Fatalf("Encode(%q): %v", input, err)
+		}
+		got, err := decode(code)
+		if err != nil {
+			t.Errorf("decoding the code for %d bytes: %v", len(input), err)
+			continue
+		}
+		if !bytes.Equal(got, input) {
+			t.Errorf("code for %q decodes as %q", input, got)
+		}
+	}
+}
+
+func TestEncodeGolden(t *testing.T) {
+	// A version 1 code that decode reads back. An encoder change that moves
+	// a module must still pass TestEncodeDecodes before this is updated.
+	want := []string{
+		"#######   #   #######",
+		"#     #  # ## #     #",
+		"# ### # #   # # ### #",
+		"# ### # # #   # ### #",
+		"# ### # ### # # ### #",
+		"#     # #   # #     #",
+		"####### # # # #######",
+		"        #  ##        ",
+		"# #####   #   #####  ",
+		"####   # # #   #  ###",
+		"  ##  ## # ####    # ",
+		"#  ##   ### ##   ##  ",
+		"   ## #   ### ##   # ",
+		"        ####  ### ## ",
+		"#######  # ##    ### ",
+		"#     # #  ### # ## #",
+		"# ### # ##    ##   ##",
+		"# ### # ##      ##   ",
+		"# ### # # ####       ",
+		"#     #   #  #  ###  ",
+		"####### ##  #### # # ",
+	}
+	code, err := Encode([]byte("TranspaCharity"))
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if got := render(code); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("code for %q =\n%s\nwant\n%s", "TranspaCharity", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// render draws a code as rows of '#' for dark and ' ' for light modules
+func render(c *Code) []string {
+	rows := make([]string, c.Size)
+	for y := range rows {
+		var b strings.Builder
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		rows[y] = b.String()
+	}
+	return rows
+}
+
+// decode reads a level M byte mode code the way a scanner would, relying
+// only on the tables of the standard. It checks the function patterns, both
+// copies of the format and version information, and that every block is a
+// Reed-Solomon codeword.
+func decode(c *Code) ([]byte, error) {
+	number := (c.Size - 17) / 4
+	if number < 1 || number > len(versions) || c.Size != 17+4*number {
+		return nil, errors.New("bad size")
+	}
+	reserved := make([][]bool, c.Size)
+	for y := range reserved {
+		reserved[y] = make([]bool, c.Size)
+	}
+	reserve := func(x, y int) { reserved[y][x] = true }
+
+	// Finder patterns with their separators, and the format areas
+	for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+		for dy := -1; dy <= 7; dy++ {
+			for dx := -1; dx <= 7; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || x >= c.Size || y < 0 || y >= c.Size {
+					continue
+				}
+				ring := max(abs(dx-3), abs(dy-3))
+				if c.Dark(x, y) != (ring != 2 && ring != 4) {
+					return nil, errors.New("bad finder pattern")
+				}
+				reserve(x, y)
+			}
+		}
+	}
+	centres := alignmentCentres[number-1]
+	for _, x := range centres {
+		for _, y := range centres {
+			// Alignment patterns never overlap a finder
+			if reserved[y][x] {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					if c.Dark(x+dx, y+dy) != (max(abs(dx), abs(dy)) != 1) {
+						return nil, errors.New("bad alignment pattern")
+					}
+					reserve(x+dx, y+dy)
+				}
+			}
+		}
+	}
+
+	for i := 8; i < c.Size-8; i++ {
+		if c.Dark(6, i) != (i%2 == 0) || c.Dark(i, 6) != (i%2 == 0) {
+			return nil, errors.New("bad timing pattern")
+		}
+		reserve(6, i)
+		reserve(i, 6)
+	}
+
+	// Format information, read around the top left finder and then split
+	// between the other two
+	var first, second []byte
+	for y := 0; y <= 8; y++ {
+		if y != 6 {
+			first = append(first, dot(c, 8, y))
+			reserve(8, y)
+		}
+	}
+	for x := 7; x >= 0; x-- {
+		if x != 6 {
+			first = append(first, dot(c, x, 8))
+			reserve(x, 8)
+		}
+	}
+	for x := c.Size - 1; x >= c.Size-8; x-- {
+		second = append(second, dot(c, x, 8))
+		reserve(x, 8)
+	}
+	for y := c.Size - 7; y < c.Size; y++ {
+		second = append(second, dot(c, 8, y))
+		reserve(8, y)
+	}
+	if !c.Dark(8, c.Size-8) {
+		return nil, errors.New("no dark module")
+	}
+	reserve(8, c.Size-8)
+	if string(first) != string(second) {
+		return nil, errors.New("format information copies differ")
+	}
+	mask := -1
+	for m, info := range formatInfo {
+		if reverse(string(first)) == info {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		return nil, errors.New("unknown format information " + string(first))
+	}
+
+	if number >= 7 {
+		var bottom, right uint
+		for i := 17; i >= 0; i-- {
+			a, b := c.Size-11+i%3, i/3
+			bottom = bottom<<1 | uint(dot(c, b, a)-'0')
+			right = right<<1 | uint(dot(c, a, b)-'0')
+			reserve(a, b)
+			reserve(b, a)
+		}
+		if bottom != versionInfo[number] || right != versionInfo[number] {
+			return nil, errors.New("bad version information")
+		}
+	}
+
+	// Data modules, two columns at a time from the right, alternately
+	// upwards and downwards
+	var bits []byte
+	upward := true
+	for right := c.Size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for i := 0; i < c.Size; i++ {
+			y := i
+			if upward {
+				y = c.Size - 1 - i
+			}
+			for x := right; x >= right-1; x-- {
+				if reserved[y][x] {
+					continue
+				}
+				dark := c.Dark(x, y)
+				if masked(mask, x, y) {
+					dark = !dark
+				}
+				if dark {
+					bits = append(bits, 1)
+				} else {
+					bits = append(bits, 0)
+				}
+			}
+		}
+		upward = !upward
+	}
+
+	v := versions[number-1]
+	codewords := make([]byte, v.totalCodewords)
+	for i := range codewords {
+		for _, b := range bits[8*i : 8*i+8] {
+			codewords[i] = codewords[i]<<1 | b
+		}
+	}
+
+	// Deinterleave; the last blocks are one data codeword longer when the
+	// codewords do not divide evenly
+	long := v.totalCodewords % v.blocks
+	blocks := make([][]byte, v.blocks)
+	next := 0
+	for i := 0; i < v.totalCodewords/v.blocks-v.eccPerBlock+1; i++ {
+		for j := range blocks {
+			if i < v.totalCodewords/v.blocks-v.eccPerBlock || j >= v.blocks-long {
+				blocks[j] = append(blocks[j], codewords[next])
+				next++
+			}
+		}
+	}
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, block...)
+	}
+	for i := 0; i < v.eccPerBlock; i++ {
+		for j := range blocks {
+			blocks[j] = append(blocks[j], codewords[next])
+			next++
+		}
+	}
+	for _, block := range blocks {
+		if !validCodeword(block, v.eccPerBlock) {
+			return nil, errors.New("block is not a Reed-Solomon codeword")
+		}
+	}
+
+	// Byte mode segment
+	if data[0]>>4 != 0x4 {
+		return nil, errors.New("not byte mode")
+	}
+	countBits := 8
+	if number >= 10 {
+		countBits = 16
+	}
+	r := reader{data: data, pos: 4}
+	n := r.read(countBits)
+	if 4+countBits+8*n > 8*len(data) {
+		return nil, errors.New("length exceeds capacity")
+	}
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(r.read(8))
+	}
+	return out, nil
+}
+
+// dot returns '1' for a dark module and '0' for a light one
+func dot(c *Code, x, y int) byte {
+	if c.Dark(x, y) {
+		return '1'
+	}
+	return '0'
+}
+
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// masked reports whether mask inverts the module at x, y (table 10 of the
+// standard, with i the row and j the column)
+func masked(mask, j, i int) bool {
+	switch mask {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return (i*j)%2+(i*j)%3 == 0
+	case 6:
+		return ((i*j)%2+(i*j)%3)%2 == 0
+	default:
+		return ((i+j)%2+(i*j)%3)%2 == 0
+	}
+}
+
+// validCodeword reports whether block, highest coefficient first, is
+// divisible by the generator polynomial with roots α^0 to α^(ecc-1)
+func validCodeword(block []byte, ecc int) bool {
+	var exp [255]byte
+	x := 1
+	for i := range exp {
+		exp[i] = byte(x)
+		x <<= 1
+		if x > 0xFF {
+			x ^= 0x11D
+		}
+	}
+	var log [256]int
+	for i, e := range exp {
+		log[e] = i
+	}
+	mul := func(a, b byte) byte {
+		if a == 0 || b == 0 {
+			return 0
+		}
+		return exp[(log[a]+log[b])%255]
+	}
+
+	for root := 0; root < ecc; root++ {
+		var s byte
+		for _, b := range block {
+			s = mul(s, exp[root]) ^ b
+		}
+		if s != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// reader reads bits most significant first
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		v = v<<1 | int(r.data[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v
+}
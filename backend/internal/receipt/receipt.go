@@ -0,0 +1,357 @@
+// Package receipt renders donation receipts and annual donation statements
+// as PDF documents
+package receipt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/pdf"
+	"github.com/ombima56/transpacharity/internal/qrcode"
+)
+
+// Page layout in points
+const (
+	marginLeft   = 50.0
+	marginRight  = pdf.PageWidth - 50
+	marginBottom = pdf.PageHeight - 60
+	contentWidth = marginRight - marginLeft
+)
+
+// Renderer renders receipts and statements
+type Renderer struct {
+	explorerURL string
+	verifyURL   string
+}
+
+// NewRenderer creates a Renderer. Transaction QR codes link to explorerURL
+// and receipts are verified at verifyURL followed by their verification code.
+func NewRenderer(explorerURL, verifyURL string) *Renderer {
+	return &Renderer{
+		explorerURL: strings.TrimRight(explorerURL, "/"),
+		verifyURL:   strings.TrimRight(verifyURL, "/"),
+	}
+}
+
+// VerificationURL returns the URL at which anyone holding the receipt can
+// check that it was issued
+func (r *Renderer) VerificationURL(receipt *models.DonationReceipt) string {
+	return r.verifyURL + "/" + receipt.VerificationCode
+}
+
+// Receipt writes the PDF receipt for a donation
+func (r *Renderer) Receipt(w io.Writer, receipt *models.DonationReceipt) error {
+	doc := pdf.New("Donation receipt " + receipt.ReceiptNumber)
+	page := doc.AddPage()
+
+	y := header(page, "Donation Receipt")
+	page.Text(marginRight-170, 62, pdf.HelveticaBold, 10, "Receipt No. "+receipt.ReceiptNumber)
+	page.Text(marginRight-170, 76, pdf.Helvetica, 9, "Issued "+formatDate(receipt.IssuedAt))
+
+	y = section(page, y, "Organization")
+	y = field(page, y, "Name", receipt.Organization)
+	y = field(page, y, "Cause", receipt.CauseTitle)
+	y = field(page, y, "Tax ID", orNotProvided(receipt.TaxID))
+
+	y = section(page, y, "Donor")
+	y = field(page, y, "Name", orNotProvided(receipt.DonorName))
+	if receipt.DonorAddress != "" {
+		y = field(page, y, "Wallet", receipt.DonorAddress)
+	}
+
+	y = section(page, y, "Donation")
+	y = field(page, y, "Amount", formatAmount(*receipt.Amount))
+	if receipt.CompletedAt != nil {
+		y = field(page, y, "Date", formatDate(*receipt.CompletedAt))
+	}
+	y = field(page, y, "Donation ID", fmt.Sprintf("%d", receipt.DonationID))
+
+	if receipt.TransactionHash != "" {
+		y = section(page, y, "Blockchain Transaction")
+		page.Text(marginLeft, y, pdf.Courier, 8, receipt.TransactionHash)
+		y += 14
+		if r.explorerURL != "" {
+			link := r.explorerURL + "/tx/" + receipt.TransactionHash
+			page.Text(marginLeft, y, pdf.Helvetica, 8, "Scan the code or open the link below to view the transaction:")
+			y += 8
+			y = drawQR(page, marginLeft, y, link)
+			y = wrapped(page, y, pdf.Courier, 7, link, 90)
+		}
+	}
+
+	y = section(page, y, "Tax Information")
+	y = paragraph(page, y, taxStatement(receipt.Organization, receipt.TaxDeductible))
+
+	y = section(page, y, "Verification")
+	y = paragraph(page, y, "Anyone holding this receipt can confirm that it was issued by TranspaCharity at:")
+	wrapped(page, y, pdf.Courier, 8, r.VerificationURL(receipt), 100)
+
+	footer(page)
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// Statement writes the PDF statement of a donor's receipts for a year
+func (r *Renderer) Statement(w io.Writer, statement *models.DonationStatement) error {
+	totals, deductible, err := statement.Totals()
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("Donation Statement %d", statement.Year)
+	doc := pdf.New(title)
+	page := doc.AddPage()
+
+	y := header(page, title)
+	page.Text(marginRight-170, 62, pdf.HelveticaBold, 10, truncate(statement.DonorName, 30))
+	page.Text(marginRight-170, 76, pdf.Helvetica, 9, "Generated "+formatDate(time.Now()))
+
+	y = paragraph(page, y, fmt.Sprintf(
+		"Donations completed between 1 January and 31 December %d (UTC). Each donation has its own receipt, "+
+			"which can be downloaded and verified individually.", statement.Year))
+	y += 6
+
+	y = tableHeader(page, y)
+	for _, receipt := range statement.Receipts {
+		if y > marginBottom-20 {
+			footer(page)
+			page = doc.AddPage()
+			y = tableHeader(page, 60)
+		}
+
+		date := ""
+		if receipt.CompletedAt != nil {
+			date = receipt.CompletedAt.UTC().Format("2006-01-02")
+		}
+		deductibleText := "No"
+		if receipt.TaxDeductible {
+			deductibleText = "Yes"
+		}
+		amount := formatAmount(*receipt.Amount)
+
+		page.Text(columns[0].x, y, pdf.Courier, 8, receipt.ReceiptNumber)
+		page.Text(columns[1].x, y, pdf.Helvetica, 8, date)
+		page.Text(columns[2].x, y, pdf.Helvetica, 8, truncate(receipt.Organization, 38))
+		page.Text(columns[2].x, y+9, pdf.Helvetica, 7, truncate(receipt.CauseTitle, 44))
+		page.Text(columns[3].x, y, pdf.Helvetica, 8, truncate(orNotProvided(receipt.TaxID), 15))
+		page.Text(columns[4].x, y, pdf.Helvetica, 8, deductibleText)
+		page.Text(marginRight-pdf.CourierWidth(amount, 8), y, pdf.Courier, 8, amount)
+		y += 22
+	}
+
+	if len(statement.Receipts) == 0 {
+		page.Text(marginLeft, y, pdf.Helvetica, 9, "No donations were completed in this year.")
+		y += 16
+	}
+
+	if y > marginBottom-60-14*float64(len(totals)+len(deductible)) {
+		footer(page)
+		page = doc.AddPage()
+		y = 60
+	}
+	page.Line(marginLeft, y-8, marginRight, y-8, 0.5)
+	y += 6
+	y = totalLines(page, y, "Total donated", totals)
+	y = totalLines(page, y, "Total marked tax deductible", deductible)
+	y += 6
+	paragraph(page, y, "Whether a donation is deductible depends on the tax rules that apply to you. "+
+		"Keep the individual receipts with your tax records.")
+
+	footer(page)
+	_, err = doc.WriteTo(w)
+	return err
+}
+
+// column is a column of the statement table
+type column struct {
+	x     float64
+	title string
+}
+
+// columns are the statement table's columns. The amount column is right
+// aligned to the margin.
+var columns = []column{
+	{marginLeft, "Receipt No."},
+	{marginLeft + 60, "Date"},
+	{marginLeft + 115, "Organization / Cause"},
+	{marginLeft + 290, "Tax ID"},
+	{marginLeft + 362, "Deductible"},
+}
+
+// tableHeader draws the statement table's header at y and returns the y of
+// the first row
+func tableHeader(page *pdf.Page, y float64) float64 {
+	for _, c := range columns {
+		page.Text(c.x, y, pdf.HelveticaBold, 8, c.title)
+	}
+	page.Text(marginRight-30, y, pdf.HelveticaBold, 8, "Amount")
+	page.Line(marginLeft, y+5, marginRight, y+5, 0.5)
+	return y + 18
+}
+
+// totalLines draws one line per currency total
+func totalLines(page *pdf.Page, y float64, label string, totals []models.Money) float64 {
+	if len(totals) == 0 {
+		page.Text(marginLeft, y, pdf.HelveticaBold, 9, label)
+		page.Text(marginRight-pdf.CourierWidth("-", 9), y, pdf.Courier, 9, "-")
+		return y + 14
+	}
+	for _, total := range totals {
+		amount := formatAmount(total)
+		page.Text(marginLeft, y, pdf.HelveticaBold, 9, label)
+		page.Text(marginRight-pdf.CourierWidth(amount, 9), y, pdf.Courier, 9, amount)
+		y += 14
+	}
+	return y
+}
+
+// header draws the page header and returns the y at which content starts
+func header(page *pdf.Page, title string) float64 {
+	page.Text(marginLeft, 62, pdf.HelveticaBold, 20, "TranspaCharity")
+	page.Text(marginLeft, 80, pdf.Helvetica, 12, title)
+	page.Line(marginLeft, 92, marginRight, 92, 1)
+	return 118
+}
+
+// footer draws the page footer
+func footer(page *pdf.Page) {
+	page.Line(marginLeft, pdf.PageHeight-45, marginRight, pdf.PageHeight-45, 0.5)
+	page.Text(marginLeft, pdf.PageHeight-33, pdf.Helvetica, 7,
+		"TranspaCharity - transparent, blockchain-verified giving. Amounts are shown in the currency donated.")
+}
+
+// section draws a section heading and returns the y of its first line
+func section(page *pdf.Page, y float64, title string) float64 {
+	y += 8
+	page.Text(marginLeft, y, pdf.HelveticaBold, 11, title)
+	page.Line(marginLeft, y+4, marginRight, y+4, 0.25)
+	return y + 18
+}
+
+// field draws a label and its value and returns the y of the next line
+func field(page *pdf.Page, y float64, label, value string) float64 {
+	page.Text(marginLeft, y, pdf.Helvetica, 9, label)
+	page.Text(marginLeft+90, y, pdf.HelveticaBold, 9, truncate(value, 70))
+	return y + 14
+}
+
+// paragraph draws text wrapped to the content width and returns the y of the
+// next line
+func paragraph(page *pdf.Page, y float64, text string) float64 {
+	// Helvetica averages about half an em per character; wrapping at 0.55
+	// em keeps even text heavy in capitals inside the margins
+	chars := contentWidth / (0.55 * 9)
+	return wrapped(page, y, pdf.Helvetica, 9, text, int(chars))
+}
+
+// wrapped draws text broken into lines of at most width characters and
+// returns the y of the next line. Words longer than a line, such as URLs,
+// are split.
+func wrapped(page *pdf.Page, y float64, font pdf.Font, size float64, text string, width int) float64 {
+	for _, line := range wrap(text, width) {
+		page.Text(marginLeft, y, font, size, line)
+		y += size + 4
+	}
+	return y + 4
+}
+
+// wrap breaks text into lines of at most width characters
+func wrap(text string, width int) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		if len(line) > 0 && len(line)+1+len(w) > width {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, w...)
+		for len(line) > width {
+			lines = append(lines, string(line[:width]))
+			line = line[width:]
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// drawQR draws a QR code of data with its top left corner at x, y and
+// returns the y below it. Data too long for a QR code is skipped.
+func drawQR(page *pdf.Page, x, y float64, data string) float64 {
+	code, err := qrcode.Encode([]byte(data))
+	if err != nil {
+		return y
+	}
+
+	// Keep the quiet zone of four modules around the code
+	const module = 2.5
+	x += 4 * module
+	y += 4 * module
+	for row := 0; row < code.Size; row++ {
+		// Draw each run of dark modules as one rectangle
+		for col := 0; col < code.Size; {
+			if !code.Dark(col, row) {
+				col++
+				continue
+			}
+			start := col
+			for col < code.Size && code.Dark(col, row) {
+				col++
+			}
+			page.Rect(x+float64(start)*module, y+float64(row)*module, float64(col-start)*module, module)
+		}
+	}
+	return y + float64(code.Size+4)*module + 8
+}
+
+// taxStatement returns the receipt's statement about deductibility
+func taxStatement(organization string, deductible bool) string {
+	if deductible {
+		return organization + " confirms that no goods or services were provided in exchange for this " +
+			"donation. It may be tax deductible to the extent allowed by the law that applies to you."
+	}
+	return organization + " has not stated that donations to this cause are tax deductible. " +
+		"This receipt confirms that the donation was made."
+}
+
+// formatAmount formats an amount with its currency, dropping trailing zeros
+// beyond two decimal places
+func formatAmount(m models.Money) string {
+	s := m.String()
+	if point := strings.IndexByte(s, '.'); point >= 0 {
+		for len(s) > point+3 && s[len(s)-1] == '0' {
+			s = s[:len(s)-1]
+		}
+	}
+	return s + " " + string(m.Currency)
+}
+
+// formatDate formats a date as printed, in UTC
+func formatDate(t time.Time) string {
+	return t.UTC().Format("2 January 2006")
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// orNotProvided returns s, or "Not provided" if it is empty
+func orNotProvided(s string) string {
+	if s == "" {
+		return "Not provided"
+	}
+	return s
+}
@@ -0,0 +1,485 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/pkg/proof"
+)
+
+// AuditRepository reads the hash-chained audit log of donations and commits
+// Merkle roots over it. Entries are appended by the repositories that change
+// donations, inside the same transaction as the change.
+type AuditRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *sql.DB, cfg *config.DatabaseConfig) *AuditRepository {
+	return &AuditRepository{db: db, schema: cfg.Schema}
+}
+
+// donationSnapshotColumns are the donation columns recorded in the audit log
+const donationSnapshotColumns = `id, cause_id, user_id, amount::text, currency, is_anonymous,
+	status, source, COALESCE(transaction_hash, ''), block_number, log_index`
+
+// scanDonationSnapshot scans a row selected with donationSnapshotColumns
+func scanDonationSnapshot(scan func(dest ...interface{}) error) (models.DonationSnapshot, error) {
+	var s models.DonationSnapshot
+	var userID, blockNumber, logIndex sql.NullInt64
+
+	err := scan(
+		&s.ID, &s.CauseID, &userID, &s.Amount, &s.Currency, &s.IsAnonymous,
+		&s.Status, &s.Source, &s.TransactionHash, &blockNumber, &logIndex,
+	)
+	if err != nil {
+		return s, err
+	}
+
+	if userID.Valid {
+		id := int(userID.Int64)
+		s.UserID = &id
+	}
+	if blockNumber.Valid {
+		s.BlockNumber = &blockNumber.Int64
+	}
+	if logIndex.Valid {
+		s.LogIndex = &logIndex.Int64
+	}
+	return s, nil
+}
+
+// logDonation appends record to the audit log with the donation's current
+// state inside tx
+func logDonation(ctx context.Context, tx *sql.Tx, schema string, donationID int, record models.AuditRecord) error {
+	snapshot, err := scanDonationSnapshot(tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM %s.donations WHERE id = $1
+	`, donationSnapshotColumns, schema), donationID).Scan)
+	if err != nil {
+		return fmt.Errorf("error reading donation %d for the audit log: %w", donationID, err)
+	}
+
+	record.Donation = snapshot
+	return appendAudit(ctx, tx, schema, record)
+}
+
+// appendAudit appends record to the audit log inside tx. The log is locked
+// until tx ends so that entries are chained, numbered and committed in the
+// same order; plain reads are not blocked.
+func appendAudit(ctx context.Context, tx *sql.Tx, schema string, record models.AuditRecord) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		LOCK TABLE %s.audit_log IN EXCLUSIVE MODE
+	`, schema))
+	if err != nil {
+		return err
+	}
+
+	prevHash := proof.GenesisHash
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT hash FROM %s.audit_log ORDER BY id DESC LIMIT 1
+	`, schema)).Scan(&prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if record.RecordedAt.IsZero() {
+		record.RecordedAt = time.Now().UTC()
+	}
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.audit_log (entry_type, donation_id, payload, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5)
+	`, schema),
+		record.Type, record.Donation.ID, string(payload), prevHash,
+		proof.EntryHash(prevHash, string(payload)),
+	)
+	return err
+}
+
+// AuditEntries gets up to limit audit log entries with IDs above afterID, in
+// ID order
+func (r *AuditRepository) AuditEntries(ctx context.Context, afterID int64, limit int) ([]models.AuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, entry_type, donation_id, payload, prev_hash, hash, created_at
+		FROM %s.audit_log
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`, r.schema), afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(
+			&e.ID, &e.Type, &e.DonationID, &e.Payload, &e.PrevHash, &e.Hash, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// DonationSnapshots gets the audited state of every donation
+func (r *AuditRepository) DonationSnapshots(ctx context.Context) ([]models.DonationSnapshot, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM %s.donations ORDER BY id
+	`, donationSnapshotColumns, r.schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []models.DonationSnapshot
+	for rows.Next() {
+		s, err := scanDonationSnapshot(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// Backfill records every donation that has no audit log entry yet, such as
+// those created before the log existed, and returns how many it recorded
+func (r *AuditRepository) Backfill(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id FROM %s.donations d
+		WHERE NOT EXISTS (
+			SELECT 1 FROM %s.audit_log a WHERE a.donation_id = d.id
+		)
+		ORDER BY id
+	`, r.schema, r.schema))
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		err := logDonation(ctx, tx, r.schema, id, models.AuditRecord{
+			Type:      models.AuditDonationImported,
+			ActorType: models.StatusActorSystem,
+			Reason:    "Recorded by audit log backfill",
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), tx.Commit()
+}
+
+// CommitRoot commits the Merkle root of every entry appended since the last
+// committed root. It returns nil if there are no new entries.
+func (r *AuditRepository) CommitRoot(ctx context.Context) (*models.AuditRoot, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Concurrent commits would cover the same entries
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		LOCK TABLE %s.audit_roots IN EXCLUSIVE MODE
+	`, r.schema))
+	if err != nil {
+		return nil, err
+	}
+
+	var lastEntryID int64
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(MAX(last_entry_id), 0) FROM %s.audit_roots
+	`, r.schema)).Scan(&lastEntryID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries become visible in ID order because appendAudit locks the log
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, hash FROM %s.audit_log WHERE id > $1 ORDER BY id
+	`, r.schema), lastEntryID)
+	if err != nil {
+		return nil, err
+	}
+	var root models.AuditRoot
+	var hashes []string
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if root.FirstEntryID == 0 {
+			root.FirstEntryID = id
+		}
+		root.LastEntryID = id
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	root.EntryCount = len(hashes)
+	if root.Root, err = proof.Root(hashes); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.audit_roots (first_entry_id, last_entry_id, entry_count, root)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, r.schema), root.FirstEntryID, root.LastEntryID, root.EntryCount, root.Root).Scan(
+		&root.ID, &root.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// Receipt gets the inclusion proof of the latest entry for a donation that is
+// covered by a committed root. It returns nil if no entry for the donation
+// has been committed yet.
+func (r *AuditRepository) Receipt(ctx context.Context, donationID int) (*proof.Receipt, error) {
+	receipt := proof.Receipt{DonationID: donationID}
+	var firstEntryID, lastEntryID int64
+	var anchor sql.NullString
+
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT a.id, a.payload, a.prev_hash, a.hash,
+			r.id, r.first_entry_id, r.last_entry_id, r.entry_count, r.root,
+			r.anchor_transaction_hash
+		FROM %s.audit_log a
+		JOIN %s.audit_roots r ON a.id BETWEEN r.first_entry_id AND r.last_entry_id
+		WHERE a.donation_id = $1
+		ORDER BY a.id DESC
+		LIMIT 1
+	`, r.schema, r.schema), donationID).Scan(
+		&receipt.EntryID, &receipt.Payload, &receipt.PrevHash, &receipt.EntryHash,
+		&receipt.RootID, &firstEntryID, &lastEntryID, &receipt.TreeSize, &receipt.Root,
+		&anchor,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	receipt.AnchorTransactionHash = anchor.String
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, hash FROM %s.audit_log
+		WHERE id BETWEEN $1 AND $2
+		ORDER BY id
+	`, r.schema), firstEntryID, lastEntryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return nil, err
+		}
+		if id == receipt.EntryID {
+			receipt.LeafIndex = len(hashes)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A batch that changed since it was committed cannot prove anything
+	if len(hashes) != receipt.TreeSize {
+		return nil, fmt.Errorf("audit root %d covers %d entries but %d remain; run verify-ledger",
+			receipt.RootID, receipt.TreeSize, len(hashes))
+	}
+	if receipt.Path, err = proof.Prove(hashes, receipt.LeafIndex); err != nil {
+		return nil, err
+	}
+	if err := receipt.Verify(); err != nil {
+		return nil, fmt.Errorf("audit root %d no longer matches its entries; run verify-ledger: %w", receipt.RootID, err)
+	}
+
+	return &receipt, nil
+}
+
+// auditRootColumns are the columns selected for an audit root
+const auditRootColumns = `r.id, r.first_entry_id, r.last_entry_id, r.entry_count, r.root,
+	r.anchor_transaction_hash, r.created_at`
+
+// scanAuditRoot scans a row selected with auditRootColumns
+func scanAuditRoot(scan func(dest ...interface{}) error) (models.AuditRoot, error) {
+	var root models.AuditRoot
+	var anchor sql.NullString
+
+	err := scan(
+		&root.ID, &root.FirstEntryID, &root.LastEntryID, &root.EntryCount, &root.Root,
+		&anchor, &root.CreatedAt,
+	)
+	root.AnchorTransactionHash = anchor.String
+	return root, err
+}
+
+// AuditRoots gets every committed root, oldest first
+func (r *AuditRepository) AuditRoots(ctx context.Context) ([]models.AuditRoot, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM %s.audit_roots r ORDER BY r.last_entry_id
+	`, auditRootColumns, r.schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roots []models.AuditRoot
+	for rows.Next() {
+		root, err := scanAuditRoot(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+
+	return roots, rows.Err()
+}
+
+// auditRootSortColumns maps the audit root sort keys to their columns
+var auditRootSortColumns = map[string]sortColumn{
+	"created_at": {"r.created_at", "timestamptz"},
+}
+
+// ListRoots gets one page of the committed roots
+func (r *AuditRepository) ListRoots(ctx context.Context, page models.PageRequest) (*models.Page[models.AuditRoot], error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*) FROM %s.audit_roots
+	`, r.schema)).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	var w where
+	order, err := pageQuery(&w, page, auditRootSortColumns, "r.id")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM %s.audit_roots r %s %s
+	`, auditRootColumns, r.schema, w.String(), order), w.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roots []models.AuditRoot
+	for rows.Next() {
+		root, err := scanAuditRoot(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	roots, next := trimPage(roots, page, func(root models.AuditRoot) (string, int) {
+		return root.CreatedAt.Format(time.RFC3339Nano), root.ID
+	})
+	return &models.Page[models.AuditRoot]{Items: roots, NextCursor: next, Total: total}, nil
+}
+
+// LatestRoot gets the most recently committed root, or nil if none has been
+// committed yet
+func (r *AuditRepository) LatestRoot(ctx context.Context) (*models.AuditRoot, error) {
+	root, err := scanAuditRoot(r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM %s.audit_roots r ORDER BY r.last_entry_id DESC LIMIT 1
+	`, auditRootColumns, r.schema)).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &root, nil
+}
+
+// SetAnchor records the transaction that published a root on-chain. It
+// returns nil if the root does not exist and ErrConflict if it is already
+// anchored.
+func (r *AuditRepository) SetAnchor(ctx context.Context, id int, transactionHash string) (*models.AuditRoot, error) {
+	root, err := scanAuditRoot(r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE %s.audit_roots r
+		SET anchor_transaction_hash = $1
+		WHERE r.id = $2 AND r.anchor_transaction_hash IS NULL
+		RETURNING %s
+	`, r.schema, auditRootColumns), transactionHash, id).Scan)
+	if err == nil {
+		return &root, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var exists bool
+	err = r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT EXISTS (SELECT 1 FROM %s.audit_roots WHERE id = $1)
+	`, r.schema), id).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+	return nil, ErrConflict
+}
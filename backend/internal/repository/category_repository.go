@@ -5,7 +5,6 @@ import (
 "database/sql"
 "errors"
 "fmt"
-"time"
 
 "github.com/ombima56/transpacharity/internal/config"
 "github.com/ombima56/transpacharity/internal/models"
@@ -34,7 +33,7 @@ func (r *CategoryRepository) Create(ctx context.Context, input models.CategoryIn
 	err := r.db.QueryRowContext(ctx, query, input.Name, input.Description).
 		Scan(&category.ID, &category.Name, &category.Description, &category.CreatedAt, &category.UpdatedAt)
 
-	return category, err
+	return category, mapWriteError(err)
 }
 
 // GetAll retrieves all categories
@@ -65,22 +64,15 @@ func (r *CategoryRepository) GetAll(ctx context.Context) ([]models.Category, err
 
 // GetByID gets a category by ID
 func (r *CategoryRepository) GetByID(ctx context.Context, id int) (*models.Category, error) {
-	query := `
-SELECT id, name, description, created_at, updated_at
-FROM categories
-WHERE id = ?
-`
+	query := fmt.Sprintf(`
+		SELECT id, name, description, created_at, updated_at
+		FROM %s.categories
+		WHERE id = $1
+	`, r.schema)
 
 	var category models.Category
-	var createdAt, updatedAt string
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-	&category.ID,
-	&category.Name,
-	&category.Description,
-	&createdAt,
-	&updatedAt,
-	)
+	err := r.db.QueryRowContext(ctx, query, id).
+		Scan(&category.ID, &category.Name, &category.Description, &category.CreatedAt, &category.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -88,46 +80,38 @@ WHERE id = ?
 		return nil, err
 	}
 
-	// Parse timestamps
-	category.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-	category.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-
 	return &category, nil
 }
 
-// Update updates a category
+// Update updates a category. It returns nil if the category does not exist.
 func (r *CategoryRepository) Update(ctx context.Context, id int, input models.CategoryInput) (*models.Category, error) {
-	query := `
-UPDATE categories
-SET name = ?, description = ?, updated_at = datetime('now')
-WHERE id = ?
-`
-
-	result, err := r.db.ExecContext(ctx, query, input.Name, input.Description, id)
-	if err != nil {
-		return nil, err
-	}
+	query := fmt.Sprintf(`
+		UPDATE %s.categories
+		SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+		RETURNING id, name, description, created_at, updated_at
+	`, r.schema)
 
-	rowsAffected, err := result.RowsAffected()
+	var category models.Category
+	err := r.db.QueryRowContext(ctx, query, input.Name, input.Description, id).
+		Scan(&category.ID, &category.Name, &category.Description, &category.CreatedAt, &category.UpdatedAt)
 	if err != nil {
-		return nil, err
-	}
-
-	if rowsAffected == 0 {
-		return nil, nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, mapWriteError(err)
 	}
 
-	// Get the updated category
-	return r.GetByID(ctx, id)
+	return &category, nil
 }
 
-// Delete deletes a category
+// Delete deletes a category. It returns ErrNotFound if the category does not
+// exist and ErrInUse if causes still belong to it.
 func (r *CategoryRepository) Delete(ctx context.Context, id int) error {
-	query := `
-DELETE FROM categories
-WHERE id = ?
-`
+	query := fmt.Sprintf(`
+		DELETE FROM %s.categories
+		WHERE id = $1
+	`, r.schema)
 
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err
+	return execDelete(ctx, r.db, query, id)
 }
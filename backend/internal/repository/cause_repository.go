@@ -5,6 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/ombima56/transpacharity/internal/config"
 	"github.com/ombima56/transpacharity/internal/models"
@@ -23,50 +27,98 @@ func NewCauseRepository(db *sql.DB, cfg *config.DatabaseConfig) *CauseRepository
 
 // Create creates a new cause
 func (r *CauseRepository) Create(ctx context.Context, input models.CauseInput) (*models.Cause, error) {
+	goal, err := input.GoalMoney()
+	if err != nil {
+		return nil, err
+	}
+
 	query := fmt.Sprintf(`
 		INSERT INTO %s.causes (
 			title, organization, description, image_url, 
-			goal_amount, category_id, featured
+			goal_amount, currency, category_id, featured, chain_charity_id, owner_id,
+			tax_id, tax_deductible
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, title, organization, description, image_url, 
-			raised_amount, goal_amount, category_id, featured, 
-			created_at, updated_at
+			raised_amount, goal_amount, currency, category_id, featured, 
+			chain_charity_id, owner_id, tax_id, tax_deductible, created_at, updated_at
 	`, r.schema)
 	
 	var cause models.Cause
-	err := r.db.QueryRowContext(
+	var raised, goalAmount decimal
+	err = r.db.QueryRowContext(
 		ctx, 
 		query, 
 		input.Title, input.Organization, input.Description, 
-		input.ImageURL, input.GoalAmount, input.CategoryID, input.Featured,
+		input.ImageURL, goal, string(goal.Currency), input.CategoryID, input.Featured,
+		input.ChainCharityID, input.OwnerID, input.TaxID, input.TaxDeductible,
 	).Scan(
 		&cause.ID, &cause.Title, &cause.Organization, 
-		&cause.Description, &cause.ImageURL, &cause.RaisedAmount, 
-		&cause.GoalAmount, &cause.CategoryID, &cause.Featured, 
-		&cause.CreatedAt, &cause.UpdatedAt,
+		&cause.Description, &cause.ImageURL, &raised, 
+		&goalAmount, &cause.Currency, &cause.CategoryID, &cause.Featured, 
+		&cause.ChainCharityID, &cause.OwnerID, &cause.TaxID, &cause.TaxDeductible, &cause.CreatedAt, &cause.UpdatedAt,
 	)
 	
 	if err != nil {
+		return nil, mapWriteError(err)
+	}
+	if err := setCauseAmounts(&cause, raised, goalAmount); err != nil {
 		return nil, err
 	}
 	
 	return &cause, nil
 }
 
-// GetAll gets all causes
-func (r *CauseRepository) GetAll(ctx context.Context) ([]*models.Cause, error) {
+// causeSortColumns maps the cause sort keys to their columns
+var causeSortColumns = map[string]sortColumn{
+	"created_at":    {"c.created_at", "timestamp"},
+	"title":         {"c.title", "text"},
+	"goal_amount":   {"c.goal_amount", "numeric"},
+	"raised_amount": {"c.raised_amount", "numeric"},
+}
+
+// List gets one page of the causes matching filter
+func (r *CauseRepository) List(ctx context.Context, filter models.CauseFilter) (*models.Page[*models.Cause], error) {
+	var w where
+	if filter.CategoryID != nil {
+		w.add("c.category_id = " + w.arg(*filter.CategoryID))
+	}
+	if filter.Featured != nil {
+		if *filter.Featured {
+			w.add("c.featured <> 0")
+		} else {
+			w.add("c.featured = 0")
+		}
+	}
+	if filter.Currency != "" {
+		w.add("c.currency = " + w.arg(string(filter.Currency)))
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*) FROM %s.causes c %s
+	`, r.schema, w.String()), w.args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := pageQuery(&w, filter.PageRequest, causeSortColumns, "c.id")
+	if err != nil {
+		return nil, err
+	}
+
 	query := fmt.Sprintf(`
 		SELECT c.id, c.title, c.organization, c.description, c.image_url, 
-			c.raised_amount, c.goal_amount, c.category_id, c.featured, 
-			c.created_at, c.updated_at,
+			c.raised_amount, c.goal_amount, c.currency, c.category_id, c.featured, 
+			c.chain_charity_id, c.owner_id, c.tax_id, c.tax_deductible, c.created_at, c.updated_at,
 			cat.name as category_name
 		FROM %s.causes c
 		LEFT JOIN %s.categories cat ON c.category_id = cat.id
-		ORDER BY c.created_at DESC
-	`, r.schema, r.schema)
+		%s
+		%s
+	`, r.schema, r.schema, w.String(), order)
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, w.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -75,16 +127,20 @@ func (r *CauseRepository) GetAll(ctx context.Context) ([]*models.Cause, error) {
 	var causes []*models.Cause
 	for rows.Next() {
 		var cause models.Cause
+		var raised, goal decimal
 		var categoryName sql.NullString
 		err := rows.Scan(
 			&cause.ID, &cause.Title, &cause.Organization, &cause.Description, &cause.ImageURL,
-			&cause.RaisedAmount, &cause.GoalAmount, &cause.CategoryID, &cause.Featured,
-			&cause.CreatedAt, &cause.UpdatedAt,
+			&raised, &goal, &cause.Currency, &cause.CategoryID, &cause.Featured,
+			&cause.ChainCharityID, &cause.OwnerID, &cause.TaxID, &cause.TaxDeductible, &cause.CreatedAt, &cause.UpdatedAt,
 			&categoryName,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if err := setCauseAmounts(&cause, raised, goal); err != nil {
+			return nil, err
+		}
 		
 		if categoryName.Valid {
 			cause.Category = categoryName.String
@@ -93,16 +149,132 @@ func (r *CauseRepository) GetAll(ctx context.Context) ([]*models.Cause, error) {
 		
 		causes = append(causes, &cause)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return causes, nil
+	causes, next := trimPage(causes, filter.PageRequest, func(c *models.Cause) (string, int) {
+		return causeSortValue(c, filter.SortKey), c.ID
+	})
+	return &models.Page[*models.Cause]{Items: causes, NextCursor: next, Total: total}, nil
+}
+
+// searchSortColumns orders search results by rank
+var searchSortColumns = map[string]sortColumn{
+	"rank": {"s.rank", "real"},
+}
+
+// Search gets one page of the causes matching a full-text search, best
+// matches first. The last word of the query also matches as a prefix so that
+// results can be shown while the user types.
+func (r *CauseRepository) Search(ctx context.Context, filter models.CauseSearchFilter) (*models.Page[*models.CauseSearchResult], error) {
+	complete, prefix, highlight := searchTerms(filter.Query)
+	if prefix == "" {
+		return &models.Page[*models.CauseSearchResult]{}, nil
+	}
+
+	var w where
+	tsquery := fmt.Sprintf("(to_tsquery('english', %s) && to_tsquery('simple', %s))", w.arg(complete), w.arg(prefix))
+	w.add("c.search_vector @@ " + tsquery)
+	if filter.CategoryID != nil {
+		w.add("c.category_id = " + w.arg(*filter.CategoryID))
+	}
+	if filter.Featured != nil {
+		if *filter.Featured {
+			w.add("c.featured <> 0")
+		} else {
+			w.add("c.featured = 0")
+		}
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*) FROM %s.causes c %s
+	`, r.schema, w.String()), w.args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	// The keyset condition applies to the ranked rows
+	outer := where{args: w.args}
+	highlightQuery := fmt.Sprintf("to_tsquery('simple', %s)", outer.arg(highlight))
+	filter.SortKey, filter.Descending = "rank", true
+	order, err := pageQuery(&outer, filter.PageRequest, searchSortColumns, "s.id")
+	if err != nil {
+		return nil, err
+	}
+
+	// Headlines are only computed for the rows on the page
+	query := fmt.Sprintf(`
+		SELECT p.id, p.title, p.organization, p.description, p.image_url,
+			p.raised_amount, p.goal_amount, p.currency, p.category_id, p.featured,
+			p.chain_charity_id, p.owner_id, p.tax_id, p.tax_deductible, p.created_at, p.updated_at, p.category_name, p.rank,
+			ts_headline('simple', %s, %s,
+				'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+			ts_headline('simple', %s, %s,
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=10, MaxWords=30')
+		FROM (
+			SELECT s.* FROM (
+				SELECT c.id, c.title, c.organization, c.description, c.image_url,
+					c.raised_amount, c.goal_amount, c.currency, c.category_id, c.featured,
+					c.chain_charity_id, c.owner_id, c.tax_id, c.tax_deductible, c.created_at, c.updated_at,
+					cat.name AS category_name,
+					ts_rank(c.search_vector, %s) AS rank
+				FROM %s.causes c
+				LEFT JOIN %s.categories cat ON c.category_id = cat.id
+				%s
+			) s
+			%s
+			%s
+		) p
+		ORDER BY p.rank DESC, p.id DESC
+	`, escapeHTML("p.title"), highlightQuery, escapeHTML("p.description"), highlightQuery,
+		tsquery, r.schema, r.schema, w.String(), outer.String(), order)
+
+	rows, err := r.db.QueryContext(ctx, query, outer.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.CauseSearchResult
+	for rows.Next() {
+		var result models.CauseSearchResult
+		var raised, goal decimal
+		var categoryName sql.NullString
+		err := rows.Scan(
+			&result.ID, &result.Title, &result.Organization, &result.Description, &result.ImageURL,
+			&raised, &goal, &result.Currency, &result.CategoryID, &result.Featured,
+			&result.ChainCharityID, &result.OwnerID, &result.TaxID, &result.TaxDeductible, &result.CreatedAt, &result.UpdatedAt,
+			&categoryName, &result.Rank, &result.Headline, &result.Snippet,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := setCauseAmounts(&result.Cause, raised, goal); err != nil {
+			return nil, err
+		}
+		result.Category = categoryName.String
+		result.CategoryName = categoryName.String
+
+		results = append(results, &result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results, next := trimPage(results, filter.PageRequest, func(result *models.CauseSearchResult) (string, int) {
+		return strconv.FormatFloat(float64(result.Rank), 'g', -1, 32), result.ID
+	})
+	return &models.Page[*models.CauseSearchResult]{Items: results, NextCursor: next, Total: total}, nil
 }
 
 // GetFeatured gets featured causes
 func (r *CauseRepository) GetFeatured(ctx context.Context) ([]*models.Cause, error) {
 	query := fmt.Sprintf(`
 		SELECT c.id, c.title, c.organization, c.description, c.image_url, 
-			c.raised_amount, c.goal_amount, c.category_id, c.featured, 
-			c.created_at, c.updated_at,
+			c.raised_amount, c.goal_amount, c.currency, c.category_id, c.featured, 
+			c.chain_charity_id, c.owner_id, c.tax_id, c.tax_deductible, c.created_at, c.updated_at,
 			cat.name as category_name
 		FROM %s.causes c
 		LEFT JOIN %s.categories cat ON c.category_id = cat.id
@@ -120,16 +292,20 @@ func (r *CauseRepository) GetFeatured(ctx context.Context) ([]*models.Cause, err
 	var causes []*models.Cause
 	for rows.Next() {
 		var cause models.Cause
+		var raised, goal decimal
 		var categoryName sql.NullString
 		err := rows.Scan(
 			&cause.ID, &cause.Title, &cause.Organization, &cause.Description, &cause.ImageURL,
-			&cause.RaisedAmount, &cause.GoalAmount, &cause.CategoryID, &cause.Featured,
-			&cause.CreatedAt, &cause.UpdatedAt,
+			&raised, &goal, &cause.Currency, &cause.CategoryID, &cause.Featured,
+			&cause.ChainCharityID, &cause.OwnerID, &cause.TaxID, &cause.TaxDeductible, &cause.CreatedAt, &cause.UpdatedAt,
 			&categoryName,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if err := setCauseAmounts(&cause, raised, goal); err != nil {
+			return nil, err
+		}
 		
 		if categoryName.Valid {
 			cause.Category = categoryName.String
@@ -142,8 +318,8 @@ func (r *CauseRepository) GetFeatured(ctx context.Context) ([]*models.Cause, err
 	if len(causes) < 3 {
 		additionalQuery := fmt.Sprintf(`
 			SELECT c.id, c.title, c.organization, c.description, c.image_url, 
-				c.raised_amount, c.goal_amount, c.category_id, c.featured, 
-				c.created_at, c.updated_at,
+				c.raised_amount, c.goal_amount, c.currency, c.category_id, c.featured, 
+				c.chain_charity_id, c.owner_id, c.tax_id, c.tax_deductible, c.created_at, c.updated_at,
 				cat.name as category_name
 			FROM %s.causes c
 			LEFT JOIN %s.categories cat ON c.category_id = cat.id
@@ -160,16 +336,20 @@ func (r *CauseRepository) GetFeatured(ctx context.Context) ([]*models.Cause, err
 		
 		for additionalRows.Next() {
 			var cause models.Cause
+			var raised, goal decimal
 			var categoryName sql.NullString
 			err := additionalRows.Scan(
 				&cause.ID, &cause.Title, &cause.Organization, &cause.Description, &cause.ImageURL,
-				&cause.RaisedAmount, &cause.GoalAmount, &cause.CategoryID, &cause.Featured,
-				&cause.CreatedAt, &cause.UpdatedAt,
+				&raised, &goal, &cause.Currency, &cause.CategoryID, &cause.Featured,
+				&cause.ChainCharityID, &cause.OwnerID, &cause.TaxID, &cause.TaxDeductible, &cause.CreatedAt, &cause.UpdatedAt,
 				&categoryName,
 			)
 			if err != nil {
 				return nil, err
 			}
+			if err := setCauseAmounts(&cause, raised, goal); err != nil {
+				return nil, err
+			}
 			
 			if categoryName.Valid {
 				cause.Category = categoryName.String
@@ -188,18 +368,19 @@ func (r *CauseRepository) GetFeatured(ctx context.Context) ([]*models.Cause, err
 func (r *CauseRepository) GetByID(ctx context.Context, id int) (*models.Cause, error) {
 	query := fmt.Sprintf(`
 		SELECT id, title, organization, description, image_url, 
-			raised_amount, goal_amount, category_id, featured, 
-			created_at, updated_at
+			raised_amount, goal_amount, currency, category_id, featured, 
+			chain_charity_id, owner_id, tax_id, tax_deductible, created_at, updated_at
 		FROM %s.causes
 		WHERE id = $1
 	`, r.schema)
 
 	var cause models.Cause
+	var raised, goal decimal
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&cause.ID, &cause.Title, &cause.Organization, 
-		&cause.Description, &cause.ImageURL, &cause.RaisedAmount, 
-		&cause.GoalAmount, &cause.CategoryID, &cause.Featured, 
-		&cause.CreatedAt, &cause.UpdatedAt,
+		&cause.Description, &cause.ImageURL, &raised, 
+		&goal, &cause.Currency, &cause.CategoryID, &cause.Featured, 
+		&cause.ChainCharityID, &cause.OwnerID, &cause.TaxID, &cause.TaxDeductible, &cause.CreatedAt, &cause.UpdatedAt,
 	)
 
 	if err != nil {
@@ -208,26 +389,42 @@ func (r *CauseRepository) GetByID(ctx context.Context, id int) (*models.Cause, e
 		}
 		return nil, err
 	}
+	if err := setCauseAmounts(&cause, raised, goal); err != nil {
+		return nil, err
+	}
 
 	return &cause, nil
 }
 
-// Update updates a cause
+// Update updates a cause. Changing the currency recomputes the raised amount
+// from the completed donations in the new currency.
 func (r *CauseRepository) Update(ctx context.Context, id int, input models.CauseInput) (*models.Cause, error) {
+	goal, err := input.GoalMoney()
+	if err != nil {
+		return nil, err
+	}
+
 	query := fmt.Sprintf(`
-		UPDATE %s.causes
+		UPDATE %s.causes c
 		SET title = $1, organization = $2, description = $3, image_url = $4,
-			goal_amount = $5, category_id = $6, featured = $7, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $8
-	`, r.schema)
+			goal_amount = $5, currency = $6, category_id = $7, featured = $8, chain_charity_id = $9,
+			owner_id = $12, tax_id = $13, tax_deductible = $14,
+			raised_amount = CASE WHEN c.currency = $6 THEN c.raised_amount ELSE COALESCE((
+				SELECT SUM(d.amount) FROM %s.donations d
+				WHERE d.cause_id = c.id AND d.status = $11 AND d.currency = $6
+			), 0) END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10
+	`, r.schema, r.schema)
 
 	result, err := r.db.ExecContext(
 		ctx, query,
 		input.Title, input.Organization, input.Description, input.ImageURL,
-		input.GoalAmount, input.CategoryID, input.Featured, id,
+		goal, string(goal.Currency), input.CategoryID, input.Featured, input.ChainCharityID, id,
+		models.DonationStatusCompleted, input.OwnerID, input.TaxID, input.TaxDeductible,
 	)
 	if err != nil {
-		return nil, err
+		return nil, mapWriteError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -243,25 +440,138 @@ func (r *CauseRepository) Update(ctx context.Context, id int, input models.Cause
 	return r.GetByID(ctx, id)
 }
 
-// Delete deletes a cause
+// Delete deletes a cause. It returns ErrNotFound if the cause does not exist
+// and ErrInUse if it already has donations.
 func (r *CauseRepository) Delete(ctx context.Context, id int) error {
-	query := `
-	DELETE FROM causes
-	WHERE id = ?
-	`
+	query := fmt.Sprintf(`
+	DELETE FROM %s.causes
+	WHERE id = $1
+	`, r.schema)
 
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err
+	return execDelete(ctx, r.db, query, id)
 }
 
-// UpdateRaisedAmount updates the raised amount for a cause
-func (r *CauseRepository) UpdateRaisedAmount(ctx context.Context, id int, amount float64) error {
-	query := `
-	UPDATE causes
-	SET raised_amount = raised_amount + ?, updated_at = datetime('now')
-	WHERE id = ?
-	`
+// ReconcileRaisedAmounts recomputes every cause's raised amount from its
+// completed donations and reports the causes whose stored value has drifted.
+// When apply is true the stored values are corrected in the same transaction.
+func (r *CauseRepository) ReconcileRaisedAmounts(ctx context.Context, apply bool) ([]models.RaisedAmountDrift, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the causes so status changes cannot race the recomputation
+	query := fmt.Sprintf(`
+		SELECT c.id, c.title, c.currency, c.raised_amount, COALESCE(t.total, 0)
+		FROM %s.causes c
+		LEFT JOIN (
+			SELECT cause_id, currency, SUM(amount) AS total
+			FROM %s.donations
+			WHERE status = $1
+			GROUP BY cause_id, currency
+		) t ON t.cause_id = c.id AND t.currency = c.currency
+		ORDER BY c.id
+		FOR UPDATE OF c
+	`, r.schema, r.schema)
+
+	rows, err := tx.QueryContext(ctx, query, models.DonationStatusCompleted)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := r.db.ExecContext(ctx, query, amount, id)
+	var drifts []models.RaisedAmountDrift
+	for rows.Next() {
+		var d models.RaisedAmountDrift
+		var currency models.Currency
+		var stored, computed decimal
+		if err := rows.Scan(&d.CauseID, &d.Title, &currency, &stored, &computed); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if d.Stored, err = stored.money(currency); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if d.Computed, err = computed.money(currency); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if d.HasDrift() {
+			drifts = append(drifts, d)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !apply {
+		return drifts, nil
+	}
+
+	for _, d := range drifts {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.causes
+			SET raised_amount = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2
+		`, r.schema), d.Computed, d.CauseID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return drifts, nil
+}
+
+// setCauseAmounts parses the scanned amounts of a cause in its currency
+func setCauseAmounts(cause *models.Cause, raised, goal decimal) error {
+	var err error
+	if cause.RaisedAmount, err = raised.money(cause.Currency); err != nil {
+		return err
+	}
+	cause.GoalAmount, err = goal.money(cause.Currency)
 	return err
 }
+
+// causeSortValue returns the value of a cause's sort key as a cursor value
+func causeSortValue(cause *models.Cause, key string) string {
+	switch key {
+	case "title":
+		return cause.Title
+	case "goal_amount":
+		return cause.GoalAmount.String()
+	case "raised_amount":
+		return cause.RaisedAmount.String()
+	}
+	return cause.CreatedAt.Format(time.RFC3339Nano)
+}
+
+// searchTerms turns free text into to_tsquery expressions. complete ANDs
+// every word but the last for the stemmed 'english' lexemes, prefix matches
+// the last word as a prefix of an unstemmed 'simple' lexeme, and highlight
+// matches every word as a prefix for ts_headline. Anything but letters and
+// digits is dropped, so the expressions are always valid. All three are ""
+// if the text has no words.
+func searchTerms(text string) (complete, prefix, highlight string) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "", "", ""
+	}
+	complete = strings.Join(words[:len(words)-1], " & ")
+	prefix = words[len(words)-1] + ":*"
+	highlight = strings.Join(words, ":* & ") + ":*"
+	return complete, prefix, highlight
+}
+
+// escapeHTML returns SQL that HTML-escapes the text column expr, so that
+// ts_headline output only contains the markup it adds itself
+func escapeHTML(expr string) string {
+	return fmt.Sprintf(`replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')`, expr)
+}
@@ -0,0 +1,396 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// ChainRepository handles database operations for indexed blockchain events
+type ChainRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewChainRepository creates a new ChainRepository
+func NewChainRepository(db *sql.DB, cfg *config.DatabaseConfig) *ChainRepository {
+	return &ChainRepository{db: db, schema: cfg.Schema}
+}
+
+// GetCheckpoint gets the checkpoint of the named indexer, or nil if it has not run yet
+func (r *ChainRepository) GetCheckpoint(ctx context.Context, name string) (*models.IndexerCheckpoint, error) {
+	query := fmt.Sprintf(`
+		SELECT name, block_number, block_hash
+		FROM %s.indexer_checkpoints
+		WHERE name = $1
+	`, r.schema)
+
+	var checkpoint models.IndexerCheckpoint
+	err := r.db.QueryRowContext(ctx, query, name).Scan(
+		&checkpoint.Name, &checkpoint.BlockNumber, &checkpoint.BlockHash,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// ApplyBlockRange stores the events found in a block range and advances the
+// checkpoint in a single transaction, so a crash never skips or repeats a range
+func (r *ChainRepository) ApplyBlockRange(
+	ctx context.Context,
+	checkpoint models.IndexerCheckpoint,
+	donations []models.ChainDonation,
+	withdrawals []models.ChainWithdrawal,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, donation := range donations {
+		if err := r.upsertDonation(ctx, tx, donation); err != nil {
+			return fmt.Errorf("error storing donation from %s#%d: %w", donation.TransactionHash, donation.LogIndex, err)
+		}
+	}
+
+	for _, withdrawal := range withdrawals {
+		if err := r.insertWithdrawal(ctx, tx, withdrawal); err != nil {
+			return fmt.Errorf("error storing withdrawal from %s#%d: %w", withdrawal.TransactionHash, withdrawal.LogIndex, err)
+		}
+	}
+
+	if err := r.saveCheckpoint(ctx, tx, checkpoint); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Rewind discards everything indexed from fromBlock onwards after a chain
+// reorganisation. The checkpoint is reset to lastGood, or removed if nil.
+// Removed donations take their matching donations with them. Every donation
+// removed or unlinked is recorded in the audit log.
+func (r *ChainRepository) Rewind(ctx context.Context, name string, fromBlock uint64, lastGood *models.IndexerCheckpoint) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Matches of donations that only existed on the orphaned blocks go with them
+	err = unmatchDonations(ctx, tx, r.schema, fmt.Sprintf(`
+		SELECT id FROM %s.donations WHERE source = $1 AND block_number >= $2
+	`, r.schema), fmt.Sprintf("Chain reorganisation from block %d removed the matched donation", fromBlock),
+		models.DonationSourceOnchain, fromBlock)
+	if err != nil {
+		return err
+	}
+
+	// Take back the amounts of donations that only existed on the orphaned blocks
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.causes c
+		SET raised_amount = c.raised_amount - d.total
+		FROM (
+			SELECT cause_id, currency, SUM(amount) AS total
+			FROM %s.donations
+			WHERE source = $1 AND block_number >= $2 AND status = 'completed'
+			GROUP BY cause_id, currency
+		) d
+		WHERE c.id = d.cause_id AND c.currency = d.currency
+	`, r.schema, r.schema), models.DonationSourceOnchain, fromBlock)
+	if err != nil {
+		return err
+	}
+
+	// Removed donations are logged with the state they had before the delete
+	removed, err := r.rewoundDonations(ctx, tx, fmt.Sprintf(`
+		SELECT %s FROM %s.donations
+		WHERE source = $1 AND block_number >= $2
+		ORDER BY id
+	`, donationSnapshotColumns, r.schema), models.DonationSourceOnchain, fromBlock)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range removed {
+		err := appendAudit(ctx, tx, r.schema, models.AuditRecord{
+			Type:      models.AuditDonationRemoved,
+			Donation:  snapshot,
+			ActorType: models.StatusActorIndexer,
+			Reason:    fmt.Sprintf("Chain reorganisation from block %d", fromBlock),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		DELETE FROM %s.donations
+		WHERE source = $1 AND block_number >= $2
+	`, r.schema), models.DonationSourceOnchain, fromBlock)
+	if err != nil {
+		return err
+	}
+
+	// Donations created through the API keep their record but lose the chain link
+	unlinked, err := r.rewoundDonations(ctx, tx, fmt.Sprintf(`
+		UPDATE %s.donations
+		SET block_number = NULL, log_index = NULL, donor_address = NULL,
+			chain_donation_id = NULL, chain_amount = NULL, chain_asset = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE source = $1 AND block_number >= $2
+		RETURNING %s
+	`, r.schema, donationSnapshotColumns), models.DonationSourceOffchain, fromBlock)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range unlinked {
+		err := appendAudit(ctx, tx, r.schema, models.AuditRecord{
+			Type:      models.AuditDonationUnlinked,
+			Donation:  snapshot,
+			ActorType: models.StatusActorIndexer,
+			Reason:    fmt.Sprintf("Chain reorganisation from block %d", fromBlock),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		DELETE FROM %s.chain_withdrawals
+		WHERE block_number >= $1
+	`, r.schema), fromBlock)
+	if err != nil {
+		return err
+	}
+
+	if lastGood != nil {
+		err = r.saveCheckpoint(ctx, tx, *lastGood)
+	} else {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			DELETE FROM %s.indexer_checkpoints WHERE name = $1
+		`, r.schema), name)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// rewoundDonations runs a query returning donationSnapshotColumns inside tx.
+// The rows are read in full before any audit entry is appended.
+func (r *ChainRepository) rewoundDonations(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]models.DonationSnapshot, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []models.DonationSnapshot
+	for rows.Next() {
+		snapshot, err := scanDonationSnapshot(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// GetWithdrawal gets an indexed withdrawal by ID
+func (r *ChainRepository) GetWithdrawal(ctx context.Context, id int) (*models.ChainWithdrawal, error) {
+	var w models.ChainWithdrawal
+	var amount decimal
+	var causeID sql.NullInt64
+
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT id, chain_charity_id, cause_id, wallet_address, amount, currency,
+			transaction_hash, block_number, log_index, created_at
+		FROM %s.chain_withdrawals
+		WHERE id = $1
+	`, r.schema), id).Scan(
+		&w.ID, &w.ChainCharityID, &causeID, &w.WalletAddress, &amount, &w.Currency,
+		&w.TransactionHash, &w.BlockNumber, &w.LogIndex, &w.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if w.Amount, err = amount.money(w.Currency); err != nil {
+		return nil, err
+	}
+
+	if causeID.Valid {
+		id := int(causeID.Int64)
+		w.CauseID = &id
+	}
+	return &w, nil
+}
+
+// saveCheckpoint upserts an indexer checkpoint
+func (r *ChainRepository) saveCheckpoint(ctx context.Context, tx *sql.Tx, checkpoint models.IndexerCheckpoint) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.indexer_checkpoints (name, block_number, block_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE
+		SET block_number = EXCLUDED.block_number,
+			block_hash = EXCLUDED.block_hash,
+			updated_at = CURRENT_TIMESTAMP
+	`, r.schema), checkpoint.Name, checkpoint.BlockNumber, checkpoint.BlockHash)
+	if err != nil {
+		return fmt.Errorf("error saving checkpoint: %w", err)
+	}
+	return nil
+}
+
+// causeIDForCharity maps an on-chain charity ID to a cause, or nil if unmapped
+func (r *ChainRepository) causeIDForCharity(ctx context.Context, tx *sql.Tx, chainCharityID int64) (*int, error) {
+	var causeID int
+	err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT id FROM %s.causes WHERE chain_charity_id = $1
+	`, r.schema), chainCharityID).Scan(&causeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &causeID, nil
+}
+
+// upsertDonation links a DonationMade event to the donation the frontend
+// created for the same transaction, or records a new on-chain donation
+func (r *ChainRepository) upsertDonation(ctx context.Context, tx *sql.Tx, donation models.ChainDonation) error {
+	causeID, err := r.causeIDForCharity(ctx, tx, donation.ChainCharityID)
+	if err != nil {
+		return err
+	}
+	if causeID == nil {
+		log.Printf("Skipping donation %s#%d: no cause mapped to charity %d",
+			donation.TransactionHash, donation.LogIndex, donation.ChainCharityID)
+		return nil
+	}
+
+	txHash := strings.ToLower(donation.TransactionHash)
+
+	var id int
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE %s.donations
+		SET block_number = $1, log_index = $2, donor_address = $3,
+			chain_donation_id = $4, chain_amount = $5, chain_asset = $6,
+			transaction_hash = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM %s.donations
+			WHERE LOWER(transaction_hash) = $7 AND cause_id = $8 AND log_index IS NULL
+			ORDER BY id
+			LIMIT 1
+		)
+		RETURNING id
+	`, r.schema, r.schema),
+		donation.BlockNumber, donation.LogIndex, donation.DonorAddress,
+		donation.ChainDonationID.String(), donation.Amount.Minor.String(), string(donation.Amount.Currency),
+		txHash, *causeID,
+	).Scan(&id)
+	if err == nil {
+		return logDonation(ctx, tx, r.schema, id, models.AuditRecord{
+			Type:      models.AuditDonationLinked,
+			ActorType: models.StatusActorIndexer,
+			Reason:    "DonationMade event indexed",
+		})
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.donations (
+			cause_id, amount, currency, is_anonymous, status, transaction_hash, source,
+			block_number, log_index, donor_address, chain_donation_id,
+			chain_amount, chain_asset
+		)
+		VALUES ($1, $2, $3, FALSE, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (transaction_hash, log_index) WHERE log_index IS NOT NULL DO NOTHING
+		RETURNING id
+	`, r.schema),
+		*causeID, donation.Amount, string(donation.Amount.Currency), models.DonationStatusCompleted,
+		txHash, models.DonationSourceOnchain,
+		donation.BlockNumber, donation.LogIndex, donation.DonorAddress, donation.ChainDonationID.String(),
+		donation.Amount.Minor.String(), string(donation.Amount.Currency),
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already indexed on a previous run
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = insertStatusChange(ctx, tx, r.schema, models.DonationStatusChange{
+		DonationID:      id,
+		ToStatus:        models.DonationStatusCompleted,
+		ActorType:       models.StatusActorIndexer,
+		Reason:          "DonationMade event indexed",
+		TransactionHash: txHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = logDonation(ctx, tx, r.schema, id, models.AuditRecord{
+		Type:      models.AuditDonationCreated,
+		ActorType: models.StatusActorIndexer,
+		Reason:    "DonationMade event indexed",
+	})
+	if err != nil {
+		return err
+	}
+
+	// Only donations in the cause's own currency count toward its raised amount
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.causes
+		SET raised_amount = raised_amount + $1
+		WHERE id = $2 AND currency = $3
+	`, r.schema), donation.Amount, *causeID, string(donation.Amount.Currency))
+	if err != nil {
+		return err
+	}
+
+	return matchDonation(ctx, tx, r.schema, id)
+}
+
+// insertWithdrawal records a withdrawal event, ignoring duplicates
+func (r *ChainRepository) insertWithdrawal(ctx context.Context, tx *sql.Tx, withdrawal models.ChainWithdrawal) error {
+	causeID, err := r.causeIDForCharity(ctx, tx, withdrawal.ChainCharityID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.chain_withdrawals (
+			chain_charity_id, cause_id, wallet_address, amount, currency,
+			transaction_hash, block_number, log_index
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (transaction_hash, log_index) DO NOTHING
+	`, r.schema),
+		withdrawal.ChainCharityID, causeID, withdrawal.WalletAddress,
+		withdrawal.Amount, string(withdrawal.Amount.Currency),
+		strings.ToLower(withdrawal.TransactionHash), withdrawal.BlockNumber, withdrawal.LogIndex,
+	)
+	return err
+}
@@ -0,0 +1,421 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// DisbursementRepository handles database operations for disbursements, the
+// reports of how causes spend their funds
+type DisbursementRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewDisbursementRepository creates a new DisbursementRepository
+func NewDisbursementRepository(db *sql.DB, cfg *config.DatabaseConfig) *DisbursementRepository {
+	return &DisbursementRepository{db: db, schema: cfg.Schema}
+}
+
+// disbursementColumns are the columns selected for a disbursement
+const disbursementColumns = `d.id, d.cause_id, d.withdrawal_id, d.amount, d.currency,
+	d.transaction_hash, d.recipient_wallet, d.purpose, d.spent_at, d.reported_by,
+	d.created_at, d.updated_at`
+
+// scanDisbursement scans a row selected with disbursementColumns
+func scanDisbursement(scan func(dest ...interface{}) error) (models.Disbursement, error) {
+	var d models.Disbursement
+	var amount decimal
+	var withdrawalID, reportedBy sql.NullInt64
+	var transactionHash, recipientWallet sql.NullString
+
+	err := scan(
+		&d.ID, &d.CauseID, &withdrawalID, &amount, &d.Currency,
+		&transactionHash, &recipientWallet, &d.Purpose, &d.SpentAt, &reportedBy,
+		&d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		return d, err
+	}
+	if d.Amount, err = amount.money(d.Currency); err != nil {
+		return d, err
+	}
+
+	if withdrawalID.Valid {
+		id := int(withdrawalID.Int64)
+		d.WithdrawalID = &id
+	}
+	if reportedBy.Valid {
+		id := int(reportedBy.Int64)
+		d.ReportedBy = &id
+	}
+	d.TransactionHash = transactionHash.String
+	d.RecipientWallet = recipientWallet.String
+	d.Documents = []models.DisbursementDocument{}
+	return d, nil
+}
+
+// Create reports a disbursement for a cause. It returns
+// models.ErrExceedsWithdrawal if the disbursements reported against its
+// withdrawal would add up to more than was withdrawn.
+func (r *DisbursementRepository) Create(ctx context.Context, causeID int, reportedBy *int, input models.DisbursementInput) (*models.Disbursement, error) {
+	amount, err := input.Money()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := r.checkWithdrawal(ctx, tx, input.WithdrawalID, 0, amount); err != nil {
+		return nil, err
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.disbursements (
+			cause_id, withdrawal_id, amount, currency, transaction_hash,
+			recipient_wallet, purpose, spent_at, reported_by
+		)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, COALESCE($8, CURRENT_TIMESTAMP), $9)
+		RETURNING id
+	`, r.schema),
+		causeID, input.WithdrawalID, amount, string(amount.Currency), input.TransactionHash,
+		input.RecipientWallet, input.Purpose, input.SpentAt, reportedBy,
+	).Scan(&id)
+	if err != nil {
+		return nil, mapWriteError(err)
+	}
+
+	if err := r.replaceDocuments(ctx, tx, id, input.Documents); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// Update replaces a disbursement and its documents. It returns nil if the
+// disbursement does not exist and models.ErrExceedsWithdrawal like Create.
+func (r *DisbursementRepository) Update(ctx context.Context, id int, input models.DisbursementInput) (*models.Disbursement, error) {
+	amount, err := input.Money()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := r.checkWithdrawal(ctx, tx, input.WithdrawalID, id, amount); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.disbursements
+		SET withdrawal_id = $1, amount = $2, currency = $3,
+			transaction_hash = NULLIF($4, ''), recipient_wallet = NULLIF($5, ''),
+			purpose = $6, spent_at = COALESCE($7, spent_at),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8
+	`, r.schema),
+		input.WithdrawalID, amount, string(amount.Currency), input.TransactionHash,
+		input.RecipientWallet, input.Purpose, input.SpentAt, id,
+	)
+	if err != nil {
+		return nil, mapWriteError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, nil
+	}
+
+	if err := r.replaceDocuments(ctx, tx, id, input.Documents); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// checkWithdrawal checks that amount still fits in what remains unreported
+// of a withdrawal, not counting the disbursement excludeID. The withdrawal is
+// locked until tx ends so that concurrent reports cannot both fit.
+func (r *DisbursementRepository) checkWithdrawal(ctx context.Context, tx *sql.Tx, withdrawalID *int, excludeID int, amount models.Money) error {
+	if withdrawalID == nil {
+		return nil
+	}
+
+	var withdrawn decimal
+	err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT amount FROM %s.chain_withdrawals WHERE id = $1 FOR UPDATE
+	`, r.schema), *withdrawalID).Scan(&withdrawn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidReference
+		}
+		return err
+	}
+
+	var remaining decimal
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT $1::numeric - COALESCE(SUM(amount), 0)
+		FROM %s.disbursements
+		WHERE withdrawal_id = $2 AND id <> $3
+	`, r.schema), string(withdrawn), *withdrawalID, excludeID).Scan(&remaining)
+	if err != nil {
+		return err
+	}
+
+	left, err := remaining.money(amount.Currency)
+	if err != nil {
+		return err
+	}
+	if cmp, err := amount.Cmp(left); err != nil || cmp > 0 {
+		return fmt.Errorf("%w: %s %s of withdrawal %d remain unreported",
+			models.ErrExceedsWithdrawal, left, left.Currency, *withdrawalID)
+	}
+	return nil
+}
+
+// replaceDocuments replaces the documents of a disbursement inside tx
+func (r *DisbursementRepository) replaceDocuments(ctx context.Context, tx *sql.Tx, disbursementID int, documents []models.DisbursementDocumentInput) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		DELETE FROM %s.disbursement_documents WHERE disbursement_id = $1
+	`, r.schema), disbursementID)
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range documents {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %s.disbursement_documents (disbursement_id, name, url, sha256)
+			VALUES ($1, $2, $3, NULLIF(LOWER($4), ''))
+		`, r.schema), disbursementID, doc.Name, doc.URL, doc.SHA256)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByID gets a disbursement and its documents by ID
+func (r *DisbursementRepository) GetByID(ctx context.Context, id int) (*models.Disbursement, error) {
+	d, err := scanDisbursement(r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM %s.disbursements d WHERE d.id = $1
+	`, disbursementColumns, r.schema), id).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	disbursements := []models.Disbursement{d}
+	if err := r.loadDocuments(ctx, disbursements); err != nil {
+		return nil, err
+	}
+	return &disbursements[0], nil
+}
+
+// Delete deletes a disbursement and its documents. It returns ErrNotFound if
+// the disbursement does not exist.
+func (r *DisbursementRepository) Delete(ctx context.Context, id int) error {
+	return execDelete(ctx, r.db, fmt.Sprintf(`
+		DELETE FROM %s.disbursements WHERE id = $1
+	`, r.schema), id)
+}
+
+// disbursementSortColumns maps the disbursement sort keys to their columns
+var disbursementSortColumns = map[string]sortColumn{
+	"spent_at": {"d.spent_at", "timestamp"},
+	"amount":   {"d.amount", "numeric"},
+}
+
+// List gets one page of the disbursements matching filter
+func (r *DisbursementRepository) List(ctx context.Context, filter models.DisbursementFilter) (*models.Page[models.Disbursement], error) {
+	var w where
+	if filter.CauseID != nil {
+		w.add("d.cause_id = " + w.arg(*filter.CauseID))
+	}
+	if filter.WithdrawalID != nil {
+		w.add("d.withdrawal_id = " + w.arg(*filter.WithdrawalID))
+	}
+	if filter.Currency != "" {
+		w.add("d.currency = " + w.arg(string(filter.Currency)))
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*) FROM %s.disbursements d %s
+	`, r.schema, w.String()), w.args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := pageQuery(&w, filter.PageRequest, disbursementSortColumns, "d.id")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM %s.disbursements d %s %s
+	`, disbursementColumns, r.schema, w.String(), order), w.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var disbursements []models.Disbursement
+	for rows.Next() {
+		d, err := scanDisbursement(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		disbursements = append(disbursements, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	disbursements, next := trimPage(disbursements, filter.PageRequest, func(d models.Disbursement) (string, int) {
+		if filter.SortKey == "amount" {
+			return d.Amount.String(), d.ID
+		}
+		return d.SpentAt.Format(time.RFC3339Nano), d.ID
+	})
+	if err := r.loadDocuments(ctx, disbursements); err != nil {
+		return nil, err
+	}
+	return &models.Page[models.Disbursement]{Items: disbursements, NextCursor: next, Total: total}, nil
+}
+
+// loadDocuments fills in the documents of disbursements
+func (r *DisbursementRepository) loadDocuments(ctx context.Context, disbursements []models.Disbursement) error {
+	if len(disbursements) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(disbursements))
+	ids := make([]int64, len(disbursements))
+	for i, d := range disbursements {
+		index[d.ID] = i
+		ids[i] = int64(d.ID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, disbursement_id, name, url, sha256
+		FROM %s.disbursement_documents
+		WHERE disbursement_id = ANY($1)
+		ORDER BY id
+	`, r.schema), pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc models.DisbursementDocument
+		var disbursementID int
+		var sha256 sql.NullString
+		if err := rows.Scan(&doc.ID, &disbursementID, &doc.Name, &doc.URL, &sha256); err != nil {
+			return err
+		}
+		doc.SHA256 = sha256.String
+
+		d := &disbursements[index[disbursementID]]
+		d.Documents = append(d.Documents, doc)
+	}
+
+	return rows.Err()
+}
+
+// Funds compares what a cause raised from completed donations, withdrew
+// on-chain and reported spent, per currency. The cause's own currency is
+// always included.
+func (r *DisbursementRepository) Funds(ctx context.Context, causeID int) (*models.CauseFundsSummary, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		WITH raised AS (
+			SELECT currency, SUM(amount) AS total
+			FROM %[1]s.donations
+			WHERE cause_id = $1 AND status = $2
+			GROUP BY currency
+		),
+		withdrawn AS (
+			SELECT currency, SUM(amount) AS total
+			FROM %[1]s.chain_withdrawals
+			WHERE cause_id = $1
+			GROUP BY currency
+		),
+		spent AS (
+			SELECT currency, SUM(amount) AS total,
+				SUM(amount) FILTER (WHERE withdrawal_id IS NOT NULL) AS reported
+			FROM %[1]s.disbursements
+			WHERE cause_id = $1
+			GROUP BY currency
+		),
+		currencies AS (
+			SELECT currency FROM %[1]s.causes WHERE id = $1
+			UNION SELECT currency FROM raised
+			UNION SELECT currency FROM withdrawn
+			UNION SELECT currency FROM spent
+		)
+		SELECT c.currency, COALESCE(r.total, 0), COALESCE(w.total, 0), COALESCE(s.total, 0),
+			COALESCE(w.total, 0) - COALESCE(s.reported, 0)
+		FROM currencies c
+		LEFT JOIN raised r ON r.currency = c.currency
+		LEFT JOIN withdrawn w ON w.currency = c.currency
+		LEFT JOIN spent s ON s.currency = c.currency
+		ORDER BY c.currency
+	`, r.schema), causeID, models.DonationStatusCompleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := &models.CauseFundsSummary{CauseID: causeID, Currencies: []models.CauseFunds{}}
+	for rows.Next() {
+		var f models.CauseFunds
+		var raised, withdrawn, spent, unreported decimal
+		if err := rows.Scan(&f.Currency, &raised, &withdrawn, &spent, &unreported); err != nil {
+			return nil, err
+		}
+		if f.Raised, err = raised.money(f.Currency); err != nil {
+			return nil, err
+		}
+		if f.Withdrawn, err = withdrawn.money(f.Currency); err != nil {
+			return nil, err
+		}
+		if f.Spent, err = spent.money(f.Currency); err != nil {
+			return nil, err
+		}
+		if f.Unreported, err = unreported.money(f.Currency); err != nil {
+			return nil, err
+		}
+		summary.Currencies = append(summary.Currencies, f)
+	}
+
+	return summary, rows.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ombima56/transpacharity/internal/config"
 	"github.com/ombima56/transpacharity/internal/models"
@@ -21,60 +22,134 @@ func NewDonationRepository(db *sql.DB, cfg *config.DatabaseConfig) *DonationRepo
 	return &DonationRepository{db: db, schema: cfg.Schema}
 }
 
-// Create creates a new donation
+// Create creates a new pending donation and records it in the audit log
 func (r *DonationRepository) Create(ctx context.Context, input models.DonationInput) (models.Donation, error) {
+	amount, err := input.Money()
+	if err != nil {
+		return models.Donation{}, err
+	}
+
 	query := fmt.Sprintf(`
 		INSERT INTO %s.donations (
-			user_id, cause_id, amount, is_anonymous, status
+			user_id, cause_id, amount, currency, is_anonymous, status
 		)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, user_id, cause_id, amount, is_anonymous, 
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, user_id, cause_id, amount, currency, is_anonymous, 
 			status, transaction_id, created_at, updated_at
 	`, r.schema)
 	
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Donation{}, err
+	}
+	defer tx.Rollback()
+
 	var donation models.Donation
+	var storedAmount decimal
 	var transactionID sql.NullString
 	
-	err := r.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx, 
 		query, 
-		input.UserID, input.CauseID, input.Amount, input.IsAnonymous, models.DonationStatusPending,
+		input.UserID, input.CauseID, amount, string(amount.Currency), input.IsAnonymous, models.DonationStatusPending,
 	).Scan(
 		&donation.ID, &donation.UserID, &donation.CauseID, 
-		&donation.Amount, &donation.IsAnonymous, &donation.Status, 
+		&storedAmount, &donation.Currency, &donation.IsAnonymous, &donation.Status, 
 		&transactionID, &donation.CreatedAt, &donation.UpdatedAt,
 	)
+	if err != nil {
+		return donation, mapWriteError(err)
+	}
+	if donation.Amount, err = storedAmount.money(donation.Currency); err != nil {
+		return donation, err
+	}
 	
 	if transactionID.Valid {
 		donation.TransactionID = transactionID.String
 	}
-	
-	// Update the raised amount for the cause
-	if err == nil {
-		updateQuery := fmt.Sprintf(`
-			UPDATE %s.causes
-			SET raised_amount = raised_amount + $1
-			WHERE id = $2
-		`, r.schema)
-		_, err = r.db.ExecContext(ctx, updateQuery, input.Amount, input.CauseID)
+
+	record := models.AuditRecord{Type: models.AuditDonationCreated, ActorType: models.StatusActorSystem}
+	if input.UserID != nil {
+		record.ActorType, record.ActorID = models.StatusActorUser, input.UserID
+	}
+	if err := logDonation(ctx, tx, r.schema, donation.ID, record); err != nil {
+		return donation, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return donation, err
 	}
 	
-	return donation, err
+	// The cause's raised amount only grows once the donation is completed
+	return donation, nil
+}
+
+// donationSortColumns maps the donation sort keys to their columns
+var donationSortColumns = map[string]sortColumn{
+	"created_at": {"d.created_at", "timestamp"},
+	"amount":     {"d.amount", "numeric"},
 }
 
-// GetAll gets all donations
-func (r *DonationRepository) GetAll(ctx context.Context) ([]models.Donation, error) {
+// donationConditions returns the conditions selecting the donations matching
+// filter, ignoring its page
+func donationConditions(filter models.DonationFilter) where {
+	var w where
+	if filter.CauseID != nil {
+		w.add("d.cause_id = " + w.arg(*filter.CauseID))
+	}
+	if filter.UserID != nil {
+		w.add("d.user_id = " + w.arg(*filter.UserID))
+	}
+	if filter.Status != "" {
+		w.add("d.status = " + w.arg(string(filter.Status)))
+	}
+	if filter.Currency != "" {
+		w.add("d.currency = " + w.arg(string(filter.Currency)))
+	}
+	if filter.MinAmount != "" {
+		w.add("d.amount >= " + w.arg(filter.MinAmount) + "::numeric")
+	}
+	if filter.MaxAmount != "" {
+		w.add("d.amount <= " + w.arg(filter.MaxAmount) + "::numeric")
+	}
+	if filter.From != nil {
+		w.add("d.created_at >= " + w.arg(*filter.From))
+	}
+	if filter.To != nil {
+		w.add("d.created_at < " + w.arg(*filter.To))
+	}
+	return w
+}
+
+// List gets one page of the donations matching filter
+func (r *DonationRepository) List(ctx context.Context, filter models.DonationFilter) (*models.Page[models.Donation], error) {
+	w := donationConditions(filter)
+
+	var total int
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*) FROM %s.donations d %s
+	`, r.schema, w.String()), w.args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := pageQuery(&w, filter.PageRequest, donationSortColumns, "d.id")
+	if err != nil {
+		return nil, err
+	}
+
 	query := fmt.Sprintf(`
-		SELECT d.id, d.user_id, d.cause_id, d.amount, d.is_anonymous, 
-			d.status, d.transaction_id, d.created_at, d.updated_at,
-			u.name as user_name, c.title as cause_title
+		SELECT d.id, d.user_id, d.cause_id, d.amount, d.currency, d.is_anonymous, 
+			d.status, d.transaction_id, d.transaction_hash, d.created_at, d.updated_at,
+			u.name as user_name, c.title as cause_title, c.organization as cause_organization
 		FROM %s.donations d
 		LEFT JOIN %s.users u ON d.user_id = u.id
 		LEFT JOIN %s.causes c ON d.cause_id = c.id
-		ORDER BY d.created_at DESC
-	`, r.schema, r.schema, r.schema)
+		%s
+		%s
+	`, r.schema, r.schema, r.schema, w.String(), order)
 	
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, w.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -83,39 +158,120 @@ func (r *DonationRepository) GetAll(ctx context.Context) ([]models.Donation, err
 	var donations []models.Donation
 	for rows.Next() {
 		var d models.Donation
-		var userName, causeTitle, transactionID sql.NullString
+		var amount decimal
+		var userID sql.NullInt64
+		var transactionID, transactionHash, userName, causeTitle, causeOrganization sql.NullString
 		
 		if err := rows.Scan(
-			&d.ID, &d.UserID, &d.CauseID, &d.Amount, &d.IsAnonymous,
-			&d.Status, &transactionID, &d.CreatedAt, &d.UpdatedAt,
-			&userName, &causeTitle,
+			&d.ID, &userID, &d.CauseID, &amount, &d.Currency, &d.IsAnonymous,
+			&d.Status, &transactionID, &transactionHash, &d.CreatedAt, &d.UpdatedAt,
+			&userName, &causeTitle, &causeOrganization,
 		); err != nil {
 			return nil, err
 		}
-		
-		if transactionID.Valid {
-			d.TransactionID = transactionID.String
-		}
-		
-		if userName.Valid {
-			d.UserName = userName.String
+		if d.Amount, err = amount.money(d.Currency); err != nil {
+			return nil, err
 		}
 		
-		if causeTitle.Valid {
-			d.CauseTitle = causeTitle.String
+		if userID.Valid {
+			id := int(userID.Int64)
+			d.UserID = &id
 		}
+		d.TransactionID = transactionID.String
+		d.TransactionHash = transactionHash.String
+		d.UserName = userName.String
+		d.CauseTitle = causeTitle.String
+		d.CauseOrganization = causeOrganization.String
 		
 		donations = append(donations, d)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	
-	return donations, nil
+	donations, next := trimPage(donations, filter.PageRequest, func(d models.Donation) (string, int) {
+		if filter.SortKey == "amount" {
+			return d.Amount.String(), d.ID
+		}
+		return d.CreatedAt.Format(time.RFC3339Nano), d.ID
+	})
+	return &models.Page[models.Donation]{Items: donations, NextCursor: next, Total: total}, nil
+}
+
+// Export calls fn with each donation matching filter, in the filter's sort
+// order. Its limit and cursor are ignored, so every matching donation is
+// exported. Rows are read from the database as fn consumes them rather than
+// loaded up front, and an error from fn stops the export.
+func (r *DonationRepository) Export(ctx context.Context, filter models.DonationFilter, fn func(models.Donation) error) error {
+	w := donationConditions(filter)
+	order, err := sortQuery(filter.PageRequest, donationSortColumns, "d.id")
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT d.id, d.user_id, d.cause_id, d.amount, d.currency, d.is_anonymous,
+			d.status, d.transaction_id, d.transaction_hash, d.source,
+			d.block_number, d.donor_address, d.created_at, d.updated_at,
+			u.name as user_name, c.title as cause_title, c.organization as cause_organization
+		FROM %[1]s.donations d
+		LEFT JOIN %[1]s.users u ON d.user_id = u.id
+		LEFT JOIN %[1]s.causes c ON d.cause_id = c.id
+		%[2]s
+		%[3]s
+	`, r.schema, w.String(), order)
+
+	rows, err := r.db.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.Donation
+		var amount decimal
+		var userID, blockNumber sql.NullInt64
+		var transactionID, transactionHash, donorAddress, userName, causeTitle, causeOrganization sql.NullString
+
+		if err := rows.Scan(
+			&d.ID, &userID, &d.CauseID, &amount, &d.Currency, &d.IsAnonymous,
+			&d.Status, &transactionID, &transactionHash, &d.Source,
+			&blockNumber, &donorAddress, &d.CreatedAt, &d.UpdatedAt,
+			&userName, &causeTitle, &causeOrganization,
+		); err != nil {
+			return err
+		}
+		if d.Amount, err = amount.money(d.Currency); err != nil {
+			return err
+		}
+
+		if userID.Valid {
+			id := int(userID.Int64)
+			d.UserID = &id
+		}
+		if blockNumber.Valid {
+			d.BlockNumber = &blockNumber.Int64
+		}
+		d.TransactionID = transactionID.String
+		d.TransactionHash = transactionHash.String
+		d.DonorAddress = donorAddress.String
+		d.UserName = userName.String
+		d.CauseTitle = causeTitle.String
+		d.CauseOrganization = causeOrganization.String
+
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 // GetByID gets a donation by ID
 func (r *DonationRepository) GetByID(ctx context.Context, id int) (*models.Donation, error) {
 	query := fmt.Sprintf(`
-		SELECT d.id, d.user_id, d.cause_id, d.amount, d.is_anonymous, 
-			d.status, d.transaction_id, d.created_at, d.updated_at,
+		SELECT d.id, d.user_id, d.cause_id, d.amount, d.currency, d.is_anonymous, 
+			d.status, d.transaction_id, d.transaction_hash, d.source,
+			d.block_number, d.donor_address, d.created_at, d.updated_at,
 			u.name as user_name, c.title as cause_title
 		FROM %s.donations d
 		LEFT JOIN %s.users u ON d.user_id = u.id
@@ -124,12 +280,14 @@ func (r *DonationRepository) GetByID(ctx context.Context, id int) (*models.Donat
 	`, r.schema, r.schema, r.schema)
 
 	var donation models.Donation
-	var userID sql.NullInt64
-	var transactionID, userName, causeTitle sql.NullString
+	var amount decimal
+	var userID, blockNumber sql.NullInt64
+	var transactionID, transactionHash, donorAddress, userName, causeTitle sql.NullString
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&donation.ID, &userID, &donation.CauseID, &donation.Amount,
+		&donation.ID, &userID, &donation.CauseID, &amount, &donation.Currency,
 		&donation.IsAnonymous, &donation.Status, &transactionID,
+		&transactionHash, &donation.Source, &blockNumber, &donorAddress,
 		&donation.CreatedAt, &donation.UpdatedAt, &userName, &causeTitle,
 	)
 	if err != nil {
@@ -138,6 +296,9 @@ func (r *DonationRepository) GetByID(ctx context.Context, id int) (*models.Donat
 		}
 		return nil, err
 	}
+	if donation.Amount, err = amount.money(donation.Currency); err != nil {
+		return nil, err
+	}
 
 	if userID.Valid {
 		id := int(userID.Int64)
@@ -148,6 +309,18 @@ func (r *DonationRepository) GetByID(ctx context.Context, id int) (*models.Donat
 		donation.TransactionID = transactionID.String
 	}
 
+	if transactionHash.Valid {
+		donation.TransactionHash = transactionHash.String
+	}
+
+	if blockNumber.Valid {
+		donation.BlockNumber = &blockNumber.Int64
+	}
+
+	if donorAddress.Valid {
+		donation.DonorAddress = donorAddress.String
+	}
+
 	if userName.Valid {
 		donation.UserName = userName.String
 	}
@@ -159,100 +332,177 @@ func (r *DonationRepository) GetByID(ctx context.Context, id int) (*models.Donat
 	return &donation, nil
 }
 
-// GetByCauseID gets donations by cause ID
-func (r *DonationRepository) GetByCauseID(ctx context.Context, causeID int) ([]*models.Donation, error) {
-	// First, check if transaction_hash column exists
-	var hasTransactionHash bool
-	err := r.db.QueryRowContext(ctx, `
-		SELECT EXISTS (
-			SELECT 1 
-			FROM information_schema.columns 
-			WHERE table_schema = $1 
-			AND table_name = 'donations' 
-			AND column_name = 'transaction_hash'
-		)
-	`, r.schema).Scan(&hasTransactionHash)
-	
+// UpdateStatus moves a donation to change.ToStatus, records the transition in
+// the status history and the audit log and, when the donation completes, adds
+// it to the cause's raised amount and makes its matching donations, all in one
+// transaction. The donation row is locked for the duration of the check so
+// concurrent confirmations cannot both succeed. It returns nil if the donation
+// does not exist and models.ErrInvalidStatusTransition if the move is not
+// allowed.
+func (r *DonationRepository) UpdateStatus(ctx context.Context, id int, change models.DonationStatusChange) (*models.Donation, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	
-	var query string
-	if hasTransactionHash {
-		query = fmt.Sprintf(`
-			SELECT d.id, d.user_id, d.cause_id, d.amount, d.is_anonymous, 
-				d.status, d.transaction_id, d.transaction_hash, d.created_at, d.updated_at,
-				c.title as cause_title, c.organization as cause_organization,
-				u.name as user_name
-			FROM %s.donations d
-			JOIN %s.causes c ON d.cause_id = c.id
-			LEFT JOIN %s.users u ON d.user_id = u.id
-			WHERE d.cause_id = $1
-			ORDER BY d.created_at DESC
-		`, r.schema, r.schema, r.schema)
-	} else {
-		query = fmt.Sprintf(`
-			SELECT d.id, d.user_id, d.cause_id, d.amount, d.is_anonymous, 
-				d.status, d.transaction_id, d.created_at, d.updated_at,
-				c.title as cause_title, c.organization as cause_organization,
-				u.name as user_name
-			FROM %s.donations d
-			JOIN %s.causes c ON d.cause_id = c.id
-			LEFT JOIN %s.users u ON d.user_id = u.id
-			WHERE d.cause_id = $1
-			ORDER BY d.created_at DESC
-		`, r.schema, r.schema, r.schema)
-	}
+	defer tx.Rollback()
 
-	rows, err := r.db.QueryContext(ctx, query, causeID)
+	var current models.DonationStatus
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT status FROM %s.donations WHERE id = $1 FOR UPDATE
+	`, r.schema), id).Scan(&current)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
 		return nil, err
 	}
-	defer rows.Close()
 
-	var donations []*models.Donation
-	for rows.Next() {
-		var d models.Donation
-		var userName sql.NullString
-		
-		if hasTransactionHash {
-			err = rows.Scan(
-				&d.ID, &d.UserID, &d.CauseID, &d.Amount, &d.IsAnonymous,
-				&d.Status, &d.TransactionID, &d.TransactionHash, &d.CreatedAt, &d.UpdatedAt,
-				&d.CauseTitle, &d.CauseOrganization, &userName,
-			)
-		} else {
-			err = rows.Scan(
-				&d.ID, &d.UserID, &d.CauseID, &d.Amount, &d.IsAnonymous,
-				&d.Status, &d.TransactionID, &d.CreatedAt, &d.UpdatedAt,
-				&d.CauseTitle, &d.CauseOrganization, &userName,
-			)
+	if !current.CanTransitionTo(change.ToStatus) {
+		return nil, fmt.Errorf("%w: %s to %s", models.ErrInvalidStatusTransition, current, change.ToStatus)
+	}
+
+	// A transaction can only ever back a single donation. Two donations
+	// claiming the same transaction at once are caught by
+	// donations_transaction_hash_idx.
+	if change.TransactionHash != "" {
+		claimed, err := transactionClaimed(ctx, tx, r.schema, change.TransactionHash, id)
+		if err != nil {
+			return nil, err
 		}
-		
+		if claimed {
+			return nil, ErrTransactionClaimed
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.donations
+		SET status = $1,
+			transaction_hash = COALESCE(NULLIF($2, ''), transaction_hash),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`, r.schema), change.ToStatus, change.TransactionHash, id)
+	if err != nil {
+		return nil, mapWriteError(err)
+	}
+
+	change.DonationID = id
+	change.FromStatus = &current
+	if err := insertStatusChange(ctx, tx, r.schema, change); err != nil {
+		return nil, err
+	}
+
+	err = logDonation(ctx, tx, r.schema, id, models.AuditRecord{
+		Type:      models.AuditDonationStatusChanged,
+		ActorType: change.ActorType,
+		ActorID:   change.ActorID,
+		Reason:    change.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Completed donations in the cause's currency count towards its raised amount
+	if change.ToStatus == models.DonationStatusCompleted {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.causes c
+			SET raised_amount = c.raised_amount + d.amount, updated_at = CURRENT_TIMESTAMP
+			FROM %s.donations d
+			WHERE d.id = $1 AND c.id = d.cause_id AND c.currency = d.currency
+		`, r.schema, r.schema), id)
 		if err != nil {
 			return nil, err
 		}
-		
-		if userName.Valid {
-			d.UserName = userName.String
-		} else {
-			d.UserName = "Anonymous"
+
+		if err := matchDonation(ctx, tx, r.schema, id); err != nil {
+			return nil, err
 		}
-		
-		donations = append(donations, &d)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
-	return donations, nil
+	return r.GetByID(ctx, id)
+}
+
+// GetStatusHistory gets the status transitions of a donation, oldest first
+func (r *DonationRepository) GetStatusHistory(ctx context.Context, donationID int) ([]models.DonationStatusChange, error) {
+	query := fmt.Sprintf(`
+		SELECT id, donation_id, from_status, to_status, actor_type, actor_id,
+			reason, transaction_hash, created_at
+		FROM %s.donation_status_changes
+		WHERE donation_id = $1
+		ORDER BY created_at, id
+	`, r.schema)
+
+	rows, err := r.db.QueryContext(ctx, query, donationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.DonationStatusChange
+	for rows.Next() {
+		var c models.DonationStatusChange
+		var fromStatus, reason, transactionHash sql.NullString
+		var actorID sql.NullInt64
+
+		if err := rows.Scan(
+			&c.ID, &c.DonationID, &fromStatus, &c.ToStatus, &c.ActorType, &actorID,
+			&reason, &transactionHash, &c.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if fromStatus.Valid {
+			status := models.DonationStatus(fromStatus.String)
+			c.FromStatus = &status
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			c.ActorID = &id
+		}
+		c.Reason = reason.String
+		c.TransactionHash = transactionHash.String
+
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+// transactionClaimed reports whether a donation other than excludeID already
+// references the transaction hash
+func transactionClaimed(ctx context.Context, tx *sql.Tx, schema, txHash string, excludeID int) (bool, error) {
+	var claimed bool
+	err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM %s.donations
+			WHERE LOWER(transaction_hash) = LOWER($1) AND id <> $2
+		)
+	`, schema), txHash, excludeID).Scan(&claimed)
+	return claimed, err
+}
+
+// insertStatusChange records a status transition inside tx
+func insertStatusChange(ctx context.Context, tx *sql.Tx, schema string, change models.DonationStatusChange) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.donation_status_changes (
+			donation_id, from_status, to_status, actor_type, actor_id,
+			reason, transaction_hash
+		)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+	`, schema),
+		change.DonationID, change.FromStatus, change.ToStatus, change.ActorType, change.ActorID,
+		change.Reason, change.TransactionHash,
+	)
+	return err
 }
 
 // GetByUserID gets donations by user ID
 func (r *DonationRepository) GetByUserID(ctx context.Context, userID int) ([]models.Donation, error) {
 	query := fmt.Sprintf(`
-		SELECT d.id, d.user_id, d.cause_id, d.amount, d.is_anonymous, 
+		SELECT d.id, d.user_id, d.cause_id, d.amount, d.currency, d.is_anonymous, 
 			d.status, d.transaction_id, d.created_at, d.updated_at,
 			c.title as cause_title, c.organization as cause_organization
 		FROM %s.donations d
@@ -270,15 +520,19 @@ func (r *DonationRepository) GetByUserID(ctx context.Context, userID int) ([]mod
 	var donations []models.Donation
 	for rows.Next() {
 		var d models.Donation
+		var amount decimal
 		var transactionID sql.NullString
 		
 		if err := rows.Scan(
-			&d.ID, &d.UserID, &d.CauseID, &d.Amount, &d.IsAnonymous,
+			&d.ID, &d.UserID, &d.CauseID, &amount, &d.Currency, &d.IsAnonymous,
 			&d.Status, &transactionID, &d.CreatedAt, &d.UpdatedAt,
 			&d.CauseTitle, &d.CauseOrganization,
 		); err != nil {
 			return nil, err
 		}
+		if d.Amount, err = amount.money(d.Currency); err != nil {
+			return nil, err
+		}
 		
 		if transactionID.Valid {
 			d.TransactionID = transactionID.String
@@ -319,7 +573,7 @@ func (r *DonationRepository) GetRecent(ctx context.Context, limit int) ([]*model
     var query string
     if hasTransactionHash {
         query = fmt.Sprintf(`
-            SELECT d.id, d.user_id, d.cause_id, d.amount, d.is_anonymous, 
+            SELECT d.id, d.user_id, d.cause_id, d.amount, d.currency, d.is_anonymous, 
                 d.status, d.transaction_id, d.transaction_hash, d.created_at, d.updated_at,
                 c.title as cause_title, c.organization as cause_organization,
                 u.name as user_name
@@ -331,7 +585,7 @@ func (r *DonationRepository) GetRecent(ctx context.Context, limit int) ([]*model
         `, r.schema, r.schema, r.schema)
     } else {
         query = fmt.Sprintf(`
-            SELECT d.id, d.user_id, d.cause_id, d.amount, d.is_anonymous, 
+            SELECT d.id, d.user_id, d.cause_id, d.amount, d.currency, d.is_anonymous, 
                 d.status, d.transaction_id, d.created_at, d.updated_at,
                 c.title as cause_title, c.organization as cause_organization,
                 u.name as user_name
@@ -352,12 +606,13 @@ func (r *DonationRepository) GetRecent(ctx context.Context, limit int) ([]*model
     var donations []*models.Donation
     for rows.Next() {
         var d models.Donation
+        var amount decimal
         var userName, transactionID sql.NullString
         
         if hasTransactionHash {
             var transactionHash sql.NullString
             err = rows.Scan(
-                &d.ID, &d.UserID, &d.CauseID, &d.Amount, &d.IsAnonymous,
+                &d.ID, &d.UserID, &d.CauseID, &amount, &d.Currency, &d.IsAnonymous,
                 &d.Status, &transactionID, &transactionHash, &d.CreatedAt, &d.UpdatedAt,
                 &d.CauseTitle, &d.CauseOrganization, &userName,
             )
@@ -366,7 +621,7 @@ func (r *DonationRepository) GetRecent(ctx context.Context, limit int) ([]*model
             }
         } else {
             err = rows.Scan(
-                &d.ID, &d.UserID, &d.CauseID, &d.Amount, &d.IsAnonymous,
+                &d.ID, &d.UserID, &d.CauseID, &amount, &d.Currency, &d.IsAnonymous,
                 &d.Status, &transactionID, &d.CreatedAt, &d.UpdatedAt,
                 &d.CauseTitle, &d.CauseOrganization, &userName,
             )
@@ -375,6 +630,9 @@ func (r *DonationRepository) GetRecent(ctx context.Context, limit int) ([]*model
         if err != nil {
             return nil, err
         }
+        if d.Amount, err = amount.money(d.Currency); err != nil {
+            return nil, err
+        }
         
         if transactionID.Valid {
             d.TransactionID = transactionID.String
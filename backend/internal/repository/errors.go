@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned by writes whose target row does not exist.
+	// Lookups keep returning a nil result instead.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write violates a uniqueness constraint
+	// that has no more specific error
+	ErrConflict = errors.New("conflicts with an existing record")
+	// ErrEmailTaken is returned when an email address belongs to another account
+	ErrEmailTaken = errors.New("email already in use")
+	// ErrCategoryNameTaken is returned when another category has the same name
+	ErrCategoryNameTaken = errors.New("category name already in use")
+	// ErrInUse is returned when deleting a row that other rows still reference
+	ErrInUse = errors.New("record is still in use")
+	// ErrInvalidReference is returned when a write references a row that does
+	// not exist
+	ErrInvalidReference = errors.New("referenced record does not exist")
+	// ErrTransactionClaimed is returned when a transaction hash already backs
+	// another donation
+	ErrTransactionClaimed = errors.New("transaction is already linked to another donation")
+	// ErrInvalidCursor is returned when a page cursor is malformed or was
+	// issued for a different sort
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Postgres error codes
+const (
+	pqForeignKeyViolation = "23503"
+	pqUniqueViolation     = "23505"
+)
+
+// uniqueViolations maps unique constraints to the errors reported for them
+var uniqueViolations = map[string]error{
+	"users_email_key":     ErrEmailTaken,
+	"categories_name_key": ErrCategoryNameTaken,
+
+	"donations_transaction_hash_idx": ErrTransactionClaimed,
+}
+
+// mapWriteError turns constraint violations from an insert or update into
+// repository errors. Other errors are returned unchanged.
+func mapWriteError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case pqUniqueViolation:
+		if mapped, ok := uniqueViolations[pqErr.Constraint]; ok {
+			return mapped
+		}
+		return ErrConflict
+	case pqForeignKeyViolation:
+		return ErrInvalidReference
+	}
+	return err
+}
+
+// mapDeleteError turns a foreign key violation from a delete into ErrInUse.
+// Other errors are returned unchanged.
+func mapDeleteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqForeignKeyViolation {
+		return ErrInUse
+	}
+	return err
+}
+
+// execDelete runs a delete statement for a single row. It returns ErrNotFound
+// if no row was deleted and ErrInUse if other rows still reference it.
+func execDelete(ctx context.Context, db *sql.DB, query string, args ...interface{}) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return mapDeleteError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// IdempotencyRepository handles database operations for idempotency keys
+type IdempotencyRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository
+func NewIdempotencyRepository(db *sql.DB, cfg *config.DatabaseConfig) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db, schema: cfg.Schema}
+}
+
+// Claim reserves key in scope for the request with fingerprint until lock
+// passes, keeping it for ttl. It returns nil if the key was claimed, or the
+// record already stored against the key. A key may be claimed again once it
+// has expired, or if the request holding it never finished.
+func (r *IdempotencyRepository) Claim(ctx context.Context, scope, key, fingerprint string, ttl, lock time.Duration) (*models.IdempotencyRecord, error) {
+	claim := fmt.Sprintf(`
+		INSERT INTO %[1]s.idempotency_keys (scope, key, fingerprint, locked_until, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4), CURRENT_TIMESTAMP + make_interval(secs => $5))
+		ON CONFLICT (scope, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status_code = NULL,
+			content_type = NULL,
+			response_body = NULL,
+			locked_until = EXCLUDED.locked_until,
+			created_at = CURRENT_TIMESTAMP,
+			expires_at = EXCLUDED.expires_at
+		WHERE %[1]s.idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+			OR (%[1]s.idempotency_keys.status_code IS NULL
+				AND %[1]s.idempotency_keys.locked_until <= CURRENT_TIMESTAMP)
+		RETURNING key
+	`, r.schema)
+
+	existing := fmt.Sprintf(`
+		SELECT fingerprint, status_code, content_type, response_body
+		FROM %s.idempotency_keys
+		WHERE scope = $1 AND key = $2
+	`, r.schema)
+
+	// The row we lost the claim to may be released before we read it, in
+	// which case the key is free to claim again
+	for attempt := 0; attempt < 3; attempt++ {
+		var claimed string
+		err := r.db.QueryRowContext(ctx, claim, scope, key, fingerprint, lock.Seconds(), ttl.Seconds()).Scan(&claimed)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		var record models.IdempotencyRecord
+		var status sql.NullInt64
+		var contentType sql.NullString
+		err = r.db.QueryRowContext(ctx, existing, scope, key).Scan(
+			&record.Fingerprint, &status, &contentType, &record.Body,
+		)
+		if err == nil {
+			record.StatusCode = int(status.Int64)
+			record.ContentType = contentType.String
+			return &record, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("claiming idempotency key: %w", ErrConflict)
+}
+
+// Complete stores the response to the request holding key
+func (r *IdempotencyRepository) Complete(ctx context.Context, scope, key string, record models.IdempotencyRecord) error {
+	query := fmt.Sprintf(`
+		UPDATE %s.idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5
+		WHERE scope = $1 AND key = $2 AND fingerprint = $6
+	`, r.schema)
+
+	_, err := r.db.ExecContext(ctx, query, scope, key, record.StatusCode, record.ContentType, record.Body, record.Fingerprint)
+	return err
+}
+
+// Release forgets a key whose request failed, so that it can be retried
+func (r *IdempotencyRepository) Release(ctx context.Context, scope, key, fingerprint string) error {
+	query := fmt.Sprintf(`
+		DELETE FROM %s.idempotency_keys
+		WHERE scope = $1 AND key = $2 AND fingerprint = $3 AND status_code IS NULL
+	`, r.schema)
+
+	_, err := r.db.ExecContext(ctx, query, scope, key, fingerprint)
+	return err
+}
+
+// DeleteExpired deletes expired keys and returns how many were deleted
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := fmt.Sprintf(`
+		DELETE FROM %s.idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP
+	`, r.schema)
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
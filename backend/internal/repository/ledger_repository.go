@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// LedgerRepository reads the public ledger of money moving in and out of
+// causes
+type LedgerRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewLedgerRepository creates a new LedgerRepository
+func NewLedgerRepository(db *sql.DB, cfg *config.DatabaseConfig) *LedgerRepository {
+	return &LedgerRepository{db: db, schema: cfg.Schema}
+}
+
+// ledgerSortColumns maps the ledger sort keys to their columns
+var ledgerSortColumns = map[string]sortColumn{
+	"occurred_at": {"l.occurred_at", "timestamp"},
+}
+
+// ledgerQuery merges completed donations, whether created through the API or
+// indexed from DonationMade events, with indexed withdrawals. Running
+// balances are computed per cause and currency over the whole ledger before
+// any filter applies; withdrawals from charities without a cause are
+// balanced per charity. Entries are ordered by time, type and id, which
+// identifies them for paging; the balances are only for display. Anonymous
+// donors are never named; matching donations are credited to their sponsor.
+// $1 is the completed donation status.
+const ledgerQuery = `
+	WITH entries AS (
+		SELECT 'donation' AS type, d.id, 'in' AS direction, d.cause_id,
+			d.cause_id::text AS account, d.amount, d.currency, d.source,
+			CASE WHEN d.is_anonymous THEN 'Anonymous'
+				ELSE COALESCE(u.name, mp.sponsor_name, d.donor_address, 'Anonymous') END AS donor,
+			NULL::text AS wallet_address, d.transaction_hash, d.block_number,
+			COALESCE(sc.completed_at, d.updated_at) AS occurred_at
+		FROM %[1]s.donations d
+		LEFT JOIN %[1]s.users u ON u.id = d.user_id
+		LEFT JOIN %[1]s.matching_pledges mp ON mp.id = d.matching_pledge_id
+		LEFT JOIN LATERAL (
+			SELECT MAX(created_at) AS completed_at
+			FROM %[1]s.donation_status_changes
+			WHERE donation_id = d.id AND to_status = $1
+		) sc ON TRUE
+		WHERE d.status = $1
+		UNION ALL
+		SELECT 'withdrawal', w.id, 'out', w.cause_id,
+			COALESCE(w.cause_id::text, 'charity:' || w.chain_charity_id), w.amount, w.currency, 'onchain',
+			NULL, w.wallet_address, w.transaction_hash, w.block_number,
+			w.created_at
+		FROM %[1]s.chain_withdrawals w
+	),
+	ledger AS (
+		SELECT e.*,
+			SUM(CASE WHEN e.direction = 'in' THEN e.amount ELSE -e.amount END) OVER (
+				PARTITION BY e.account, e.currency
+				ORDER BY e.occurred_at, e.type, e.id
+			) AS balance
+		FROM entries e
+	)
+`
+
+// List gets one page of the ledger entries matching filter
+func (r *LedgerRepository) List(ctx context.Context, filter models.LedgerFilter) (*models.Page[models.LedgerEntry], error) {
+	var w where
+	w.arg(models.DonationStatusCompleted)
+	if filter.CauseID != nil {
+		w.add("l.cause_id = " + w.arg(*filter.CauseID))
+	}
+	if filter.Currency != "" {
+		w.add("l.currency = " + w.arg(string(filter.Currency)))
+	}
+	if filter.Type != "" {
+		w.add("l.type = " + w.arg(filter.Type))
+	}
+	if filter.From != nil {
+		w.add("l.occurred_at >= " + w.arg(*filter.From))
+	}
+	if filter.To != nil {
+		w.add("l.occurred_at < " + w.arg(*filter.To))
+	}
+
+	with := fmt.Sprintf(ledgerQuery, r.schema)
+
+	var total int
+	err := r.db.QueryRowContext(ctx, with+fmt.Sprintf(`
+		SELECT COUNT(*) FROM ledger l %s
+	`, w.String()), w.args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := ledgerPageQuery(&w, filter.PageRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, with+fmt.Sprintf(`
+		SELECT l.type, l.id, l.direction, l.cause_id, c.title, l.amount, l.currency,
+			l.balance, l.source, l.donor, l.wallet_address, l.transaction_hash,
+			l.block_number, l.occurred_at
+		FROM ledger l
+		LEFT JOIN %s.causes c ON c.id = l.cause_id
+		%s
+		%s
+	`, r.schema, w.String(), order), w.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.LedgerEntry
+	for rows.Next() {
+		var e models.LedgerEntry
+		var amount, balance decimal
+		var causeID, blockNumber sql.NullInt64
+		var causeTitle, donor, walletAddress, transactionHash sql.NullString
+
+		if err := rows.Scan(
+			&e.Type, &e.ID, &e.Direction, &causeID, &causeTitle, &amount, &e.Currency,
+			&balance, &e.Source, &donor, &walletAddress, &transactionHash,
+			&blockNumber, &e.OccurredAt,
+		); err != nil {
+			return nil, err
+		}
+		if e.Amount, err = amount.money(e.Currency); err != nil {
+			return nil, err
+		}
+		if e.Balance, err = balance.money(e.Currency); err != nil {
+			return nil, err
+		}
+
+		if causeID.Valid {
+			id := int(causeID.Int64)
+			e.CauseID = &id
+		}
+		if blockNumber.Valid {
+			e.BlockNumber = &blockNumber.Int64
+		}
+		e.CauseTitle = causeTitle.String
+		e.Donor = donor.String
+		e.WalletAddress = walletAddress.String
+		e.TransactionHash = transactionHash.String
+
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Like trimPage, but the cursor records the type of the last entry too
+	var next string
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+		last := entries[len(entries)-1]
+		next = cursor{
+			Sort:  filter.Sort(),
+			Value: last.OccurredAt.Format(time.RFC3339Nano),
+			Kind:  last.Type,
+			ID:    last.ID,
+		}.encode()
+	}
+	return &models.Page[models.LedgerEntry]{Items: entries, NextCursor: next, Total: total}, nil
+}
+
+// ledgerPageQuery is pageQuery for the ledger, whose entries are identified by
+// their type and id since donations and withdrawals may share ids
+func ledgerPageQuery(w *where, page models.PageRequest) (string, error) {
+	sort, ok := ledgerSortColumns[page.SortKey]
+	if !ok {
+		return "", fmt.Errorf("unknown sort key %q", page.SortKey)
+	}
+	direction, compare := "ASC", ">"
+	if page.Descending {
+		direction, compare = "DESC", "<"
+	}
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor, page.Sort(), sort)
+		if err != nil {
+			return "", err
+		}
+		if c.Kind != models.LedgerEntryDonation && c.Kind != models.LedgerEntryWithdrawal {
+			return "", ErrInvalidCursor
+		}
+		w.add(fmt.Sprintf("(%s, l.type, l.id) %s (%s::%s, %s, %s)",
+			sort.column, compare, w.arg(c.Value), sort.cast, w.arg(c.Kind), w.arg(c.ID)))
+	}
+
+	return fmt.Sprintf("ORDER BY %[1]s %[2]s, l.type %[2]s, l.id %[2]s LIMIT %[3]d",
+		sort.column, direction, page.Limit+1), nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+func TestLedgerPageQuery(t *testing.T) {
+	page := models.PageRequest{Limit: 20, SortKey: "occurred_at", Descending: true}
+	order, err := ledgerPageQuery(&where{}, page)
+	if err != nil {
+		t.Fatalf("ledgerPageQuery: %v", err)
+	}
+	if order != "ORDER BY l.occurred_at DESC, l.type DESC, l.id DESC LIMIT 21" {
+		t.Errorf("order = %q", order)
+	}
+
+	// The cursor resumes after the entry with the same time, type and id,
+	// wherever that entry now is in the ledger
+	page.Cursor = cursor{Sort: page.Sort(), Value: "2026-03-01T12:00:00Z", Kind: models.LedgerEntryWithdrawal, ID: 7}.encode()
+	var w where
+	if _, err := ledgerPageQuery(&w, page); err != nil {
+		t.Fatalf("ledgerPageQuery: %v", err)
+	}
+	if got := w.String(); got != "WHERE (l.occurred_at, l.type, l.id) < ($1::timestamp, $2, $3)" {
+		t.Errorf("where = %q", got)
+	}
+	if len(w.args) != 3 || w.args[1] != models.LedgerEntryWithdrawal || w.args[2] != 7 {
+		t.Errorf("args = %v", w.args)
+	}
+
+	for _, kind := range []string{"", "transfer"} {
+		page.Cursor = cursor{Sort: page.Sort(), Value: "2026-03-01T12:00:00Z", Kind: kind, ID: 7}.encode()
+		if _, err := ledgerPageQuery(&where{}, page); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor of kind %q: %v, want ErrInvalidCursor", kind, err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// sortColumn is a column a list may be ordered by. cast is the SQL type the
+// cursor value is converted to before it is compared with the column.
+type sortColumn struct {
+	column string
+	cast   string
+}
+
+// cursor marks the last row of a page. It records the sort it was issued for
+// so that it cannot be replayed against a different order. Kind tells rows
+// of lists that merge several tables apart, as IDs may repeat between them.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Kind  string `json:"k,omitempty"`
+	ID    int    `json:"id"`
+}
+
+// encode returns the opaque form of the cursor
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// encodeCursor returns the opaque cursor for the row with the given sort value
+func encodeCursor(sort, value string, id int) string {
+	return cursor{Sort: sort, Value: value, ID: id}.encode()
+}
+
+// decodeCursor parses a cursor issued for sort on column. The value must be
+// valid for the column's cast, so that a forged cursor is rejected here
+// rather than failing the query.
+func decodeCursor(s, sort string, column sortColumn) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort || !validCursorValue(c.Value, column.cast) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// decimalPattern matches the numbers cursors are issued with: plain decimals
+// for numeric columns, and Go's shortest float format for real ones
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// validCursorValue reports whether value can be cast to the SQL type cast
+func validCursorValue(value, cast string) bool {
+	switch cast {
+	case "timestamp", "timestamptz":
+		_, err := time.Parse(time.RFC3339Nano, value)
+		return err == nil
+	case "numeric", "real":
+		return decimalPattern.MatchString(value)
+	case "text":
+		return !strings.ContainsRune(value, 0)
+	}
+	return false
+}
+
+// where collects the conditions and arguments of a list query
+type where struct {
+	conds []string
+	args  []interface{}
+}
+
+// arg adds an argument and returns its placeholder
+func (w *where) arg(v interface{}) string {
+	w.args = append(w.args, v)
+	return fmt.Sprintf("$%d", len(w.args))
+}
+
+// add adds a condition. Use arg to build its placeholders.
+func (w *where) add(cond string) {
+	w.conds = append(w.conds, cond)
+}
+
+// String returns the WHERE clause, or "" if there are no conditions
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// pageQuery adds the keyset condition for page to w and returns the ORDER BY
+// and LIMIT clauses. One extra row is fetched to tell whether another page
+// follows. The id column breaks ties between equal sort values.
+func pageQuery(w *where, page models.PageRequest, columns map[string]sortColumn, idColumn string) (string, error) {
+	order, err := sortQuery(page, columns, idColumn)
+	if err != nil {
+		return "", err
+	}
+
+	if page.Cursor != "" {
+		sort, compare := columns[page.SortKey], ">"
+		c, err := decodeCursor(page.Cursor, page.Sort(), sort)
+		if err != nil {
+			return "", err
+		}
+		if page.Descending {
+			compare = "<"
+		}
+		w.add(fmt.Sprintf("(%s, %s) %s (%s::%s, %s)",
+			sort.column, idColumn, compare, w.arg(c.Value), sort.cast, w.arg(c.ID)))
+	}
+
+	return fmt.Sprintf("%s LIMIT %d", order, page.Limit+1), nil
+}
+
+// sortQuery returns the ORDER BY clause for page's sort, ignoring its limit
+// and cursor. The id column breaks ties between equal sort values.
+func sortQuery(page models.PageRequest, columns map[string]sortColumn, idColumn string) (string, error) {
+	sort, ok := columns[page.SortKey]
+	if !ok {
+		return "", fmt.Errorf("unknown sort key %q", page.SortKey)
+	}
+
+	direction := "ASC"
+	if page.Descending {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s, %s %s", sort.column, direction, idColumn, direction), nil
+}
+
+// trimPage drops the extra row fetched by pageQuery and returns the cursor
+// for the next page, or "" if items is the last page
+func trimPage[T any](items []T, page models.PageRequest, last func(T) (string, int)) ([]T, string) {
+	if len(items) <= page.Limit {
+		return items, ""
+	}
+	items = items[:page.Limit]
+	value, id := last(items[len(items)-1])
+	return items, encodeCursor(page.Sort(), value, id)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	for _, tt := range []struct {
+		key   string
+		value string
+		valid bool
+	}{
+		{"created_at", "2026-03-01T12:00:00.123456Z", true},
+		{"created_at", "2026-03-01T12:00:00+03:00", true},
+		{"created_at", "2026-03-01", false},
+		{"created_at", "now", false},
+		{"created_at", "'; DROP TABLE donations; --", false},
+		{"goal_amount", "25.50", true},
+		{"goal_amount", "-1.00", true},
+		{"goal_amount", "100", true},
+		{"goal_amount", "NaN", false},
+		{"goal_amount", "1/3", false},
+		{"goal_amount", "25.50 ", false},
+		{"goal_amount", "", false},
+		{"rank", "0.06079271", true},
+		{"rank", "1e-05", true},
+		{"rank", "Infinity", false},
+		{"rank", "0x1p-2", false},
+		{"title", "Clean water", true},
+		{"title", "", true},
+		{"title", "nul\x00byte", false},
+	} {
+		column := causeSortColumns[tt.key]
+		if tt.key == "rank" {
+			column = searchSortColumns[tt.key]
+		}
+		s := encodeCursor(tt.key, tt.value, 7)
+		c, err := decodeCursor(s, tt.key, column)
+		if tt.valid && (err != nil || c.Value != tt.value || c.ID != 7) {
+			t.Errorf("%s cursor %q = %+v, %v; want it decoded", tt.key, tt.value, c, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s cursor %q = %+v, %v; want ErrInvalidCursor", tt.key, tt.value, c, err)
+		}
+	}
+
+	column := causeSortColumns["created_at"]
+	for _, s := range []string{"not base64!", "bm90IGpzb24", encodeCursor("-created_at", "2026-03-01T12:00:00Z", 1)} {
+		if _, err := decodeCursor(s, "created_at", column); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q) = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestPageQuery(t *testing.T) {
+	page := models.PageRequest{Limit: 20, SortKey: "amount", Descending: true}
+	page.Cursor = encodeCursor(page.Sort(), "25.50", 9)
+
+	var w where
+	order, err := pageQuery(&w, page, donationSortColumns, "d.id")
+	if err != nil {
+		t.Fatalf("pageQuery: %v", err)
+	}
+	if order != "ORDER BY d.amount DESC, d.id DESC LIMIT 21" {
+		t.Errorf("order = %q", order)
+	}
+	if got := w.String(); got != "WHERE (d.amount, d.id) < ($1::numeric, $2)" {
+		t.Errorf("where = %q", got)
+	}
+	if len(w.args) != 2 || w.args[0] != "25.50" || w.args[1] != 9 {
+		t.Errorf("args = %v", w.args)
+	}
+
+	page.Cursor = encodeCursor(page.Sort(), "2026-03-01T12:00:00Z", 9)
+	if _, err := pageQuery(&where{}, page, donationSortColumns, "d.id"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("timestamp cursor for an amount sort: %v, want ErrInvalidCursor", err)
+	}
+
+	page.SortKey = "title"
+	if _, err := pageQuery(&where{}, page, donationSortColumns, "d.id"); err == nil || !strings.Contains(err.Error(), "title") {
+		t.Errorf("unknown sort key: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// LoginAttemptRepository handles database operations for the login audit log
+type LoginAttemptRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewLoginAttemptRepository creates a new LoginAttemptRepository
+func NewLoginAttemptRepository(db *sql.DB, cfg *config.DatabaseConfig) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db, schema: cfg.Schema}
+}
+
+// Record stores a login attempt
+func (r *LoginAttemptRepository) Record(ctx context.Context, attempt models.LoginAttempt) error {
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.login_attempts (email, user_id, ip_address, user_agent, result)
+		VALUES ($1, $2, $3, $4, $5)
+	`, r.schema), attempt.Email, attempt.UserID, attempt.IPAddress, attempt.UserAgent, attempt.Result)
+	return err
+}
@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// MatchingPledgeRepository handles database operations for matching pledges
+type MatchingPledgeRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewMatchingPledgeRepository creates a new MatchingPledgeRepository
+func NewMatchingPledgeRepository(db *sql.DB, cfg *config.DatabaseConfig) *MatchingPledgeRepository {
+	return &MatchingPledgeRepository{db: db, schema: cfg.Schema}
+}
+
+// pledgeColumns are the columns selected for a matching pledge
+const pledgeColumns = `p.id, p.cause_id, p.sponsor_name, p.currency, p.cap, p.matched_amount,
+	p.starts_at, p.ends_at, p.closed_at, p.created_by, p.created_at, p.updated_at`
+
+// scanPledge scans a row selected with pledgeColumns
+func scanPledge(scan func(dest ...interface{}) error) (models.MatchingPledge, error) {
+	var p models.MatchingPledge
+	var pledgeCap, matched decimal
+	var closedAt sql.NullTime
+	var createdBy sql.NullInt64
+
+	err := scan(
+		&p.ID, &p.CauseID, &p.SponsorName, &p.Currency, &pledgeCap, &matched,
+		&p.StartsAt, &p.EndsAt, &closedAt, &createdBy, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return p, err
+	}
+	if p.Cap, err = pledgeCap.money(p.Currency); err != nil {
+		return p, err
+	}
+	if p.MatchedAmount, err = matched.money(p.Currency); err != nil {
+		return p, err
+	}
+	if p.Remaining, err = p.Cap.Sub(p.MatchedAmount); err != nil {
+		return p, err
+	}
+
+	if closedAt.Valid {
+		p.ClosedAt = &closedAt.Time
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		p.CreatedBy = &id
+	}
+	p.Status = p.PledgeStatus(time.Now())
+	return p, nil
+}
+
+// Create creates a matching pledge for a cause. It starts at input.StartsAt,
+// or now if that is not set.
+func (r *MatchingPledgeRepository) Create(ctx context.Context, causeID int, createdBy *int, input models.MatchingPledgeInput) (*models.MatchingPledge, error) {
+	pledgeCap, err := input.CapMoney()
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+	err = r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.matching_pledges (
+			cause_id, sponsor_name, currency, cap, starts_at, ends_at, created_by
+		)
+		VALUES ($1, $2, $3, $4, COALESCE($5, CURRENT_TIMESTAMP), $6, $7)
+		RETURNING id
+	`, r.schema),
+		causeID, input.SponsorName, string(pledgeCap.Currency), pledgeCap,
+		input.StartsAt, input.EndsAt, createdBy,
+	).Scan(&id)
+	if err != nil {
+		return nil, mapWriteError(err)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// GetByID gets a matching pledge by ID
+func (r *MatchingPledgeRepository) GetByID(ctx context.Context, id int) (*models.MatchingPledge, error) {
+	p, err := scanPledge(r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM %s.matching_pledges p WHERE p.id = $1
+	`, pledgeColumns, r.schema), id).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetByCauseID gets the matching pledges of a cause, oldest first
+func (r *MatchingPledgeRepository) GetByCauseID(ctx context.Context, causeID int) ([]models.MatchingPledge, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM %s.matching_pledges p
+		WHERE p.cause_id = $1
+		ORDER BY p.starts_at, p.id
+	`, pledgeColumns, r.schema), causeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pledges := []models.MatchingPledge{}
+	for rows.Next() {
+		p, err := scanPledge(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		pledges = append(pledges, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pledges, nil
+}
+
+// MatchedAmount gets the total of a cause's completed matching donations in
+// currency
+func (r *MatchingPledgeRepository) MatchedAmount(ctx context.Context, causeID int, currency models.Currency) (models.Money, error) {
+	var total decimal
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(SUM(amount), 0)
+		FROM %s.donations
+		WHERE cause_id = $1 AND currency = $2 AND source = $3 AND status = $4
+	`, r.schema), causeID, string(currency), models.DonationSourceMatching, models.DonationStatusCompleted).Scan(&total)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return total.money(currency)
+}
+
+// Close stops a matching pledge from matching further donations. The matches
+// it already made are kept. It returns nil if the pledge does not exist and
+// models.ErrPledgeClosed if it was already closed.
+func (r *MatchingPledgeRepository) Close(ctx context.Context, id int) (*models.MatchingPledge, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var closedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT closed_at FROM %s.matching_pledges WHERE id = $1 FOR UPDATE
+	`, r.schema), id).Scan(&closedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if closedAt.Valid {
+		return nil, models.ErrPledgeClosed
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.matching_pledges
+		SET closed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, r.schema), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// matchDonation makes the matching donations of a donation that has just
+// completed, inside tx. Every open pledge of the donation's cause in its
+// currency matches it once, for the donation's amount or what remains of the
+// pledge's cap if that is less. The pledges are locked until tx ends so that
+// concurrent completions cannot overrun a cap. Matching donations are
+// completed, count toward the cause's raised amount like any other, and are
+// not matched themselves.
+func matchDonation(ctx context.Context, tx *sql.Tx, schema string, donationID int) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT p.id
+		FROM %s.donations d
+		JOIN %s.matching_pledges p ON p.cause_id = d.cause_id AND p.currency = d.currency
+		WHERE d.id = $1 AND d.status = $2 AND d.matching_pledge_id IS NULL
+			AND p.closed_at IS NULL AND p.matched_amount < p.cap
+			AND p.starts_at <= CURRENT_TIMESTAMP AND p.ends_at > CURRENT_TIMESTAMP
+		ORDER BY p.id
+		FOR UPDATE OF p
+	`, schema, schema), donationID, models.DonationStatusCompleted)
+	if err != nil {
+		return err
+	}
+	var pledgeIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		pledgeIDs = append(pledgeIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, pledgeID := range pledgeIDs {
+		var matchID int
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+			INSERT INTO %s.donations (
+				cause_id, amount, currency, is_anonymous, status, source,
+				matching_pledge_id, matched_donation_id
+			)
+			SELECT d.cause_id, LEAST(d.amount, p.cap - p.matched_amount), d.currency,
+				FALSE, $1, $2, p.id, d.id
+			FROM %s.donations d, %s.matching_pledges p
+			WHERE d.id = $3 AND p.id = $4
+			ON CONFLICT (matching_pledge_id, matched_donation_id)
+				WHERE matching_pledge_id IS NOT NULL
+				DO NOTHING
+			RETURNING id
+		`, schema, schema, schema),
+			models.DonationStatusCompleted, models.DonationSourceMatching, donationID, pledgeID,
+		).Scan(&matchID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Already matched by this pledge
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.matching_pledges p
+			SET matched_amount = p.matched_amount + d.amount, updated_at = CURRENT_TIMESTAMP
+			FROM %s.donations d
+			WHERE p.id = $1 AND d.id = $2
+		`, schema, schema), pledgeID, matchID)
+		if err != nil {
+			return err
+		}
+
+		reason := fmt.Sprintf("Matching pledge %d matched donation %d", pledgeID, donationID)
+		err = insertStatusChange(ctx, tx, schema, models.DonationStatusChange{
+			DonationID: matchID,
+			ToStatus:   models.DonationStatusCompleted,
+			ActorType:  models.StatusActorSystem,
+			Reason:     reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = logDonation(ctx, tx, schema, matchID, models.AuditRecord{
+			Type:      models.AuditDonationCreated,
+			ActorType: models.StatusActorSystem,
+			Reason:    reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.causes c
+			SET raised_amount = c.raised_amount + d.amount, updated_at = CURRENT_TIMESTAMP
+			FROM %s.donations d
+			WHERE d.id = $1 AND c.id = d.cause_id AND c.currency = d.currency
+		`, schema, schema), matchID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmatchDonations removes the matching donations of the donations selected
+// by donationsQuery inside tx, returning their amounts to the pledges and
+// taking them back from the causes' raised amounts. Each removal is recorded
+// in the audit log with reason. args are the arguments of donationsQuery.
+func unmatchDonations(ctx context.Context, tx *sql.Tx, schema, donationsQuery, reason string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s FROM %s.donations
+		WHERE matched_donation_id IN (%s)
+		ORDER BY id
+	`, donationSnapshotColumns, schema, donationsQuery), args...)
+	if err != nil {
+		return err
+	}
+	var matches []models.DonationSnapshot
+	for rows.Next() {
+		snapshot, err := scanDonationSnapshot(rows.Scan)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		matches = append(matches, snapshot)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, match := range matches {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.matching_pledges p
+			SET matched_amount = p.matched_amount - d.amount, updated_at = CURRENT_TIMESTAMP
+			FROM %s.donations d
+			WHERE d.id = $1 AND p.id = d.matching_pledge_id
+		`, schema, schema), match.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.causes c
+			SET raised_amount = c.raised_amount - d.amount, updated_at = CURRENT_TIMESTAMP
+			FROM %s.donations d
+			WHERE d.id = $1 AND d.status = $2 AND c.id = d.cause_id AND c.currency = d.currency
+		`, schema, schema), match.ID, models.DonationStatusCompleted)
+		if err != nil {
+			return err
+		}
+
+		err = appendAudit(ctx, tx, schema, models.AuditRecord{
+			Type:      models.AuditDonationRemoved,
+			Donation:  match,
+			ActorType: models.StatusActorSystem,
+			Reason:    reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			DELETE FROM %s.donations WHERE id = $1
+		`, schema), match.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// decimal scans a NUMERIC column as its exact decimal text so that amounts
+// never pass through a float
+type decimal string
+
+// Scan implements sql.Scanner
+func (d *decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = "0"
+	case []byte:
+		*d = decimal(v)
+	case string:
+		*d = decimal(v)
+	case int64:
+		*d = decimal(fmt.Sprint(v))
+	default:
+		return fmt.Errorf("cannot scan %T into decimal", src)
+	}
+	return nil
+}
+
+// money parses the scanned decimal in the given currency
+func (d decimal) money(currency models.Currency) (models.Money, error) {
+	return models.ParseMoney(string(d), currency)
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// ReceiptRepository handles database operations for donation receipts
+type ReceiptRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewReceiptRepository creates a new ReceiptRepository
+func NewReceiptRepository(db *sql.DB, cfg *config.DatabaseConfig) *ReceiptRepository {
+	return &ReceiptRepository{db: db, schema: cfg.Schema}
+}
+
+// completedAt is when donation d completed: its last transition to
+// completed, or its last update for donations that predate the status history
+const completedAt = `COALESCE((
+	SELECT MAX(sc.created_at) FROM %[1]s.donation_status_changes sc
+	WHERE sc.donation_id = d.id AND sc.to_status = 'completed'
+), d.updated_at)`
+
+// receiptQuery selects receipts with the details printed on them. Voided
+// receipts have no donation, so every donation column may be NULL.
+const receiptQuery = `
+	SELECT r.number, r.verification_code, r.issued_at,
+		d.id, d.amount, d.currency, ` + completedAt + `,
+		d.transaction_hash, u.name, d.donor_address,
+		c.id, c.title, c.organization, c.tax_id, c.tax_deductible
+	FROM %[1]s.donation_receipts r
+	LEFT JOIN %[1]s.donations d ON d.id = r.donation_id
+	LEFT JOIN %[1]s.users u ON u.id = d.user_id
+	LEFT JOIN %[1]s.causes c ON c.id = d.cause_id
+`
+
+// scanReceipt scans a row selected by receiptQuery
+func scanReceipt(scan func(dest ...interface{}) error) (*models.DonationReceipt, error) {
+	var receipt models.DonationReceipt
+	var donationID, causeID sql.NullInt64
+	var amount decimal
+	var currency, txHash, donorName, donorAddress, causeTitle, organization, taxID sql.NullString
+	var completed sql.NullTime
+	var taxDeductible sql.NullBool
+
+	err := scan(
+		&receipt.Number, &receipt.VerificationCode, &receipt.IssuedAt,
+		&donationID, &amount, &currency, &completed,
+		&txHash, &donorName, &donorAddress,
+		&causeID, &causeTitle, &organization, &taxID, &taxDeductible,
+	)
+	if err != nil {
+		return nil, err
+	}
+	receipt.ReceiptNumber = models.FormatReceiptNumber(receipt.Number)
+
+	if !donationID.Valid {
+		receipt.Voided = true
+		return &receipt, nil
+	}
+
+	receipt.DonationID = int(donationID.Int64)
+	receipt.Currency = models.Currency(currency.String)
+	money, err := amount.money(receipt.Currency)
+	if err != nil {
+		return nil, err
+	}
+	receipt.Amount = &money
+	if completed.Valid {
+		receipt.CompletedAt = &completed.Time
+	}
+	receipt.TransactionHash = txHash.String
+	receipt.DonorName = donorName.String
+	receipt.DonorAddress = donorAddress.String
+	receipt.CauseID = int(causeID.Int64)
+	receipt.CauseTitle = causeTitle.String
+	receipt.Organization = organization.String
+	receipt.TaxID = taxID.String
+	receipt.TaxDeductible = taxDeductible.Bool
+	return &receipt, nil
+}
+
+// GetByDonationID gets the receipt issued for a donation, or nil if none has
+// been issued
+func (r *ReceiptRepository) GetByDonationID(ctx context.Context, donationID int) (*models.DonationReceipt, error) {
+	query := fmt.Sprintf(receiptQuery+`WHERE r.donation_id = $1`, r.schema)
+
+	receipt, err := scanReceipt(r.db.QueryRowContext(ctx, query, donationID).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// GetByVerificationCode gets the receipt with a verification code, or nil if
+// there is none
+func (r *ReceiptRepository) GetByVerificationCode(ctx context.Context, code string) (*models.DonationReceipt, error) {
+	query := fmt.Sprintf(receiptQuery+`WHERE r.verification_code = $1`, r.schema)
+
+	receipt, err := scanReceipt(r.db.QueryRowContext(ctx, query, code).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// Issue gets the receipt for a donation, issuing it with the next receipt
+// number if it has none yet. It returns nil if the donation does not exist
+// and models.ErrReceiptUnavailable if it has not completed.
+func (r *ReceiptRepository) Issue(ctx context.Context, donationID int) (*models.DonationReceipt, error) {
+	receipt, err := r.GetByDonationID(ctx, donationID)
+	if err != nil || receipt != nil {
+		return receipt, err
+	}
+
+	err = r.issueAll(ctx, func(tx *sql.Tx) ([]int, error) {
+		var status models.DonationStatus
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+			SELECT d.status FROM %[1]s.donations d
+			WHERE d.id = $1
+				AND NOT EXISTS (SELECT 1 FROM %[1]s.donation_receipts r WHERE r.donation_id = d.id)
+		`, r.schema), donationID).Scan(&status)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// Missing, or issued while we waited for the lock
+				return nil, nil
+			}
+			return nil, err
+		}
+		if status != models.DonationStatusCompleted {
+			return nil, models.ErrReceiptUnavailable
+		}
+		return []int{donationID}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByDonationID(ctx, donationID)
+}
+
+// Statement gets a user's statement for a calendar year in UTC, issuing
+// receipts for every donation they completed that year that has none yet.
+// Receipts are numbered in the order the donations completed.
+func (r *ReceiptRepository) Statement(ctx context.Context, userID, year int) (*models.DonationStatement, error) {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+
+	err := r.issueAll(ctx, func(tx *sql.Tx) ([]int, error) {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+			SELECT d.id FROM (
+				SELECT d.id, `+completedAt+` AS completed_at
+				FROM %[1]s.donations d
+				WHERE d.user_id = $1 AND d.status = $2
+					AND NOT EXISTS (SELECT 1 FROM %[1]s.donation_receipts r WHERE r.donation_id = d.id)
+			) d
+			WHERE d.completed_at >= $3 AND d.completed_at < $4
+			ORDER BY d.completed_at, d.id
+		`, r.schema), userID, models.DonationStatusCompleted, from, to)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statement := models.DonationStatement{UserID: userID, Year: year}
+	err = r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT name FROM %s.users WHERE id = $1
+	`, r.schema), userID).Scan(&statement.DonorName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(receiptQuery+`
+		WHERE d.user_id = $1 AND `+completedAt+` >= $2 AND `+completedAt+` < $3
+		ORDER BY r.number
+	`, r.schema), userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		receipt, err := scanReceipt(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		statement.Receipts = append(statement.Receipts, *receipt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// issueAll issues receipts, in order, for the donations returned by pending.
+// The table is locked while pending runs and the receipts are numbered so
+// that numbers are never skipped or given out twice.
+func (r *ReceiptRepository) issueAll(ctx context.Context, pending func(tx *sql.Tx) ([]int, error)) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Readers may continue; other issuers wait
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		LOCK TABLE %s.donation_receipts IN EXCLUSIVE MODE
+	`, r.schema))
+	if err != nil {
+		return err
+	}
+
+	donationIDs, err := pending(tx)
+	if err != nil {
+		return err
+	}
+	if len(donationIDs) == 0 {
+		return nil
+	}
+
+	var last int64
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(MAX(number), 0) FROM %s.donation_receipts
+	`, r.schema)).Scan(&last)
+	if err != nil {
+		return err
+	}
+
+	insert := fmt.Sprintf(`
+		INSERT INTO %s.donation_receipts (number, donation_id, verification_code)
+		VALUES ($1, $2, $3)
+	`, r.schema)
+	for i, donationID := range donationIDs {
+		code, err := newVerificationCode()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, insert, last+int64(i)+1, donationID, code); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// newVerificationCode returns a random code for a receipt's verification URL
+func newVerificationCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,408 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// RecurringDonationRepository handles database operations for recurring
+// donations and makes the donations they are due
+type RecurringDonationRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewRecurringDonationRepository creates a new RecurringDonationRepository
+func NewRecurringDonationRepository(db *sql.DB, cfg *config.DatabaseConfig) *RecurringDonationRepository {
+	return &RecurringDonationRepository{db: db, schema: cfg.Schema}
+}
+
+// recurringColumns are the columns selected for a recurring donation r joined
+// with its cause c
+const recurringColumns = `r.id, r.user_id, r.cause_id, c.title, r.amount, r.currency,
+	r.is_anonymous, r.frequency, r.status, r.start_at, r.run_count, r.next_run_at,
+	r.next_attempt_at, r.last_run_at, r.last_donation_id, r.failure_count, r.last_error,
+	r.created_at, r.updated_at, r.cancelled_at`
+
+// recurringSchedule is a recurring donation with the position of its next run
+type recurringSchedule struct {
+	models.RecurringDonation
+	runCount int
+}
+
+// occurrence returns the time run n of the schedule is due
+func (s *recurringSchedule) occurrence(n int) time.Time {
+	return s.Interval.Occurrence(s.StartAt.UTC(), n)
+}
+
+// scanRecurring scans a row selected with recurringColumns
+func scanRecurring(scan func(dest ...interface{}) error) (recurringSchedule, error) {
+	var s recurringSchedule
+	rd := &s.RecurringDonation
+	var amount decimal
+	var lastRunAt, cancelledAt sql.NullTime
+	var lastDonationID sql.NullInt64
+	var lastError sql.NullString
+
+	err := scan(
+		&rd.ID, &rd.UserID, &rd.CauseID, &rd.CauseTitle, &amount, &rd.Currency,
+		&rd.IsAnonymous, &rd.Interval, &rd.Status, &rd.StartAt, &s.runCount, &rd.NextRunAt,
+		&rd.NextAttemptAt, &lastRunAt, &lastDonationID, &rd.FailureCount, &lastError,
+		&rd.CreatedAt, &rd.UpdatedAt, &cancelledAt,
+	)
+	if err != nil {
+		return s, err
+	}
+	if rd.Amount, err = amount.money(rd.Currency); err != nil {
+		return s, err
+	}
+
+	if lastRunAt.Valid {
+		rd.LastRunAt = &lastRunAt.Time
+	}
+	if lastDonationID.Valid {
+		id := int(lastDonationID.Int64)
+		rd.LastDonationID = &id
+	}
+	if cancelledAt.Valid {
+		rd.CancelledAt = &cancelledAt.Time
+	}
+	rd.LastError = lastError.String
+	return s, nil
+}
+
+// Create creates a recurring donation for a user. Its first run is due at
+// input.StartAt, or now if that is not set.
+func (r *RecurringDonationRepository) Create(ctx context.Context, userID int, input models.RecurringDonationInput) (*models.RecurringDonation, error) {
+	amount, err := input.Money()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now().UTC()
+	if input.StartAt != nil {
+		start = input.StartAt.UTC()
+	}
+
+	var id int
+	err = r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.recurring_donations (
+			user_id, cause_id, amount, currency, is_anonymous, frequency, status,
+			start_at, next_run_at, next_attempt_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $8)
+		RETURNING id
+	`, r.schema),
+		userID, input.CauseID, amount, string(amount.Currency), input.IsAnonymous,
+		string(input.Interval), models.RecurringStatusActive, start,
+	).Scan(&id)
+	if err != nil {
+		return nil, mapWriteError(err)
+	}
+
+	return r.GetByID(ctx, userID, id)
+}
+
+// GetByID gets one of a user's recurring donations by ID. It returns nil if
+// the user has no recurring donation with that ID.
+func (r *RecurringDonationRepository) GetByID(ctx context.Context, userID, id int) (*models.RecurringDonation, error) {
+	s, err := scanRecurring(r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM %s.recurring_donations r
+		JOIN %s.causes c ON c.id = r.cause_id
+		WHERE r.id = $1 AND r.user_id = $2
+	`, recurringColumns, r.schema, r.schema), id, userID).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s.RecurringDonation, nil
+}
+
+// GetByUserID gets a user's recurring donations, newest first
+func (r *RecurringDonationRepository) GetByUserID(ctx context.Context, userID int) ([]models.RecurringDonation, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM %s.recurring_donations r
+		JOIN %s.causes c ON c.id = r.cause_id
+		WHERE r.user_id = $1
+		ORDER BY r.created_at DESC, r.id DESC
+	`, recurringColumns, r.schema, r.schema), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recurring := []models.RecurringDonation{}
+	for rows.Next() {
+		s, err := scanRecurring(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		recurring = append(recurring, s.RecurringDonation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return recurring, nil
+}
+
+// lock gets one of a user's recurring donations and locks it until tx ends.
+// It returns nil if the user has no recurring donation with that ID.
+func (r *RecurringDonationRepository) lock(ctx context.Context, tx *sql.Tx, userID, id int) (*recurringSchedule, error) {
+	s, err := scanRecurring(tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM %s.recurring_donations r
+		JOIN %s.causes c ON c.id = r.cause_id
+		WHERE r.id = $1 AND r.user_id = $2
+		FOR UPDATE OF r
+	`, recurringColumns, r.schema, r.schema), id, userID).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Update replaces the cause, amount, anonymity and interval of one of a
+// user's recurring donations. Changing the interval restarts the schedule
+// from the next run. It returns nil if the recurring donation does not exist
+// and models.ErrRecurringCancelled if it has been cancelled.
+func (r *RecurringDonationRepository) Update(ctx context.Context, userID, id int, input models.RecurringDonationInput) (*models.RecurringDonation, error) {
+	amount, err := input.Money()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := r.lock(ctx, tx, userID, id)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if s.Status == models.RecurringStatusCancelled {
+		return nil, models.ErrRecurringCancelled
+	}
+
+	start, runCount := s.StartAt, s.runCount
+	if input.Interval != s.Interval {
+		start, runCount = s.NextRunAt, 0
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.recurring_donations
+		SET cause_id = $1, amount = $2, currency = $3, is_anonymous = $4,
+			frequency = $5, start_at = $6, run_count = $7,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8
+	`, r.schema),
+		input.CauseID, amount, string(amount.Currency), input.IsAnonymous,
+		string(input.Interval), start, runCount, id,
+	)
+	if err != nil {
+		return nil, mapWriteError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, userID, id)
+}
+
+// SetStatus pauses, resumes or cancels one of a user's recurring donations.
+// Resuming skips the runs that fell due while it was paused and clears past
+// failures. It returns nil if the recurring donation does not exist and
+// models.ErrInvalidRecurringTransition if it cannot move to status.
+func (r *RecurringDonationRepository) SetStatus(ctx context.Context, userID, id int, status models.RecurringStatus) (*models.RecurringDonation, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := r.lock(ctx, tx, userID, id)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if !s.Status.CanTransitionTo(status) {
+		return nil, fmt.Errorf("%w: cannot move from %s to %s",
+			models.ErrInvalidRecurringTransition, s.Status, status)
+	}
+
+	switch status {
+	case models.RecurringStatusActive:
+		now := time.Now()
+		run := s.runCount
+		for s.occurrence(run).Before(now) {
+			run++
+		}
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.recurring_donations
+			SET status = $1, run_count = $2, next_run_at = $3, next_attempt_at = $3,
+				failure_count = 0, last_error = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $4
+		`, r.schema), status, run, s.occurrence(run), id)
+	case models.RecurringStatusCancelled:
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.recurring_donations
+			SET status = $1, cancelled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2
+		`, r.schema), status, id)
+	default:
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.recurring_donations
+			SET status = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2
+		`, r.schema), status, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, userID, id)
+}
+
+// RunDue makes the donation of one active recurring donation whose next
+// attempt is due at now, or returns nil if none is due. The recurring
+// donation is locked with SKIP LOCKED, so concurrent schedulers each claim a
+// different one, and the donation and the advanced schedule are committed
+// together, so a crash between them makes neither. Runs missed while the
+// scheduler was down are skipped; only the latest one due is made.
+//
+// A run that fails is rolled back and retried after models.RecurringRetryDelay;
+// the failure is reported in the returned run rather than as an error.
+func (r *RecurringDonationRepository) RunDue(ctx context.Context, now time.Time) (*models.RecurringRun, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := scanRecurring(tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM %s.recurring_donations r
+		JOIN %s.causes c ON c.id = r.cause_id
+		WHERE r.status = $1 AND r.next_attempt_at <= $2
+		ORDER BY r.next_attempt_at, r.id
+		LIMIT 1
+		FOR UPDATE OF r SKIP LOCKED
+	`, recurringColumns, r.schema, r.schema), models.RecurringStatusActive, now).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	run := s.runCount
+	for !s.occurrence(run + 1).After(now) {
+		run++
+	}
+	result := &models.RecurringRun{RecurringDonationID: s.ID, ScheduledFor: s.occurrence(run)}
+
+	// The savepoint keeps the lock when a failed run is rolled back
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT recurring_run"); err != nil {
+		return nil, err
+	}
+
+	donationID, runErr := r.makeDonation(ctx, tx, &s, result.ScheduledFor)
+	if runErr != nil {
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT recurring_run"); err != nil {
+			return nil, err
+		}
+
+		failures := s.FailureCount + 1
+		status := models.RecurringStatusActive
+		if failures >= models.RecurringMaxFailures {
+			status = models.RecurringStatusPaused
+		}
+		result.Err, result.Paused = runErr, status == models.RecurringStatusPaused
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.recurring_donations
+			SET status = $1, failure_count = $2, last_error = $3, next_attempt_at = $4,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $5
+		`, r.schema), status, failures, runErr.Error(), now.Add(models.RecurringRetryDelay(failures)), s.ID)
+	} else {
+		result.DonationID = donationID
+		next := s.occurrence(run + 1)
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s.recurring_donations
+			SET run_count = $1, next_run_at = $2, next_attempt_at = $2, last_run_at = $3,
+				last_donation_id = COALESCE($4, last_donation_id),
+				failure_count = 0, last_error = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $5
+		`, r.schema), run+1, next, now, donationID, s.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// makeDonation creates the pending donation of a recurring donation's run
+// scheduled for runAt inside tx and logs it to the audit log. It returns nil
+// if the run's donation already exists.
+func (r *RecurringDonationRepository) makeDonation(ctx context.Context, tx *sql.Tx, s *recurringSchedule, runAt time.Time) (*int, error) {
+	limit := models.MaxDonation(s.Currency)
+	if cmp, err := s.Amount.Cmp(limit); err != nil || cmp > 0 {
+		return nil, fmt.Errorf("amount exceeds the %s %s limit for a single donation", limit, s.Currency)
+	}
+
+	var id int
+	err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.donations (
+			user_id, cause_id, amount, currency, is_anonymous, status,
+			recurring_donation_id, recurring_run_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (recurring_donation_id, recurring_run_at)
+			WHERE recurring_donation_id IS NOT NULL
+			DO NOTHING
+		RETURNING id
+	`, r.schema),
+		s.UserID, s.CauseID, s.Amount, string(s.Currency), s.IsAnonymous,
+		models.DonationStatusPending, s.ID, runAt,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	record := models.AuditRecord{
+		Type:      models.AuditDonationCreated,
+		ActorType: models.StatusActorSystem,
+		Reason:    fmt.Sprintf("Recurring donation %d, run due %s", s.ID, runAt.Format(time.RFC3339)),
+	}
+	if err := logDonation(ctx, tx, r.schema, id, record); err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
@@ -8,18 +8,40 @@ import (
 
 // Repository holds all repositories
 type Repository struct {
-    User     *UserRepository
-    Category *CategoryRepository
-    Cause    *CauseRepository
-    Donation *DonationRepository
+    User         *UserRepository
+    Session      *SessionRepository
+    Token        *UserTokenRepository
+    Login        *LoginAttemptRepository
+    Category     *CategoryRepository
+    Cause        *CauseRepository
+    Donation     *DonationRepository
+    Chain        *ChainRepository
+    Ledger       *LedgerRepository
+    Audit        *AuditRepository
+    Disbursement *DisbursementRepository
+    Recurring    *RecurringDonationRepository
+    Matching     *MatchingPledgeRepository
+    Idempotency  *IdempotencyRepository
+    Receipt      *ReceiptRepository
 }
 
 // New creates a new repository
 func New(db *sql.DB, cfg *config.DatabaseConfig) *Repository {
     return &Repository{
-        User:     NewUserRepository(db, cfg),
-        Category: NewCategoryRepository(db, cfg),
-        Cause:    NewCauseRepository(db, cfg),
-        Donation: NewDonationRepository(db, cfg),
+        User:         NewUserRepository(db, cfg),
+        Session:      NewSessionRepository(db, cfg),
+        Token:        NewUserTokenRepository(db, cfg),
+        Login:        NewLoginAttemptRepository(db, cfg),
+        Category:     NewCategoryRepository(db, cfg),
+        Cause:        NewCauseRepository(db, cfg),
+        Donation:     NewDonationRepository(db, cfg),
+        Chain:        NewChainRepository(db, cfg),
+        Ledger:       NewLedgerRepository(db, cfg),
+        Audit:        NewAuditRepository(db, cfg),
+        Disbursement: NewDisbursementRepository(db, cfg),
+        Recurring:    NewRecurringDonationRepository(db, cfg),
+        Matching:     NewMatchingPledgeRepository(db, cfg),
+        Idempotency:  NewIdempotencyRepository(db, cfg),
+        Receipt:      NewReceiptRepository(db, cfg),
     }
 }
\ No newline at end of file
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+var (
+	// ErrSessionNotFound is returned for refresh tokens we never issued
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionExpired is returned for refresh tokens past their expiry
+	ErrSessionExpired = errors.New("session expired")
+	// ErrSessionRevoked is returned for refresh tokens whose session was revoked
+	ErrSessionRevoked = errors.New("session revoked")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is
+	// presented again. The whole session family is revoked when this happens.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// Reasons recorded when a session family is revoked
+const (
+	SessionRevokedLogout         = "logout"
+	SessionRevokedReuse          = "refresh token reuse"
+	SessionRevokedPasswordReset  = "password reset"
+	SessionRevokedPasswordChange = "password change"
+)
+
+// SessionRepository handles database operations for sessions
+type SessionRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewSessionRepository creates a new SessionRepository
+func NewSessionRepository(db *sql.DB, cfg *config.DatabaseConfig) *SessionRepository {
+	return &SessionRepository{db: db, schema: cfg.Schema}
+}
+
+// Create stores the first refresh token of a new session family
+func (r *SessionRepository) Create(ctx context.Context, session models.Session, tokenHash string) (*models.Session, error) {
+	query := fmt.Sprintf(`
+		INSERT INTO %s.sessions (family_id, user_id, token_hash, expires_at, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, r.schema)
+
+	err := r.db.QueryRowContext(
+		ctx, query,
+		session.FamilyID, session.UserID, tokenHash, session.ExpiresAt,
+		session.UserAgent, session.IPAddress,
+	).Scan(&session.ID, &session.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Rotate exchanges the refresh token with hash oldHash for a new one with hash
+// newHash in the same family and returns the new session. Presenting a token
+// that was already rotated revokes the whole family and returns
+// ErrRefreshTokenReused.
+func (r *SessionRepository) Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*models.Session, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current models.Session
+	var rotatedAt, revokedAt sql.NullTime
+	var userAgent, ipAddress sql.NullString
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT id, family_id, user_id, expires_at, rotated_at, revoked_at, user_agent, ip_address
+		FROM %s.sessions
+		WHERE token_hash = $1
+		FOR UPDATE
+	`, r.schema), oldHash).Scan(
+		&current.ID, &current.FamilyID, &current.UserID, &current.ExpiresAt,
+		&rotatedAt, &revokedAt, &userAgent, &ipAddress,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		return nil, ErrSessionRevoked
+	}
+
+	// A rotated token should never come back; assume it was stolen
+	if rotatedAt.Valid {
+		if err := revokeFamily(ctx, tx, r.schema, current.FamilyID, SessionRevokedReuse); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+
+	next := models.Session{
+		FamilyID:  current.FamilyID,
+		UserID:    current.UserID,
+		ExpiresAt: expiresAt,
+		UserAgent: userAgent.String,
+		IPAddress: ipAddress.String,
+	}
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.sessions (family_id, user_id, token_hash, expires_at, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, r.schema),
+		next.FamilyID, next.UserID, newHash, next.ExpiresAt, next.UserAgent, next.IPAddress,
+	).Scan(&next.ID, &next.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.sessions
+		SET rotated_at = CURRENT_TIMESTAMP, replaced_by = $1
+		WHERE id = $2
+	`, r.schema), next.ID, current.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &next, nil
+}
+
+// RevokeByToken revokes the family of the refresh token with the given hash.
+// It returns ErrSessionNotFound if no such token exists.
+func (r *SessionRepository) RevokeByToken(ctx context.Context, tokenHash, reason string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var familyID string
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT family_id FROM %s.sessions WHERE token_hash = $1
+	`, r.schema), tokenHash).Scan(&familyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	if err := revokeFamily(ctx, tx, r.schema, familyID, reason); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeFamily revokes every refresh token of a session family
+func (r *SessionRepository) RevokeFamily(ctx context.Context, familyID, reason string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := revokeFamily(ctx, tx, r.schema, familyID, reason); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsSessionActive reports whether a session family still has a usable refresh
+// token, i.e. it has been neither revoked nor left to expire
+func (r *SessionRepository) IsSessionActive(ctx context.Context, familyID string) (bool, error) {
+	var active bool
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM %s.sessions
+			WHERE family_id = $1 AND revoked_at IS NULL AND rotated_at IS NULL
+				AND expires_at > CURRENT_TIMESTAMP
+		)
+	`, r.schema), familyID).Scan(&active)
+	return active, err
+}
+
+// revokeFamily marks every token of a family as revoked
+func revokeFamily(ctx context.Context, tx *sql.Tx, schema, familyID, reason string) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.sessions
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = $1
+		WHERE family_id = $2 AND revoked_at IS NULL
+	`, schema), reason, familyID)
+	return err
+}
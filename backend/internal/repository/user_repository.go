@@ -22,7 +22,11 @@ func NewUserRepository(db *sql.DB, cfg *config.DatabaseConfig) *UserRepository {
 	return &UserRepository{db: db, schema: cfg.Schema}
 }
 
-// Create creates a new user
+// ErrLastAdmin is returned when a role change would leave no admins
+var ErrLastAdmin = errors.New("cannot demote the last admin")
+
+// Create creates a new user. New users are always plain users; roles are
+// only changed through UpdateRole.
 func (r *UserRepository) Create(ctx context.Context, input models.UserInput) (models.User, error) {
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
@@ -33,26 +37,26 @@ func (r *UserRepository) Create(ctx context.Context, input models.UserInput) (mo
 	query := fmt.Sprintf(`
 		INSERT INTO %s.users (name, email, password_hash, role)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, name, email, role, created_at, updated_at
+		RETURNING id, name, email, role, email_verified, created_at, updated_at
 	`, r.schema)
 	
 	var user models.User
 	err = r.db.QueryRowContext(
 		ctx, 
 		query, 
-		input.Name, input.Email, string(hashedPassword), input.Role,
+		input.Name, input.Email, string(hashedPassword), models.RoleUser,
 	).Scan(
-		&user.ID, &user.Name, &user.Email, &user.Role, 
+		&user.ID, &user.Name, &user.Email, &user.Role, &user.EmailVerified,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
 	
-	return user, err
+	return user, mapWriteError(err)
 }
 
 // GetByID gets a user by ID
 func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
 	query := fmt.Sprintf(`
-		SELECT id, name, email, password_hash, role, created_at, updated_at
+		SELECT id, name, email, password_hash, role, email_verified, created_at, updated_at
 		FROM %s.users
 		WHERE id = $1
 	`, r.schema)
@@ -60,7 +64,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, err
 	var user models.User
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.Name, &user.Email, &user.PasswordHash,
-		&user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -73,10 +77,25 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, err
 	return &user, nil
 }
 
+// GetRole gets a user's role, or "" if the user does not exist
+func (r *UserRepository) GetRole(ctx context.Context, id int) (string, error) {
+	var role string
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT role FROM %s.users WHERE id = $1
+	`, r.schema), id).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return role, nil
+}
+
 // GetByEmail gets a user by email
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := fmt.Sprintf(`
-		SELECT id, name, email, password_hash, role, created_at, updated_at
+		SELECT id, name, email, password_hash, role, email_verified, created_at, updated_at
 		FROM %s.users
 		WHERE email = $1
 	`, r.schema)
@@ -84,7 +103,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	var user models.User
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID, &user.Name, &user.Email, &user.PasswordHash, 
-		&user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -97,49 +116,184 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	return &user, nil
 }
 
-// Update updates a user
-func (r *UserRepository) Update(ctx context.Context, id int, input models.UserInput) (*models.User, error) {
-	// Get the current user to preserve existing data
-	currentUser, err := r.GetByID(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-	if currentUser == nil {
-		return nil, nil
-	}
-
-	// Prepare update query with only the fields that need to be updated
+// Update applies a partial profile update. It returns nil if the user does
+// not exist.
+func (r *UserRepository) Update(ctx context.Context, id int, input models.UserUpdateInput) (*models.User, error) {
 	query := fmt.Sprintf(`
 		UPDATE %s.users
-		SET name = $1, updated_at = CURRENT_TIMESTAMP
+		SET name = COALESCE($1, name), updated_at = CURRENT_TIMESTAMP
 		WHERE id = $2
-		RETURNING id, name, email, role, created_at, updated_at
+		RETURNING id, name, email, role, email_verified, created_at, updated_at
 	`, r.schema)
 	
 	var user models.User
-	err = r.db.QueryRowContext(
+	err := r.db.QueryRowContext(
 		ctx, 
 		query, 
 		input.Name, id,
 	).Scan(
-		&user.ID, &user.Name, &user.Email, &user.Role, 
+		&user.ID, &user.Name, &user.Email, &user.Role, &user.EmailVerified,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
 		return nil, err
 	}
 	
 	return &user, nil
 }
 
-// Delete deletes a user
+// UpdatePassword stores a new password hash and revokes every session of the
+// user except the one identified by keepFamilyID
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int, passwordHash, keepFamilyID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.users
+		SET password_hash = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, r.schema), passwordHash, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.sessions
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = $1
+		WHERE user_id = $2 AND family_id <> $3 AND revoked_at IS NULL
+	`, r.schema), SessionRevokedPasswordChange, id, keepFamilyID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateEmail changes a user's email address and marks it unverified. It
+// returns ErrEmailTaken if another account already uses the address.
+func (r *UserRepository) UpdateEmail(ctx context.Context, id int, email string) (*models.User, error) {
+	query := fmt.Sprintf(`
+		UPDATE %s.users
+		SET email = $1, email_verified = FALSE, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+		RETURNING id, name, email, role, email_verified, created_at, updated_at
+	`, r.schema)
+
+	var user models.User
+	err := r.db.QueryRowContext(ctx, query, email, id).Scan(
+		&user.ID, &user.Name, &user.Email, &user.Role, &user.EmailVerified,
+		&user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, mapWriteError(err)
+	}
+
+	return &user, nil
+}
+
+// GetAll gets all users
+func (r *UserRepository) GetAll(ctx context.Context) ([]models.User, error) {
+	query := fmt.Sprintf(`
+		SELECT id, name, email, role, email_verified, created_at, updated_at
+		FROM %s.users
+		ORDER BY id
+	`, r.schema)
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID, &user.Name, &user.Email, &user.Role, &user.EmailVerified,
+			&user.CreatedAt, &user.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// UpdateRole changes a user's role. It returns nil if the user does not exist
+// and ErrLastAdmin if the change would demote the only remaining admin.
+func (r *UserRepository) UpdateRole(ctx context.Context, id int, role string) (*models.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the admins so two concurrent demotions cannot both pass the check
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id FROM %s.users WHERE role = $1 FOR UPDATE
+	`, r.schema), models.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	var admins []int
+	for rows.Next() {
+		var adminID int
+		if err := rows.Scan(&adminID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		admins = append(admins, adminID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if role != models.RoleAdmin && len(admins) == 1 && admins[0] == id {
+		return nil, ErrLastAdmin
+	}
+
+	var user models.User
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE %s.users
+		SET role = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+		RETURNING id, name, email, role, email_verified, created_at, updated_at
+	`, r.schema), role, id).Scan(
+		&user.ID, &user.Name, &user.Email, &user.Role, &user.EmailVerified,
+		&user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// Delete deletes a user. It returns ErrNotFound if the user does not exist.
 func (r *UserRepository) Delete(ctx context.Context, id int) error {
-	query := `
-	DELETE FROM users
-	WHERE id = ?
-	`
+	query := fmt.Sprintf(`
+	DELETE FROM %s.users
+	WHERE id = $1
+	`, r.schema)
 
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err
+	return execDelete(ctx, r.db, query, id)
 }
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+)
+
+// ErrInvalidUserToken is returned for user tokens that are unknown, expired,
+// already used or issued for a different purpose
+var ErrInvalidUserToken = errors.New("invalid or expired token")
+
+// UserTokenRepository handles database operations for single-use user tokens
+type UserTokenRepository struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewUserTokenRepository creates a new UserTokenRepository
+func NewUserTokenRepository(db *sql.DB, cfg *config.DatabaseConfig) *UserTokenRepository {
+	return &UserTokenRepository{db: db, schema: cfg.Schema}
+}
+
+// Create stores the hash of a new token for userID. Earlier unused tokens for
+// the same purpose are invalidated so only the latest email works.
+func (r *UserTokenRepository) Create(ctx context.Context, userID int, purpose models.TokenPurpose, tokenHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, r.schema), userID, purpose)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s.user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, r.schema), userID, purpose, tokenHash, time.Now().Add(purpose.TTL()))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ResetPassword redeems a password reset token, sets the new password hash
+// and revokes every session of the user, all in one transaction
+func (r *UserTokenRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	userID, err := r.consume(ctx, tx, models.TokenPurposePasswordReset, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.users
+		SET password_hash = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, r.schema), passwordHash, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Whoever knew the old password must not stay logged in
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.sessions
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = $1
+		WHERE user_id = $2 AND revoked_at IS NULL
+	`, r.schema), SessionRevokedPasswordReset, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// VerifyEmail redeems an email verification token and marks the user's email
+// as verified
+func (r *UserTokenRepository) VerifyEmail(ctx context.Context, tokenHash string) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	userID, err := r.consume(ctx, tx, models.TokenPurposeEmailVerification, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.users
+		SET email_verified = TRUE, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, r.schema), userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// consume marks a valid token as used and returns its user. The conditional
+// update makes sure a token can only be redeemed once.
+func (r *UserTokenRepository) consume(ctx context.Context, tx *sql.Tx, purpose models.TokenPurpose, tokenHash string) (int, error) {
+	var userID int
+	err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE %s.user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND purpose = $2
+			AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id
+	`, r.schema), tokenHash, purpose).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidUserToken
+		}
+		return 0, err
+	}
+	return userID, nil
+}
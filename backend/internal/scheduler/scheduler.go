@@ -0,0 +1,88 @@
+// Package scheduler makes the donations of recurring donations as they fall
+// due. Any number of schedulers may run against the same database: each due
+// recurring donation is claimed by exactly one of them.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+	"github.com/ombima56/transpacharity/internal/models"
+	"github.com/ombima56/transpacharity/internal/repository"
+)
+
+// Store makes the donation of one due recurring donation. It is implemented
+// by repository.RecurringDonationRepository and can be replaced in tests.
+type Store interface {
+	// RunDue makes the donation of one recurring donation due at now and
+	// returns the run, or nil if none is due
+	RunDue(ctx context.Context, now time.Time) (*models.RecurringRun, error)
+}
+
+var _ Store = (*repository.RecurringDonationRepository)(nil)
+
+// Scheduler polls for due recurring donations and makes their donations
+type Scheduler struct {
+	store Store
+	cfg   *config.RecurringConfig
+}
+
+// New creates a new Scheduler
+func New(store Store, cfg *config.RecurringConfig) *Scheduler {
+	return &Scheduler{store: store, cfg: cfg}
+}
+
+// Run polls until the context is cancelled. Errors are logged and retried on
+// the next tick so a database hiccup does not stop the worker.
+func (s *Scheduler) Run(ctx context.Context) error {
+	interval := time.Duration(s.cfg.PollIntervalSeconds) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Poll(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Scheduler poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll runs up to BatchSize due recurring donations and returns how many it
+// ran. Failed runs are logged and count as run; they are retried later.
+func (s *Scheduler) Poll(ctx context.Context) (int, error) {
+	ran := 0
+	for ran < s.cfg.BatchSize {
+		run, err := s.store.RunDue(ctx, time.Now().UTC())
+		if err != nil {
+			return ran, err
+		}
+		if run == nil {
+			break
+		}
+		ran++
+
+		switch {
+		case run.Err != nil && run.Paused:
+			log.Printf("Recurring donation %d failed too often and was paused: %v",
+				run.RecurringDonationID, run.Err)
+		case run.Err != nil:
+			log.Printf("Recurring donation %d failed for run due %s, will retry: %v",
+				run.RecurringDonationID, run.ScheduledFor.Format(time.RFC3339), run.Err)
+		case run.DonationID != nil:
+			log.Printf("Recurring donation %d made donation %d for run due %s",
+				run.RecurringDonationID, *run.DonationID, run.ScheduledFor.Format(time.RFC3339))
+		default:
+			log.Printf("Recurring donation %d already made its donation for run due %s",
+				run.RecurringDonationID, run.ScheduledFor.Format(time.RFC3339))
+		}
+	}
+	return ran, nil
+}
@@ -0,0 +1,130 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often a MemoryStore drops expired entries
+const sweepInterval = time.Minute
+
+// memoryEntry is the state of one key in a MemoryStore
+type memoryEntry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+	expiresAt    time.Time
+}
+
+// MemoryStore keeps failure counters in process memory. Counters are lost on
+// restart and not shared between replicas; use the Postgres store when the
+// API runs on more than one instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+// AddFailure implements Store
+func (s *MemoryStore) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	if now.Sub(entry.lastFailure) > window {
+		entry.failures = 0
+	}
+	entry.failures++
+	entry.lastFailure = now
+	entry.extend(now.Add(window))
+
+	return entry.failures, nil
+}
+
+// Block implements Store
+func (s *MemoryStore) Block(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	entry.blockedUntil = until
+	entry.extend(until)
+	return nil
+}
+
+// BlockedUntil implements Store
+func (s *MemoryStore) BlockedUntil(ctx context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok {
+		return entry.blockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+// Reset implements Store
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// DeleteExpired implements Store. Entries expire with the window their last
+// failure was recorded with, so window is not needed; entries are also swept
+// as failures are added.
+func (s *MemoryStore) DeleteExpired(ctx context.Context, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteExpired(time.Now()), nil
+}
+
+// sweep drops expired entries at most once per sweepInterval. The caller must
+// hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.deleteExpired(now)
+}
+
+// deleteExpired drops entries that are neither blocked nor within their
+// window and returns how many were dropped. The caller must hold s.mu.
+func (s *MemoryStore) deleteExpired(now time.Time) int64 {
+	var deleted int64
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, key)
+			deleted++
+		}
+	}
+	s.lastSweep = now
+	return deleted
+}
+
+// extend keeps the entry until at least t
+func (e *memoryEntry) extend(t time.Time) {
+	if t.After(e.expiresAt) {
+		e.expiresAt = t
+	}
+}
@@ -0,0 +1,84 @@
+package throttle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+)
+
+// PostgresStore keeps failure counters in the login_throttle table so that
+// every API replica sees the same counters
+type PostgresStore struct {
+	db     *sql.DB
+	schema string
+}
+
+// NewPostgresStore creates a PostgresStore
+func NewPostgresStore(db *sql.DB, cfg *config.DatabaseConfig) *PostgresStore {
+	return &PostgresStore{db: db, schema: cfg.Schema}
+}
+
+// AddFailure implements Store
+func (s *PostgresStore) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var failures int
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO %[1]s.login_throttle (key, failures, last_failure_at)
+		VALUES ($1, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN %[1]s.login_throttle.last_failure_at < CURRENT_TIMESTAMP - make_interval(secs => $2) THEN 1
+				ELSE %[1]s.login_throttle.failures + 1
+			END,
+			last_failure_at = CURRENT_TIMESTAMP
+		RETURNING failures
+	`, s.schema), key, window.Seconds()).Scan(&failures)
+	return failures, err
+}
+
+// Block implements Store
+func (s *PostgresStore) Block(ctx context.Context, key string, until time.Time) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s.login_throttle SET blocked_until = $1 WHERE key = $2
+	`, s.schema), until, key)
+	return err
+}
+
+// BlockedUntil implements Store
+func (s *PostgresStore) BlockedUntil(ctx context.Context, key string) (time.Time, error) {
+	var until sql.NullTime
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT blocked_until FROM %s.login_throttle WHERE key = $1
+	`, s.schema), key).Scan(&until)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return until.Time, nil
+}
+
+// Reset implements Store
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		DELETE FROM %s.login_throttle WHERE key = $1
+	`, s.schema), key)
+	return err
+}
+
+// DeleteExpired implements Store
+func (s *PostgresStore) DeleteExpired(ctx context.Context, window time.Duration) (int64, error) {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		DELETE FROM %s.login_throttle
+		WHERE last_failure_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+		  AND (blocked_until IS NULL OR blocked_until < CURRENT_TIMESTAMP)
+	`, s.schema), window.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
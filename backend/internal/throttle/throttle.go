@@ -0,0 +1,178 @@
+// Package throttle slows down password guessing by delaying and locking out
+// repeated failed logins
+package throttle
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+)
+
+// Store keeps failure counters per key. Keys are opaque strings such as
+// "email:alice@example.com" or "ip:203.0.113.7".
+type Store interface {
+	// AddFailure records a failed attempt and returns the number of failures
+	// for key. The count restarts once window has passed since the last one.
+	AddFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	// Block rejects attempts for key until the given time
+	Block(ctx context.Context, key string, until time.Time) error
+	// BlockedUntil returns when key may be tried again, or the zero time
+	BlockedUntil(ctx context.Context, key string) (time.Time, error)
+	// Reset forgets every failure for key
+	Reset(ctx context.Context, key string) error
+	// DeleteExpired forgets keys that are not blocked and have had no failure
+	// for window, and returns how many were deleted
+	DeleteExpired(ctx context.Context, window time.Duration) (int64, error)
+}
+
+// Policy describes how quickly failures for one key are slowed down
+type Policy struct {
+	// FreeAttempts failures are allowed before any delay is applied
+	FreeAttempts int
+	// BaseDelay is the delay after the first failure past FreeAttempts. It
+	// doubles with every further failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAfter failures lock the key for LockoutDuration. Zero disables
+	// the lockout.
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	// Window is how long failures are remembered after the last one
+	Window time.Duration
+}
+
+// delay returns how long to block a key after its nth failure
+func (p Policy) delay(failures int) time.Duration {
+	if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Limiter throttles logins per account and per client IP
+type Limiter struct {
+	store   Store
+	account Policy
+	ip      Policy
+}
+
+// NewLimiter creates a Limiter with policies built from cfg
+func NewLimiter(store Store, cfg *config.LoginThrottleConfig) *Limiter {
+	return &Limiter{
+		store: store,
+		account: Policy{
+			FreeAttempts:    cfg.AccountFreeAttempts,
+			BaseDelay:       time.Second,
+			MaxDelay:        5 * time.Minute,
+			LockoutAfter:    cfg.LockoutThreshold,
+			LockoutDuration: cfg.LockoutDuration(),
+			Window:          time.Hour,
+		},
+		ip: Policy{
+			FreeAttempts: cfg.IPFreeAttempts,
+			BaseDelay:    time.Second,
+			MaxDelay:     15 * time.Minute,
+			Window:       time.Hour,
+		},
+	}
+}
+
+// NewStore creates the store selected by cfg.Store
+func NewStore(cfg *config.LoginThrottleConfig, db *sql.DB, dbCfg *config.DatabaseConfig) (Store, error) {
+	switch cfg.Store {
+	case "memory", "":
+		return NewMemoryStore(), nil
+	case "postgres":
+		return NewPostgresStore(db, dbCfg), nil
+	}
+	return nil, fmt.Errorf("unknown LOGIN_THROTTLE_STORE %q: use memory or postgres", cfg.Store)
+}
+
+// Check returns how long the caller must wait before trying to log in to
+// email from ip again. Zero means the attempt may go ahead.
+func (l *Limiter) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		until, err := l.store.BlockedUntil(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if remaining := time.Until(until); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait, nil
+}
+
+// Failure records a failed login to email from ip and blocks further
+// attempts for as long as the policies require
+func (l *Limiter) Failure(ctx context.Context, email, ip string) error {
+	if err := l.fail(ctx, accountKey(email), l.account); err != nil {
+		return err
+	}
+	return l.fail(ctx, ipKey(ip), l.ip)
+}
+
+// Success clears the failures for an account. The client IP keeps its
+// counter so that one valid account cannot be used to reset it.
+func (l *Limiter) Success(ctx context.Context, email string) error {
+	return l.store.Reset(ctx, accountKey(email))
+}
+
+// fail records a failure for key and applies policy
+func (l *Limiter) fail(ctx context.Context, key string, policy Policy) error {
+	failures, err := l.store.AddFailure(ctx, key, policy.Window)
+	if err != nil {
+		return err
+	}
+	if delay := policy.delay(failures); delay > 0 {
+		return l.store.Block(ctx, key, time.Now().Add(delay))
+	}
+	return nil
+}
+
+// PurgeExpired deletes expired counters from the store every interval until
+// ctx is cancelled
+func (l *Limiter) PurgeExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	window := max(l.account.Window, l.ip.Window)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := l.store.DeleteExpired(ctx, window); err != nil {
+				log.Printf("Error deleting expired login throttle counters: %v", err)
+			}
+		}
+	}
+}
+
+// accountKey returns the store key for an email address. Addresses without
+// an account get a key too, so responses do not reveal which ones exist.
+func accountKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// ipKey returns the store key for a client IP
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,225 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ombima56/transpacharity/internal/config"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	policy := Policy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        10 * time.Second,
+		LockoutAfter:    10,
+		LockoutDuration: time.Hour,
+	}
+	for failures, want := range map[int]time.Duration{
+		1:  0,
+		3:  0,
+		4:  time.Second,
+		5:  2 * time.Second,
+		6:  4 * time.Second,
+		7:  8 * time.Second,
+		8:  10 * time.Second,
+		9:  10 * time.Second,
+		10: time.Hour,
+		50: time.Hour,
+	} {
+		if got := policy.delay(failures); got != want {
+			t.Errorf("delay(%d) = %s, want %s", failures, got, want)
+		}
+	}
+
+	policy.LockoutAfter = 0
+	if got := policy.delay(50); got != policy.MaxDelay {
+		t.Errorf("delay(50) without lockout = %s, want %s", got, policy.MaxDelay)
+	}
+}
+
+func newTestLimiter(store Store) *Limiter {
+	return NewLimiter(store, &config.LoginThrottleConfig{
+		LockoutThreshold:    5,
+		LockoutMinutes:      15,
+		AccountFreeAttempts: 3,
+		IPFreeAttempts:      8,
+	})
+}
+
+func TestLimiterBacksOffAndLocksAccounts(t *testing.T) {
+	ctx := context.Background()
+	limiter := newTestLimiter(NewMemoryStore())
+
+	for i := 1; i <= 5; i++ {
+		if wait, err := limiter.Check(ctx, "jane@example.com", "203.0.113.7"); err != nil || (i <= 4 && wait != 0) {
+			t.Fatalf("Check before failure %d = %s, %v; want no wait", i, wait, err)
+		}
+		if err := limiter.Failure(ctx, "jane@example.com", "203.0.113.7"); err != nil {
+			t.Fatalf("Failure: %v", err)
+		}
+		wait, err := limiter.Check(ctx, "jane@example.com", "203.0.113.7")
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		switch {
+		case i <= 3 && wait != 0:
+			t.Errorf("after %d failures: wait %s, want none", i, wait)
+		case i == 4 && (wait <= 0 || wait > time.Second):
+			t.Errorf("after 4 failures: wait %s, want about a second", wait)
+		case i == 5 && (wait <= 14*time.Minute || wait > 15*time.Minute):
+			t.Errorf("after 5 failures: wait %s, want the 15 minute lockout", wait)
+		}
+	}
+
+	// The account is locked from any address, with any spelling
+	if wait, _ := limiter.Check(ctx, " Jane@Example.COM", "198.51.100.1"); wait < 14*time.Minute {
+		t.Errorf("locked account from another IP: wait %s", wait)
+	}
+	if wait, _ := limiter.Check(ctx, "john@example.com", "198.51.100.1"); wait != 0 {
+		t.Errorf("other account: wait %s, want none", wait)
+	}
+}
+
+func TestLimiterThrottlesIPs(t *testing.T) {
+	ctx := context.Background()
+	limiter := newTestLimiter(NewMemoryStore())
+
+	// Spread over many accounts, so only the IP policy applies
+	for i := 0; i < 9; i++ {
+		email := string(rune('a'+i)) + "@example.com"
+		if err := limiter.Failure(ctx, email, "203.0.113.7"); err != nil {
+			t.Fatalf("Failure: %v", err)
+		}
+	}
+	if wait, _ := limiter.Check(ctx, "new@example.com", "203.0.113.7"); wait <= 0 {
+		t.Error("IP past its free attempts is not throttled")
+	}
+	if wait, _ := limiter.Check(ctx, "new@example.com", "198.51.100.1"); wait != 0 {
+		t.Errorf("other IP: wait %s, want none", wait)
+	}
+}
+
+func TestLimiterSuccessResetsOnlyTheAccount(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limiter := newTestLimiter(store)
+
+	for i := 0; i < 4; i++ {
+		limiter.Failure(ctx, "jane@example.com", "203.0.113.7")
+	}
+	if err := limiter.Success(ctx, "JANE@example.com"); err != nil {
+		t.Fatalf("Success: %v", err)
+	}
+	if until, _ := store.BlockedUntil(ctx, accountKey("jane@example.com")); !until.IsZero() {
+		t.Errorf("account still blocked until %s after a successful login", until)
+	}
+	failures, _ := store.AddFailure(ctx, ipKey("203.0.113.7"), time.Hour)
+	if failures != 5 {
+		t.Errorf("IP has %d failures after a successful login, want its count kept", failures)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	for want := 1; want <= 3; want++ {
+		if got, err := store.AddFailure(ctx, "k", time.Hour); err != nil || got != want {
+			t.Fatalf("AddFailure = %d, %v; want %d", got, err, want)
+		}
+	}
+
+	// Failures older than the window are forgotten
+	time.Sleep(2 * time.Millisecond)
+	if got, _ := store.AddFailure(ctx, "k", time.Millisecond); got != 1 {
+		t.Errorf("AddFailure after the window = %d, want 1", got)
+	}
+
+	until := time.Now().Add(time.Minute).Truncate(time.Second)
+	if err := store.Block(ctx, "blocked", until); err != nil {
+		t.Fatalf("Block: %v", err)
+	}
+	if got, _ := store.BlockedUntil(ctx, "blocked"); !got.Equal(until) {
+		t.Errorf("BlockedUntil = %s, want %s", got, until)
+	}
+	if got, _ := store.BlockedUntil(ctx, "unknown"); !got.IsZero() {
+		t.Errorf("BlockedUntil of an unknown key = %s", got)
+	}
+
+	store.Reset(ctx, "blocked")
+	if got, _ := store.BlockedUntil(ctx, "blocked"); !got.IsZero() {
+		t.Errorf("BlockedUntil after Reset = %s", got)
+	}
+}
+
+func TestMemoryStoreSweepsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.AddFailure(ctx, "expired", time.Millisecond)
+	store.Block(ctx, "blocked", time.Now().Add(time.Hour))
+
+	time.Sleep(2 * time.Millisecond)
+	store.lastSweep = time.Time{}
+	store.AddFailure(ctx, "new", time.Hour)
+
+	if _, ok := store.entries["expired"]; ok {
+		t.Error("expired entry was not swept")
+	}
+	if _, ok := store.entries["blocked"]; !ok {
+		t.Error("blocked entry was swept")
+	}
+}
+
+func TestMemoryStoreDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.AddFailure(ctx, "expired", time.Millisecond)
+	store.AddFailure(ctx, "recent", time.Hour)
+	store.AddFailure(ctx, "blocked", time.Millisecond)
+	store.Block(ctx, "blocked", time.Now().Add(time.Hour))
+
+	time.Sleep(2 * time.Millisecond)
+	if deleted, err := store.DeleteExpired(ctx, time.Hour); err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpired = %d, %v; want 1", deleted, err)
+	}
+	if _, ok := store.entries["expired"]; ok {
+		t.Error("expired entry was not deleted")
+	}
+	if len(store.entries) != 2 {
+		t.Errorf("%d entries left, want the recent and blocked ones", len(store.entries))
+	}
+}
+
+func TestLimiterAccountFreeAttempts(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewLimiter(NewMemoryStore(), &config.LoginThrottleConfig{
+		LockoutThreshold:    10,
+		LockoutMinutes:      15,
+		AccountFreeAttempts: 1,
+		IPFreeAttempts:      20,
+	})
+
+	limiter.Failure(ctx, "jane@example.com", "203.0.113.7")
+	if wait, _ := limiter.Check(ctx, "jane@example.com", "203.0.113.7"); wait != 0 {
+		t.Errorf("after the free attempt: wait %s, want none", wait)
+	}
+	limiter.Failure(ctx, "jane@example.com", "203.0.113.7")
+	if wait, _ := limiter.Check(ctx, "jane@example.com", "203.0.113.7"); wait <= 0 {
+		t.Error("account past its free attempts is not throttled")
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	for _, name := range []string{"", "memory"} {
+		if store, err := NewStore(&config.LoginThrottleConfig{Store: name}, nil, nil); err != nil {
+			t.Errorf("NewStore(%q): %v", name, err)
+		} else if _, ok := store.(*MemoryStore); !ok {
+			t.Errorf("NewStore(%q) = %T, want a MemoryStore", name, store)
+		}
+	}
+	if _, err := NewStore(&config.LoginThrottleConfig{Store: "redis"}, nil, nil); err == nil {
+		t.Error("NewStore accepted an unknown store")
+	}
+}
@@ -0,0 +1,226 @@
+// Package validation checks request inputs against their validate struct
+// tags and collects the problems per field
+package validation
+
+import (
+	"fmt"
+	"math/big"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error codes reported in FieldError.Code
+const (
+	CodeRequired = "required"
+	CodeMin      = "min"
+	CodeMax      = "max"
+	CodeGreater  = "gt"
+	CodeEmail    = "email"
+	CodeURL      = "url"
+	CodeOneOf    = "oneof"
+	CodeNumber   = "number"
+	CodeNotFound = "not_found"
+	CodeInvalid  = "invalid"
+)
+
+// FieldError describes why one field of an input is invalid. Field is the
+// field's JSON name so clients can map it to a form input.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+}
+
+// Errors lists every invalid field of an input. It is empty for valid input.
+type Errors []FieldError
+
+// Error implements error
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Add records an error for field
+func (e *Errors) Add(field, code, message string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Has reports whether field already has an error, so that custom checks can
+// skip fields that failed their tags
+func (e Errors) Has(field string) bool {
+	for _, fe := range e {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Struct checks every field of the struct v points to against its validate
+// tag. The supported rules are required, omitempty, min, max, gt, email, url
+// and oneof. For strings min and max count characters and gt compares the
+// string as a decimal number; for numbers they compare the value.
+func Struct(v interface{}) Errors {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	typ := val.Type()
+
+	var errs Errors
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+		if fe, ok := checkField(jsonName(field), val.Field(i), tag); !ok {
+			errs = append(errs, fe)
+		}
+	}
+	return errs
+}
+
+// checkField applies the rules of tag to value and returns the first failure
+func checkField(name string, value reflect.Value, tag string) (FieldError, bool) {
+	rules := strings.Split(tag, ",")
+
+	// Unset pointers only need to satisfy required and omitempty
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			for _, rule := range rules {
+				if rule == "required" {
+					return FieldError{Field: name, Code: CodeRequired, Message: "is required"}, false
+				}
+			}
+			return FieldError{}, true
+		}
+		value = value.Elem()
+	}
+
+	for _, rule := range rules {
+		rule, param, _ := strings.Cut(rule, "=")
+		switch rule {
+		case "omitempty":
+			if value.IsZero() {
+				return FieldError{}, true
+			}
+		case "required":
+			if isEmpty(value) {
+				return FieldError{Field: name, Code: CodeRequired, Message: "is required"}, false
+			}
+		case "min", "max", "gt":
+			if fe, ok := checkBound(name, value, rule, param); !ok {
+				return fe, false
+			}
+		case "email":
+			s := value.String()
+			if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+				return FieldError{Field: name, Code: CodeEmail, Message: "must be a valid email address"}, false
+			}
+		case "url":
+			u, err := url.Parse(value.String())
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return FieldError{Field: name, Code: CodeURL, Message: "must be a valid http or https URL"}, false
+			}
+		case "oneof":
+			options := strings.Fields(param)
+			if !contains(options, fmt.Sprint(value.Interface())) {
+				return FieldError{
+					Field: name, Code: CodeOneOf, Param: param,
+					Message: "must be one of: " + strings.Join(options, ", "),
+				}, false
+			}
+		default:
+			panic(fmt.Sprintf("validation: unknown rule %q on field %s", rule, name))
+		}
+	}
+	return FieldError{}, true
+}
+
+// checkBound applies a min, max or gt rule
+func checkBound(name string, value reflect.Value, rule, param string) (FieldError, bool) {
+	limit, ok := new(big.Rat).SetString(param)
+	if !ok {
+		panic(fmt.Sprintf("validation: invalid %s parameter %q on field %s", rule, param, name))
+	}
+
+	// min and max limit the length of strings
+	if value.Kind() == reflect.String && rule != "gt" {
+		length := big.NewRat(int64(utf8.RuneCountInString(value.String())), 1)
+		if rule == "min" && length.Cmp(limit) < 0 {
+			return FieldError{Field: name, Code: CodeMin, Param: param, Message: "must be at least " + param + " characters"}, false
+		}
+		if rule == "max" && length.Cmp(limit) > 0 {
+			return FieldError{Field: name, Code: CodeMax, Param: param, Message: "must be at most " + param + " characters"}, false
+		}
+		return FieldError{}, true
+	}
+
+	n, ok := number(value)
+	if !ok {
+		return FieldError{Field: name, Code: CodeNumber, Message: "must be a number"}, false
+	}
+	switch cmp := n.Cmp(limit); {
+	case rule == "min" && cmp < 0:
+		return FieldError{Field: name, Code: CodeMin, Param: param, Message: "must be at least " + param}, false
+	case rule == "max" && cmp > 0:
+		return FieldError{Field: name, Code: CodeMax, Param: param, Message: "must be at most " + param}, false
+	case rule == "gt" && cmp <= 0:
+		return FieldError{Field: name, Code: CodeGreater, Param: param, Message: "must be greater than " + param}, false
+	}
+	return FieldError{}, true
+}
+
+// number returns the numeric value of a number or decimal string
+func number(value reflect.Value) (*big.Rat, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Rat).SetUint64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		n := new(big.Rat).SetFloat64(value.Float())
+		return n, n != nil
+	case reflect.String:
+		s := strings.TrimSpace(value.String())
+		// big.Rat also accepts fractions like "1/3", which are not amounts
+		if strings.Contains(s, "/") {
+			return nil, false
+		}
+		return new(big.Rat).SetString(s)
+	}
+	return nil, false
+}
+
+// isEmpty reports whether a required value is missing. Strings of only
+// whitespace count as missing.
+func isEmpty(value reflect.Value) bool {
+	if value.Kind() == reflect.String {
+		return strings.TrimSpace(value.String()) == ""
+	}
+	return value.IsZero()
+}
+
+// jsonName returns the name a struct field has in JSON
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// contains reports whether options contains s
+func contains(options []string, s string) bool {
+	for _, option := range options {
+		if option == s {
+			return true
+		}
+	}
+	return false
+}
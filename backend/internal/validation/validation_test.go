@@ -0,0 +1,272 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+// check validates v and returns its only error, or the zero FieldError
+func check(t *testing.T, v interface{}) FieldError {
+	t.Helper()
+	errs := Struct(v)
+	switch len(errs) {
+	case 0:
+		return FieldError{}
+	case 1:
+		return errs[0]
+	}
+	t.Fatalf("Struct(%+v) = %v, want at most one error", v, errs)
+	return FieldError{}
+}
+
+func TestRequired(t *testing.T) {
+	type input struct {
+		Name   string   `json:"name" validate:"required"`
+		Count  int      `json:"count" validate:"required"`
+		Causes []int    `json:"causes" validate:"required"`
+		Amount *float64 `json:"amount" validate:"required"`
+	}
+	amount := 2.5
+
+	for _, tt := range []struct {
+		input input
+		field string
+	}{
+		{input{Name: "", Count: 1, Causes: []int{1}, Amount: &amount}, "name"},
+		{input{Name: " \t\n", Count: 1, Causes: []int{1}, Amount: &amount}, "name"},
+		{input{Name: "x", Count: 0, Causes: []int{1}, Amount: &amount}, "count"},
+		{input{Name: "x", Count: 1, Causes: nil, Amount: &amount}, "causes"},
+		{input{Name: "x", Count: 1, Causes: []int{1}, Amount: nil}, "amount"},
+	} {
+		fe := check(t, &tt.input)
+		if fe.Field != tt.field || fe.Code != CodeRequired || fe.Message != "is required" {
+			t.Errorf("Struct(%+v) = %+v, want %s required", tt.input, fe, tt.field)
+		}
+	}
+
+	// An empty but non-nil slice is present
+	if fe := check(t, &input{Name: "x", Count: 1, Causes: []int{}, Amount: &amount}); fe.Field != "" {
+		t.Errorf("complete input: %+v", fe)
+	}
+}
+
+func TestOmitEmpty(t *testing.T) {
+	type input struct {
+		Website string  `json:"website" validate:"omitempty,url"`
+		Limit   int     `json:"limit" validate:"omitempty,min=1"`
+		Email   *string `json:"email" validate:"omitempty,email"`
+	}
+	if errs := Struct(&input{}); len(errs) != 0 {
+		t.Errorf("empty optional fields: %v", errs)
+	}
+	bad := "not an address"
+	errs := Struct(&input{Website: "ftp://example.com", Limit: -1, Email: &bad})
+	if len(errs) != 3 || errs[0].Code != CodeURL || errs[1].Code != CodeMin || errs[2].Code != CodeEmail {
+		t.Errorf("invalid optional fields: %+v", errs)
+	}
+}
+
+func TestStringLength(t *testing.T) {
+	type input struct {
+		Name string `json:"name" validate:"min=2,max=4"`
+	}
+	for _, tt := range []struct {
+		name  string
+		code  string
+		param string
+	}{
+		{"ab", "", ""},
+		{"abcd", "", ""},
+		{"éèêë", "", ""}, // characters, not bytes
+		{"a", CodeMin, "2"},
+		{"abcde", CodeMax, "4"},
+	} {
+		fe := check(t, &input{Name: tt.name})
+		if fe.Code != tt.code || fe.Param != tt.param {
+			t.Errorf("name %q: %+v, want code %q", tt.name, fe, tt.code)
+		}
+	}
+	if fe := check(t, &input{Name: "a"}); fe.Message != "must be at least 2 characters" {
+		t.Errorf("message = %q", fe.Message)
+	}
+}
+
+func TestNumberBounds(t *testing.T) {
+	type input struct {
+		Limit int     `json:"limit" validate:"min=1,max=100"`
+		Ratio float64 `json:"ratio" validate:"gt=0,max=1"`
+		Year  uint16  `json:"year" validate:"min=2000"`
+	}
+	valid := input{Limit: 1, Ratio: 0.5, Year: 2000}
+	for _, tt := range []struct {
+		change func(*input)
+		field  string
+		code   string
+	}{
+		{func(in *input) {}, "", ""},
+		{func(in *input) { in.Limit = 100; in.Ratio = 1 }, "", ""},
+		{func(in *input) { in.Limit = 0 }, "limit", CodeMin},
+		{func(in *input) { in.Limit = 101 }, "limit", CodeMax},
+		{func(in *input) { in.Ratio = 0 }, "ratio", CodeGreater},
+		{func(in *input) { in.Ratio = -0.1 }, "ratio", CodeGreater},
+		{func(in *input) { in.Ratio = 1.0001 }, "ratio", CodeMax},
+		{func(in *input) { in.Year = 1999 }, "year", CodeMin},
+	} {
+		in := valid
+		tt.change(&in)
+		fe := check(t, &in)
+		if fe.Field != tt.field || fe.Code != tt.code {
+			t.Errorf("Struct(%+v) = %+v, want %s %s", in, fe, tt.field, tt.code)
+		}
+	}
+	in := valid
+	in.Limit = 0
+	if fe := check(t, &in); fe.Message != "must be at least 1" || fe.Param != "1" {
+		t.Errorf("limit 0: %+v", fe)
+	}
+}
+
+func TestDecimalString(t *testing.T) {
+	type input struct {
+		Amount string `json:"amount" validate:"required,gt=0"`
+	}
+	for _, tt := range []struct {
+		amount string
+		code   string
+	}{
+		{"0.01", ""},
+		{" 12.50 ", ""},
+		{"0.000000000000000001", ""},
+		{"0", CodeGreater},
+		{"0.00", CodeGreater},
+		{"-5", CodeGreater},
+		{"1/3", CodeNumber},
+		{"ten", CodeNumber},
+		{"", CodeRequired},
+	} {
+		if fe := check(t, &input{Amount: tt.amount}); fe.Code != tt.code {
+			t.Errorf("amount %q: %+v, want code %q", tt.amount, fe, tt.code)
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	type input struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	for email, valid := range map[string]bool{
+		"jane@example.com":           true,
+		"jane.doe+gifts@example.org": true,
+		"jane":                       false,
+		"@example.com":               false,
+		"Jane <jane@example.com>":    false,
+		"jane@example.com ":          false,
+	} {
+		fe := check(t, &input{Email: email})
+		if valid != (fe.Code == "") || (!valid && fe.Code != CodeEmail) {
+			t.Errorf("email %q: %+v, want valid %t", email, fe, valid)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	type input struct {
+		Website string `json:"website" validate:"url"`
+	}
+	for website, valid := range map[string]bool{
+		"https://example.org":      true,
+		"http://example.org/a?b=c": true,
+		"example.org":              false,
+		"javascript:alert(1)":      false,
+		"ftp://example.org":        false,
+		"https://":                 false,
+		"http://exa mple.org/%zz":  false,
+	} {
+		fe := check(t, &input{Website: website})
+		if valid != (fe.Code == "") || (!valid && fe.Code != CodeURL) {
+			t.Errorf("website %q: %+v, want valid %t", website, fe, valid)
+		}
+	}
+}
+
+func TestOneOf(t *testing.T) {
+	type input struct {
+		Status string `json:"status" validate:"required,oneof=completed failed"`
+		Kind   int    `json:"kind" validate:"oneof=1 2"`
+	}
+	if fe := check(t, &input{Status: "failed", Kind: 2}); fe.Code != "" {
+		t.Errorf("valid input: %+v", fe)
+	}
+	fe := check(t, &input{Status: "pending", Kind: 1})
+	if fe.Field != "status" || fe.Code != CodeOneOf || fe.Param != "completed failed" || fe.Message != "must be one of: completed, failed" {
+		t.Errorf("pending status: %+v", fe)
+	}
+	if fe := check(t, &input{Status: "Completed", Kind: 1}); fe.Code != CodeOneOf {
+		t.Errorf("options are case sensitive: %+v", fe)
+	}
+	if fe := check(t, &input{Status: "completed", Kind: 3}); fe.Field != "kind" || fe.Code != CodeOneOf {
+		t.Errorf("kind 3: %+v", fe)
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	type input struct {
+		CauseID   int    `json:"cause_id,omitempty" validate:"required"`
+		TxHash    string `json:"-" validate:"required"`
+		Memo      string `validate:"required"`
+		Unchecked string `json:"unchecked"`
+		internal  string `validate:"required"`
+	}
+	errs := Struct(&input{internal: ""})
+	var fields []string
+	for _, fe := range errs {
+		fields = append(fields, fe.Field)
+	}
+	if got := strings.Join(fields, ","); got != "cause_id,TxHash,Memo" {
+		t.Errorf("fields = %s, want cause_id,TxHash,Memo in declaration order", got)
+	}
+
+	// Each field reports only its first failure
+	type lengths struct {
+		Name string `json:"name" validate:"required,min=3"`
+	}
+	if errs := Struct(&lengths{}); len(errs) != 1 || errs[0].Code != CodeRequired {
+		t.Errorf("empty name: %+v", errs)
+	}
+
+	// Struct accepts values as well as pointers
+	if errs := Struct(input{CauseID: 1, TxHash: "0x1", Memo: "m"}); len(errs) != 0 {
+		t.Errorf("struct value: %v", errs)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var errs Errors
+	errs.Add("cause_id", CodeNotFound, "does not exist")
+	errs.Add("amount", CodeInvalid, "has too many decimal places")
+	if !errs.Has("amount") || errs.Has("currency") {
+		t.Errorf("Has: %+v", errs)
+	}
+	if got := errs.Error(); got != "cause_id does not exist; amount has too many decimal places" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestMisconfiguredRulesPanic(t *testing.T) {
+	type unknownRule struct {
+		Name string `json:"name" validate:"alpha"`
+	}
+	type badParam struct {
+		Name string `json:"name" validate:"max=ten"`
+	}
+	for _, v := range []interface{}{&unknownRule{Name: "x"}, &badParam{Name: "x"}} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(r.(string), "field name") {
+					t.Errorf("Struct(%T) recovered %v, want a panic naming the field", v, r)
+				}
+			}()
+			Struct(v)
+		}()
+	}
+}
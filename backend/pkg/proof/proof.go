@@ -0,0 +1,249 @@
+// Package proof verifies TranspaCharity donation receipts offline.
+//
+// Every change to a donation is appended to an audit log in which each entry's
+// hash is the SHA-256 of the previous entry's hash followed by the entry's
+// JSON payload. Batches of consecutive entries are committed to a Merkle root
+// built as in RFC 6962, with the entry hashes as leaves. A Receipt carries one
+// entry and the audit path from it to the root of its batch, so anyone can
+// check that the donation is included in a published root without access to
+// the database:
+//
+//	var receipt proof.Receipt
+//	if err := json.NewDecoder(resp.Body).Decode(&receipt); err != nil {
+//		return err
+//	}
+//	// root is obtained independently, e.g. from the anchor transaction
+//	if err := receipt.VerifyRoot(root); err != nil {
+//		return err
+//	}
+//
+// The package only depends on the standard library.
+package proof
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GenesisHash is the previous hash of the first audit log entry
+var GenesisHash = strings.Repeat("0", 64)
+
+// Domain separation prefixes, as in RFC 6962, so that a leaf can never be
+// passed off as an interior node
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// ErrInvalidProof is returned when a receipt or audit path does not verify
+var ErrInvalidProof = errors.New("invalid proof")
+
+// EntryHash returns the hex SHA-256 hash of an audit log entry whose
+// predecessor has hash prevHash
+func EntryHash(prevHash, payload string) string {
+	sum := sha256.Sum256([]byte(prevHash + payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// Root returns the hex Merkle root of a batch of entry hashes, given in log
+// order. The left subtree of every node holds the largest power of two of its
+// leaves.
+func Root(entryHashes []string) (string, error) {
+	leaves, err := leafHashes(entryHashes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(subtreeRoot(leaves)), nil
+}
+
+// Prove returns the audit path of the entry at index in a batch of entry
+// hashes: the sibling hashes from its leaf up to the root, as hex
+func Prove(entryHashes []string, index int) ([]string, error) {
+	if index < 0 || index >= len(entryHashes) {
+		return nil, fmt.Errorf("index %d is outside a batch of %d entries", index, len(entryHashes))
+	}
+	leaves, err := leafHashes(entryHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	var path []string
+	for len(leaves) > 1 {
+		k := splitPoint(len(leaves))
+		if index < k {
+			path = append(path, hex.EncodeToString(subtreeRoot(leaves[k:])))
+			leaves = leaves[:k]
+		} else {
+			path = append(path, hex.EncodeToString(subtreeRoot(leaves[:k])))
+			leaves, index = leaves[k:], index-k
+		}
+	}
+
+	// Siblings were collected from the root down
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
+
+// VerifyInclusion checks that entryHash is the entry at index in a batch of
+// size entries whose Merkle root is root, using the algorithm of RFC 9162
+// section 2.1.3.2
+func VerifyInclusion(entryHash string, index, size int, path []string, root string) error {
+	if index < 0 || index >= size {
+		return fmt.Errorf("%w: index %d is outside a batch of %d entries", ErrInvalidProof, index, size)
+	}
+	leaf, err := decodeHash(entryHash)
+	if err != nil {
+		return err
+	}
+	want, err := decodeHash(root)
+	if err != nil {
+		return err
+	}
+
+	fn, sn := index, size-1
+	r := leafHash(leaf)
+	for _, p := range path {
+		sibling, err := decodeHash(p)
+		if err != nil {
+			return err
+		}
+		if sn == 0 {
+			return fmt.Errorf("%w: audit path is too long", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(sibling, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, sibling)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 {
+		return fmt.Errorf("%w: audit path is too short", ErrInvalidProof)
+	}
+	if !bytes.Equal(r, want) {
+		return fmt.Errorf("%w: audit path leads to %x, not %s", ErrInvalidProof, r, root)
+	}
+	return nil
+}
+
+// Receipt proves that an audit log entry recording a donation is included in
+// a committed Merkle root. Payload is the entry's JSON exactly as hashed.
+// Receipts without Payload and PrevHash only prove that EntryHash is included;
+// check them with VerifyInclusion.
+type Receipt struct {
+	DonationID            int      `json:"donation_id"`
+	EntryID               int64    `json:"entry_id"`
+	Payload               string   `json:"payload,omitempty"`
+	PrevHash              string   `json:"prev_hash,omitempty"`
+	EntryHash             string   `json:"entry_hash"`
+	LeafIndex             int      `json:"leaf_index"`
+	TreeSize              int      `json:"tree_size"`
+	Path                  []string `json:"path"`
+	RootID                int      `json:"root_id"`
+	Root                  string   `json:"root"`
+	AnchorTransactionHash string   `json:"anchor_transaction_hash,omitempty"`
+}
+
+// Verify checks that the payload records the receipt's donation, that it
+// hashes to EntryHash and that EntryHash is included in Root. It does not
+// check that Root itself was published; use VerifyRoot for that.
+func (r *Receipt) Verify() error {
+	var payload struct {
+		Donation struct {
+			ID int `json:"id"`
+		} `json:"donation"`
+	}
+	if err := json.Unmarshal([]byte(r.Payload), &payload); err != nil {
+		return fmt.Errorf("%w: payload is not valid JSON: %v", ErrInvalidProof, err)
+	}
+	if payload.Donation.ID != r.DonationID {
+		return fmt.Errorf("%w: payload records donation %d, not %d", ErrInvalidProof, payload.Donation.ID, r.DonationID)
+	}
+
+	if !strings.EqualFold(EntryHash(r.PrevHash, r.Payload), r.EntryHash) {
+		return fmt.Errorf("%w: payload does not hash to entry hash %s", ErrInvalidProof, r.EntryHash)
+	}
+
+	return VerifyInclusion(r.EntryHash, r.LeafIndex, r.TreeSize, r.Path, r.Root)
+}
+
+// VerifyRoot checks the receipt like Verify and that its root is root, a hex
+// Merkle root obtained from a source other than the receipt
+func (r *Receipt) VerifyRoot(root string) error {
+	if !strings.EqualFold(strings.TrimPrefix(root, "0x"), r.Root) {
+		return fmt.Errorf("%w: receipt is for root %s, not %s", ErrInvalidProof, r.Root, root)
+	}
+	return r.Verify()
+}
+
+// leafHashes decodes hex entry hashes and hashes them into leaves
+func leafHashes(entryHashes []string) ([][]byte, error) {
+	if len(entryHashes) == 0 {
+		return nil, errors.New("cannot build a Merkle tree over an empty batch")
+	}
+	leaves := make([][]byte, len(entryHashes))
+	for i, h := range entryHashes {
+		b, err := decodeHash(h)
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = leafHash(b)
+	}
+	return leaves, nil
+}
+
+// decodeHash decodes a hex SHA-256 hash
+func decodeHash(h string) ([]byte, error) {
+	b, err := hex.DecodeString(h)
+	if err != nil || len(b) != sha256.Size {
+		return nil, fmt.Errorf("%w: %q is not a hex SHA-256 hash", ErrInvalidProof, h)
+	}
+	return b, nil
+}
+
+// subtreeRoot returns the root of the tree over leaves
+func subtreeRoot(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := splitPoint(len(leaves))
+	return nodeHash(subtreeRoot(leaves[:k]), subtreeRoot(leaves[k:]))
+}
+
+// splitPoint returns the largest power of two smaller than n
+func splitPoint(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
+
+// leafHash hashes an entry hash into a leaf
+func leafHash(entryHash []byte) []byte {
+	sum := sha256.Sum256(append([]byte{leafPrefix}, entryHash...))
+	return sum[:]
+}
+
+// nodeHash hashes two children into their parent
+func nodeHash(left, right []byte) []byte {
+	data := make([]byte, 0, 1+len(left)+len(right))
+	data = append(data, nodePrefix)
+	data = append(data, left...)
+	data = append(data, right...)
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
@@ -0,0 +1,166 @@
+package proof_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ombima56/transpacharity/pkg/proof"
+)
+
+// batch returns n distinct entry hashes chained like the audit log
+func batch(n int) []string {
+	hashes := make([]string, n)
+	prev := proof.GenesisHash
+	for i := range hashes {
+		prev = proof.EntryHash(prev, fmt.Sprintf(`{"donation":{"id":%d}}`, i+1))
+		hashes[i] = prev
+	}
+	return hashes
+}
+
+// sha returns the hex SHA-256 of prefix followed by the decoded hex hashes
+func sha(prefix byte, hashes ...string) string {
+	data := []byte{prefix}
+	for _, h := range hashes {
+		b, _ := hex.DecodeString(h)
+		data = append(data, b...)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestRootMatchesRFC6962(t *testing.T) {
+	h := batch(3)
+	leaf := func(i int) string { return sha(0x00, h[i]) }
+
+	tests := []struct {
+		size int
+		want string
+	}{
+		{1, leaf(0)},
+		{2, sha(0x01, leaf(0), leaf(1))},
+		{3, sha(0x01, sha(0x01, leaf(0), leaf(1)), leaf(2))},
+	}
+	for _, tt := range tests {
+		root, err := proof.Root(h[:tt.size])
+		if err != nil {
+			t.Fatalf("Root(%d): %v", tt.size, err)
+		}
+		if root != tt.want {
+			t.Errorf("Root(%d) = %s, want %s", tt.size, root, tt.want)
+		}
+	}
+}
+
+func TestProveAndVerifyInclusion(t *testing.T) {
+	for size := 1; size <= 33; size++ {
+		hashes := batch(size)
+		root, err := proof.Root(hashes)
+		if err != nil {
+			t.Fatalf("Root(%d): %v", size, err)
+		}
+		for index := 0; index < size; index++ {
+			path, err := proof.Prove(hashes, index)
+			if err != nil {
+				t.Fatalf("Prove(%d, %d): %v", size, index, err)
+			}
+			if err := proof.VerifyInclusion(hashes[index], index, size, path, root); err != nil {
+				t.Errorf("VerifyInclusion(size %d, index %d): %v", size, index, err)
+			}
+		}
+	}
+}
+
+func TestVerifyInclusionRejects(t *testing.T) {
+	const size = 13
+	hashes := batch(size)
+	root, err := proof.Root(hashes)
+	if err != nil {
+		t.Fatalf("Root: %v", err)
+	}
+	path, err := proof.Prove(hashes, 5)
+	if err != nil {
+		t.Fatalf("Prove: %v", err)
+	}
+	tampered := append([]string(nil), path...)
+	tampered[1] = hashes[0]
+
+	tests := []struct {
+		name      string
+		entryHash string
+		index     int
+		size      int
+		path      []string
+		root      string
+	}{
+		{"tampered leaf", batch(size + 1)[size], 5, size, path, root},
+		{"wrong index", hashes[5], 4, size, path, root},
+		{"index of a sibling subtree", hashes[5], 7, size, path, root},
+		{"index outside the batch", hashes[5], size, size, path, root},
+		{"negative index", hashes[5], -1, size, path, root},
+		// A size giving the entry the same position in the tree shape, such
+		// as 14, yields the same path; any other size is rejected
+		{"larger size", hashes[5], 5, 2 * size, path, root},
+		{"smaller size", hashes[5], 5, 8, path, root},
+		{"tampered path", hashes[5], 5, size, tampered, root},
+		{"path too short", hashes[5], 5, size, path[:len(path)-1], root},
+		{"path too long", hashes[5], 5, size, append(append([]string(nil), path...), hashes[0]), root},
+		{"wrong root", hashes[5], 5, size, path, hashes[0]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := proof.VerifyInclusion(tt.entryHash, tt.index, tt.size, tt.path, tt.root)
+			if !errors.Is(err, proof.ErrInvalidProof) {
+				t.Errorf("err = %v, want %v", err, proof.ErrInvalidProof)
+			}
+		})
+	}
+}
+
+func TestProveRejectsIndexOutsideBatch(t *testing.T) {
+	hashes := batch(4)
+	for _, index := range []int{-1, 4} {
+		if _, err := proof.Prove(hashes, index); err == nil {
+			t.Errorf("Prove(4 entries, %d) succeeded", index)
+		}
+	}
+	if _, err := proof.Root(nil); err == nil {
+		t.Error("Root of an empty batch succeeded")
+	}
+}
+
+func TestReceiptVerifyRoot(t *testing.T) {
+	hashes := batch(6)
+	root, _ := proof.Root(hashes)
+	path, _ := proof.Prove(hashes, 2)
+	receipt := proof.Receipt{
+		DonationID: 3,
+		Payload:    `{"donation":{"id":3}}`,
+		PrevHash:   hashes[1],
+		EntryHash:  hashes[2],
+		LeafIndex:  2,
+		TreeSize:   6,
+		Path:       path,
+		Root:       root,
+	}
+	if err := receipt.VerifyRoot("0x" + root); err != nil {
+		t.Fatalf("VerifyRoot: %v", err)
+	}
+
+	other := receipt
+	other.DonationID = 4
+	if err := other.Verify(); !errors.Is(err, proof.ErrInvalidProof) {
+		t.Errorf("receipt for another donation: err = %v, want %v", err, proof.ErrInvalidProof)
+	}
+	edited := receipt
+	edited.Payload = `{"donation":{"id":3},"amount":"1"}`
+	if err := edited.Verify(); !errors.Is(err, proof.ErrInvalidProof) {
+		t.Errorf("edited payload: err = %v, want %v", err, proof.ErrInvalidProof)
+	}
+	if err := receipt.VerifyRoot(hashes[0]); !errors.Is(err, proof.ErrInvalidProof) {
+		t.Errorf("unpublished root: err = %v, want %v", err, proof.ErrInvalidProof)
+	}
+}
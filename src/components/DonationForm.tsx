@@ -6,7 +6,7 @@ import { Label } from "./ui/label";
 import { RadioGroup, RadioGroupItem } from "./ui/radio-group";
 import { toast } from "./ui/use-toast";
 import { Heart } from "lucide-react";
-import { donationsApi } from "@/lib/api";
+import { donationsApi, getFieldErrors } from "@/lib/api";
 import { useMutation } from "@tanstack/react-query";
 
 interface DonationFormProps {
@@ -67,6 +67,7 @@ const DonationForm = ({
         variant: "destructive",
         title: "Error submitting donation",
         description:
+          Object.values(getFieldErrors(error)).join(". ") ||
           error.message ||
           "There was an error processing your donation. Please try again.",
       });
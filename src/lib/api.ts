@@ -9,7 +9,27 @@ import {
   Cause,
   CreateCauseRequest,
   Donation,
-  CreateDonationRequest
+  CreateDonationRequest,
+  ApiErrorResponse,
+  CauseListParams,
+  CauseSearchParams,
+  CauseSearchResult,
+  DonationListParams,
+  DonationExportParams,
+  LedgerEntry,
+  LedgerParams,
+  AuditRoot,
+  DonationReceipt,
+  ReceiptVerification,
+  ListParams,
+  Disbursement,
+  DisbursementRequest,
+  DisbursementParams,
+  CauseFundsSummary,
+  RecurringDonation,
+  RecurringDonationRequest,
+  MatchingPledge,
+  MatchingPledgeRequest
 } from "@/types";
 
 // Create an axios instance with base URL and default headers
@@ -32,11 +52,59 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
+// Refresh the access token once when a request is rejected as unauthorized
+let refreshing: Promise<string> | null = null;
+
+api.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const original = error.config;
+    const refreshToken = localStorage.getItem("refresh_token");
+    const isAuthCall = original?.url?.startsWith("/users/refresh") || original?.url?.startsWith("/users/login");
+    if (error.response?.status !== 401 || !refreshToken || !original || original._retried || isAuthCall) {
+      return Promise.reject(error);
+    }
+    original._retried = true;
+
+    try {
+      refreshing =
+        refreshing ??
+        api
+          .post<AuthResponse>("/users/refresh", { refresh_token: refreshToken })
+          .then((response) => {
+            storeSession(response.data);
+            return response.data.token;
+          })
+          .finally(() => {
+            refreshing = null;
+          });
+      const token = await refreshing;
+      original.headers.Authorization = `Bearer ${token}`;
+      return api(original);
+    } catch (refreshError) {
+      clearSession();
+      return Promise.reject(error);
+    }
+  }
+);
+
+const storeSession = (data: AuthResponse) => {
+  localStorage.setItem("token", data.token);
+  localStorage.setItem("refresh_token", data.refresh_token);
+  localStorage.setItem("user", JSON.stringify(data.user));
+};
+
+const clearSession = () => {
+  localStorage.removeItem("token");
+  localStorage.removeItem("refresh_token");
+  localStorage.removeItem("user");
+};
+
 // API functions for causes
 export const causesApi = {
-  getAll: async () => {
+  getAll: async (params?: CauseListParams) => {
     try {
-      const response = await api.get<Cause[]>("/causes");
+      const response = await api.get<Cause[]>("/causes", { params });
       return response;
     } catch (error) {
       console.error("Error fetching causes:", error);
@@ -52,6 +120,14 @@ export const causesApi = {
       throw error;
     }
   },
+  search: async (q: string, params?: CauseSearchParams) => {
+    try {
+      return await api.get<CauseSearchResult[]>("/causes/search", { params: { ...params, q } });
+    } catch (error) {
+      console.error("Error searching causes:", error);
+      throw error;
+    }
+  },
   getById: async (id: string | number) => {
     try {
       return await api.get<Cause>(`/causes/${id}`);
@@ -145,17 +221,25 @@ export const categoriesApi = {
 
 // API functions for donations
 export const donationsApi = {
-  create: async (data: CreateDonationRequest) => {
+  // Pass the same idempotencyKey when retrying a failed create so that the
+  // donation is only made once. An expired token is refreshed and the
+  // request retried with the same key by the response interceptor.
+  create: async (
+    data: CreateDonationRequest,
+    idempotencyKey: string = crypto.randomUUID()
+  ) => {
     try {
-      return await api.post<Donation>("/donations", data);
+      return await api.post<Donation>("/donations", data, {
+        headers: { "Idempotency-Key": idempotencyKey },
+      });
     } catch (error) {
       console.error("Error creating donation:", error);
       throw error;
     }
   },
-  getAll: async () => {
+  getAll: async (params?: DonationListParams) => {
     try {
-      return await api.get<Donation[]>("/donations");
+      return await api.get<Donation[]>("/donations", { params });
     } catch (error) {
       console.error("Error fetching donations:", error);
       throw error;
@@ -177,14 +261,25 @@ export const donationsApi = {
       throw error;
     }
   },
-  getByCauseId: async (id: string | number) => {
+  getByCauseId: async (id: string | number, params?: DonationListParams) => {
     try {
-      return await api.get<Donation[]>(`/causes/${id}/donations`);
+      return await api.get<Donation[]>(`/causes/${id}/donations`, { params });
     } catch (error) {
       console.error(`Error fetching donations for cause ${id}:`, error);
       throw error;
     }
   },
+  getProof: (id: string | number) => api.get<DonationReceipt>(`/donations/${id}/proof`),
+  // PDF receipt for a completed donation
+  getReceiptPdf: (id: string | number) =>
+    api.get<Blob>(`/donations/${id}/receipt.pdf`, { responseType: "blob" }),
+  // PDF statement of the current user's donations completed in year
+  getStatementPdf: (year: number) =>
+    api.get<Blob>(`/users/me/statements/${year}.pdf`, { responseType: "blob" }),
+  verifyReceipt: (code: string) => api.get<ReceiptVerification>(`/receipts/${code}`),
+  // Every donation matching params as a csv, jsonl or xlsx file (admin only)
+  exportDonations: (params?: DonationExportParams) =>
+    api.get<Blob>("/admin/exports/donations", { params, responseType: "blob" }),
   getByUserId: async (id: string | number) => {
     try {
       return await api.get<Donation[]>(`/users/${id}/donations`);
@@ -203,12 +298,71 @@ export const donationsApi = {
   },
 };
 
+// API functions for the public ledger
+export const ledgerApi = {
+  getAll: async (params?: LedgerParams) => {
+    try {
+      return await api.get<LedgerEntry[]>("/ledger", { params });
+    } catch (error) {
+      console.error("Error fetching ledger:", error);
+      throw error;
+    }
+  },
+};
+
+// API functions for how causes spend their funds
+export const disbursementsApi = {
+  getByCauseId: (causeId: string | number, params?: DisbursementParams) =>
+    api.get<Disbursement[]>(`/causes/${causeId}/disbursements`, { params }),
+  getFunds: (causeId: string | number) =>
+    api.get<CauseFundsSummary>(`/causes/${causeId}/funds`),
+  getById: (id: string | number) => api.get<Disbursement>(`/disbursements/${id}`),
+  create: (causeId: string | number, data: DisbursementRequest) =>
+    api.post<Disbursement>(`/causes/${causeId}/disbursements`, data),
+  update: (id: string | number, data: DisbursementRequest) =>
+    api.put<Disbursement>(`/disbursements/${id}`, data),
+  delete: (id: string | number) => api.delete(`/disbursements/${id}`),
+};
+
+// API functions for sponsors' matching pledges
+export const pledgesApi = {
+  getByCauseId: (causeId: string | number) =>
+    api.get<MatchingPledge[]>(`/causes/${causeId}/pledges`),
+  create: (causeId: string | number, data: MatchingPledgeRequest) =>
+    api.post<MatchingPledge>(`/causes/${causeId}/pledges`, data),
+  close: (id: string | number) => api.post<MatchingPledge>(`/pledges/${id}/close`),
+};
+
+// API functions for the current user's recurring donations
+export const recurringDonationsApi = {
+  getMine: () => api.get<RecurringDonation[]>("/users/me/recurring"),
+  getById: (id: string | number) => api.get<RecurringDonation>(`/users/me/recurring/${id}`),
+  create: (data: RecurringDonationRequest) =>
+    api.post<RecurringDonation>("/users/me/recurring", data),
+  update: (id: string | number, data: RecurringDonationRequest) =>
+    api.put<RecurringDonation>(`/users/me/recurring/${id}`, data),
+  pause: (id: string | number) =>
+    api.post<RecurringDonation>(`/users/me/recurring/${id}/pause`),
+  resume: (id: string | number) =>
+    api.post<RecurringDonation>(`/users/me/recurring/${id}/resume`),
+  cancel: (id: string | number) =>
+    api.post<RecurringDonation>(`/users/me/recurring/${id}/cancel`),
+};
+
+// API functions for the Merkle roots of the donation audit log
+export const auditApi = {
+  getRoots: (params?: ListParams) => api.get<AuditRoot[]>("/audit/roots", { params }),
+  getLatestRoot: () => api.get<AuditRoot>("/audit/roots/latest"),
+};
+
 // API functions for users
 export const usersApi = {
   register: (data: RegisterRequest): Promise<AxiosResponse<AuthResponse>> =>
     api.post<AuthResponse>("/users/register", data),
   login: (data: LoginRequest): Promise<AxiosResponse<AuthResponse>> => 
     api.post<AuthResponse>("/users/login", data),
+  logout: (refreshToken: string) =>
+    api.post("/users/logout", { refresh_token: refreshToken }),
   getMe: () => api.get<User>("/users/me"),
   updateMe: (data: Partial<User>) => {
     // Only send the name field when updating profile
@@ -233,8 +387,7 @@ export const auth = {
         throw new Error("Invalid server response. Please try again.");
       }
       
-      localStorage.setItem("token", response.data.token);
-      localStorage.setItem("user", JSON.stringify(response.data.user));
+      storeSession(response.data);
       return response.data;
     } catch (error: any) {
       console.error("Login error details:", error);
@@ -260,7 +413,7 @@ export const auth = {
             throw new Error("Server error. Our team has been notified and is working on a fix.");
           default:
             // Use server message if available, otherwise use a generic message with the status
-            const message = error.response.data?.message || 
+            const message = getErrorMessage(error) ||
                           `Login failed (Error ${error.response.status}). Please try again.`;
             throw new Error(message);
         }
@@ -282,8 +435,7 @@ export const auth = {
   ): Promise<AuthResponse> => {
     try {
       const response = await usersApi.register({ name, email, password });
-      localStorage.setItem("token", response.data.token);
-      localStorage.setItem("user", JSON.stringify(response.data.user));
+      storeSession(response.data);
       return response.data;
     } catch (error: any) {
       console.error("Registration error:", error);
@@ -294,15 +446,21 @@ export const auth = {
         
         // Handle specific HTTP status codes with user-friendly messages
         switch (error.response.status) {
-          case 400:
-            if (error.response.data?.message?.includes("Email already in use")) {
+          case 422: {
+            const fields = getFieldErrors(error);
+            throw new Error(Object.values(fields).join(". ") || "Invalid registration data. Please check your information.");
+          }
+          case 409:
+            if (getErrorCode(error) === "email_taken") {
               throw new Error("This email is already registered. Please use a different email or try logging in.");
             }
-            throw new Error(error.response.data?.message || "Invalid registration data. Please check your information.");
+            throw new Error(getErrorMessage(error) || "Invalid registration data. Please check your information.");
+          case 400:
+            throw new Error(getErrorMessage(error) || "Invalid registration data. Please check your information.");
           case 500:
             throw new Error("Server error. Our team has been notified and is working on a fix.");
           default:
-            const message = error.response.data?.message || 
+            const message = getErrorMessage(error) ||
                           `Registration failed (Error ${error.response.status}). Please try again.`;
             throw new Error(message);
         }
@@ -318,8 +476,11 @@ export const auth = {
     }
   },
   logout: (): void => {
-    localStorage.removeItem("token");
-    localStorage.removeItem("user");
+    const refreshToken = localStorage.getItem("refresh_token");
+    if (refreshToken) {
+      usersApi.logout(refreshToken).catch((error) => console.error("Logout error:", error));
+    }
+    clearSession();
   },
   getUser: (): User | null => {
     const user = localStorage.getItem("user");
@@ -334,4 +495,44 @@ export const auth = {
   },
 };
 
+// Returns the number of items matching a list request's filters
+export const getTotalCount = (response: AxiosResponse): number | undefined => {
+  const total = response.headers["x-total-count"];
+  return total === undefined ? undefined : Number(total);
+};
+
+// Returns the cursor of the page after a list response, if there is one
+export const getNextCursor = (response: AxiosResponse): string | undefined => {
+  const link: string | undefined = response.headers["link"];
+  const next = link?.split(",").find((part) => part.includes('rel="next"'));
+  const url = next?.match(/<([^>]+)>/)?.[1];
+  return url ? new URL(url, window.location.origin).searchParams.get("cursor") ?? undefined : undefined;
+};
+
+// Returns the code of an API error envelope, e.g. "email_taken"
+export const getErrorCode = (error: any): string | undefined => {
+  return (error?.response?.data as ApiErrorResponse | undefined)?.error?.code;
+};
+
+// Returns the message of an API error envelope
+export const getErrorMessage = (error: any): string | undefined => {
+  return (error?.response?.data as ApiErrorResponse | undefined)?.error?.message;
+};
+
+// Returns the per-field messages of a 422 validation error keyed by the
+// request field name, so forms can show them next to their inputs
+export const getFieldErrors = (error: any): Record<string, string> => {
+  const data = error?.response?.data as ApiErrorResponse | undefined;
+  if (error?.response?.status !== 422 || !data?.error?.details) {
+    return {};
+  }
+  const fields: Record<string, string> = {};
+  for (const detail of data.error.details) {
+    if (!fields[detail.field]) {
+      fields[detail.field] = `${detail.field.replace(/_/g, " ")} ${detail.message}`;
+    }
+  }
+  return fields;
+};
+
 export default api;
@@ -85,6 +85,17 @@ const CausesPage = () => {
     },
   });
 
+  // Search on the server once the user has typed something
+  const query = searchTerm.trim();
+  const { data: searchData } = useQuery({
+    queryKey: ["causes", "search", query],
+    queryFn: async () => {
+      const response = await causesApi.search(query, { limit: 100 });
+      return response.data;
+    },
+    enabled: query !== "",
+  });
+
   // Add this after fetching causes data
   useEffect(() => {
     if (causesData && Array.isArray(causesData)) {
@@ -122,7 +133,9 @@ const CausesPage = () => {
   console.log("causesData before filtering:", causesData);
 
   // Make sure causesData is an array before filtering
-  const safeData = causesData && Array.isArray(causesData) ? causesData : [];
+  const safeData = query !== "" && Array.isArray(searchData)
+    ? searchData
+    : causesData && Array.isArray(causesData) ? causesData : [];
   console.log("safeData after check:", safeData);
 
   const filteredCauses = safeData
@@ -134,7 +147,10 @@ const CausesPage = () => {
         const organization = cause.organization || "";
         const causeCategory = cause.category || "";
 
+        // Server search results already match; fall back to matching
+        // locally until they arrive
         const matchesSearch =
+          searchData !== undefined ||
           title.toLowerCase().includes(searchTerm.toLowerCase()) ||
           description.toLowerCase().includes(searchTerm.toLowerCase()) ||
           organization.toLowerCase().includes(searchTerm.toLowerCase());
@@ -159,7 +175,8 @@ const CausesPage = () => {
         if (sortBy === "raised-asc") return aRaised - bRaised;
         if (sortBy === "goal-desc") return bGoal - aGoal;
         if (sortBy === "goal-asc") return aGoal - bGoal;
-        // Default: most raised first
+        // Default: best search matches first, otherwise most raised first
+        if (query !== "" && searchData !== undefined) return 0;
         return bRaised - aRaised;
       } catch (error) {
         console.error("Error sorting causes:", error, a, b);
@@ -59,7 +59,7 @@ const Dashboard = () => {
     enabled: isAuthenticated
   });
   
-  const totalDonated = userDonations.reduce((sum, donation) => sum + donation.amount, 0);
+  const totalDonated = userDonations.reduce((sum, donation) => sum + Number(donation.amount), 0);
   const causesSupported = new Set(userDonations.map(d => d.cause_id)).size;
   
   if (!isAuthenticated) {
@@ -11,6 +11,8 @@ export interface User {
 // Auth types
 export interface AuthResponse {
   token: string;
+  refresh_token: string;
+  expires_in: number;
   user: User;
 }
 
@@ -52,10 +54,42 @@ export interface Cause {
   category_id: number;
   category?: Category;
   featured: boolean;
+  owner_id?: number;
+  tax_id?: string;
+  tax_deductible: boolean;
+  matched_amount?: string;
+  matching_pledges?: MatchingPledge[];
   created_at: string;
   updated_at: string;
 }
 
+export type MatchingPledgeStatus = "scheduled" | "open" | "exhausted" | "ended" | "closed";
+
+export interface MatchingPledge {
+  id: number;
+  cause_id: number;
+  sponsor_name: string;
+  currency: string;
+  cap: string;
+  matched_amount: string;
+  remaining: string;
+  status: MatchingPledgeStatus;
+  starts_at: string;
+  ends_at: string;
+  closed_at?: string;
+  created_by?: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface MatchingPledgeRequest {
+  sponsor_name: string;
+  cap: string;
+  currency?: string;
+  starts_at?: string;
+  ends_at: string;
+}
+
 export interface CreateCauseRequest {
   title: string;
   organization: string;
@@ -64,6 +98,9 @@ export interface CreateCauseRequest {
   goal_amount: number;
   category_id: number;
   featured: boolean; // Keep as boolean in frontend for easier form handling
+  owner_id?: number | null;
+  tax_id?: string;
+  tax_deductible?: boolean;
 }
 
 // Donation types
@@ -85,3 +122,220 @@ export interface CreateDonationRequest {
   message?: string;
   anonymous: boolean;
 }
+
+// One invalid field of a request rejected with 422
+export interface FieldError {
+  field: string;
+  code: string;
+  message: string;
+  param?: string;
+}
+
+export interface ApiErrorResponse {
+  error: {
+    code: string;
+    message: string;
+    details?: FieldError[];
+    request_id?: string;
+  };
+}
+
+export interface ListParams {
+  limit?: number;
+  cursor?: string;
+  sort?: string;
+}
+
+export interface CauseSearchResult extends Cause {
+  rank: number;
+  headline: string;
+  snippet: string;
+}
+
+export interface CauseSearchParams {
+  limit?: number;
+  cursor?: string;
+  category_id?: number;
+  featured?: boolean;
+}
+
+export interface CauseListParams extends ListParams {
+  category_id?: number;
+  featured?: boolean;
+  currency?: string;
+}
+
+export interface DonationListParams extends ListParams {
+  cause_id?: number;
+  user_id?: number;
+  status?: "pending" | "completed" | "failed";
+  currency?: string;
+  min_amount?: string;
+  max_amount?: string;
+  from?: string;
+  to?: string;
+}
+
+// Query parameters for the admin donation export
+export interface DonationExportParams extends Omit<DonationListParams, "limit" | "cursor"> {
+  format?: "csv" | "jsonl" | "xlsx";
+}
+
+export interface LedgerEntry {
+  type: "donation" | "withdrawal";
+  id: number;
+  direction: "in" | "out";
+  cause_id: number | null;
+  cause_title?: string;
+  amount: string;
+  currency: string;
+  balance: string;
+  source: "offchain" | "onchain";
+  donor?: string;
+  wallet_address?: string;
+  transaction_hash?: string;
+  explorer_url?: string;
+  block_number?: number;
+  occurred_at: string;
+}
+
+export interface LedgerParams extends ListParams {
+  cause_id?: number;
+  currency?: string;
+  type?: "donation" | "withdrawal";
+  from?: string;
+  to?: string;
+}
+
+export interface DisbursementDocument {
+  id?: number;
+  name: string;
+  url: string;
+  sha256?: string;
+}
+
+export interface Disbursement {
+  id: number;
+  cause_id: number;
+  withdrawal_id: number | null;
+  amount: string;
+  currency: string;
+  transaction_hash?: string;
+  recipient_wallet?: string;
+  purpose: string;
+  spent_at: string;
+  reported_by?: number;
+  documents: DisbursementDocument[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface DisbursementRequest {
+  withdrawal_id?: number | null;
+  amount: string;
+  currency?: string;
+  transaction_hash?: string;
+  recipient_wallet?: string;
+  purpose: string;
+  spent_at?: string;
+  documents?: DisbursementDocument[];
+}
+
+export interface DisbursementParams extends ListParams {
+  withdrawal_id?: number;
+  currency?: string;
+}
+
+export interface CauseFunds {
+  currency: string;
+  raised: string;
+  withdrawn: string;
+  spent: string;
+  unreported: string;
+}
+
+export interface CauseFundsSummary {
+  cause_id: number;
+  currencies: CauseFunds[];
+}
+
+export type RecurringInterval = "weekly" | "monthly";
+
+export type RecurringStatus = "active" | "paused" | "cancelled";
+
+export interface RecurringDonation {
+  id: number;
+  user_id: number;
+  cause_id: number;
+  cause_title?: string;
+  amount: string;
+  currency: string;
+  is_anonymous: boolean;
+  interval: RecurringInterval;
+  status: RecurringStatus;
+  start_at: string;
+  next_run_at: string;
+  next_attempt_at: string;
+  last_run_at?: string;
+  last_donation_id?: number;
+  failure_count: number;
+  last_error?: string;
+  created_at: string;
+  updated_at: string;
+  cancelled_at?: string;
+}
+
+export interface RecurringDonationRequest {
+  cause_id: number;
+  amount: string;
+  currency?: string;
+  is_anonymous?: boolean;
+  interval: RecurringInterval;
+  start_at?: string;
+}
+
+export interface DonationReceipt {
+  donation_id: number;
+  entry_id: number;
+  // Only returned to the donor and admins
+  payload?: string;
+  prev_hash?: string;
+  entry_hash: string;
+  leaf_index: number;
+  tree_size: number;
+  path: string[];
+  root_id: number;
+  root: string;
+  anchor_transaction_hash?: string;
+}
+
+// A donation receipt as returned by its verification URL
+export interface ReceiptVerification {
+  number: number;
+  receipt_number: string;
+  issued_at: string;
+  voided: boolean;
+  donation_id?: number;
+  amount?: string;
+  currency?: string;
+  completed_at?: string;
+  transaction_hash?: string;
+  donor_name?: string;
+  donor_address?: string;
+  cause_id?: number;
+  cause_title?: string;
+  organization?: string;
+  tax_id?: string;
+  tax_deductible: boolean;
+}
+
+export interface AuditRoot {
+  id: number;
+  first_entry_id: number;
+  last_entry_id: number;
+  entry_count: number;
+  root: string;
+  anchor_transaction_hash?: string;
+  explorer_url?: string;
+  created_at: string;
+}